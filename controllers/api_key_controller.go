@@ -152,6 +152,35 @@ func (a *APIKeyController) Delete(ctx *gin.Context) {
 	ctx.Status(http.StatusNoContent)
 }
 
+// Verify 供业务服务校验密钥，返回密钥所属用户；无需登录，密钥本身即凭证。
+func (a *APIKeyController) Verify(ctx *gin.Context) {
+	var req struct {
+		APIKey string `json:"api_key"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil || req.APIKey == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "缺少 api_key"})
+		return
+	}
+
+	key, err := a.apiKeyService.Lookup(req.APIKey)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"error": "密钥无效或已删除"})
+			return
+		}
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"user_id":        key.UserID,
+		"email":          key.User.Email,
+		"name":           key.User.Name,
+		"level":          key.User.Level,
+		"level_snapshot": key.LevelSnapshot,
+	})
+}
+
 func sanitizeKeys(keys []models.APIKey) []gin.H {
 	result := make([]gin.H, 0, len(keys))
 	for _, key := range keys {
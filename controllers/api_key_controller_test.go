@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/xiufeng-chen278/developer-platform-backend/models"
+	"github.com/xiufeng-chen278/developer-platform-backend/services"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// testDB 连接 TEST_DATABASE_URL 指定的 PostgreSQL 并执行迁移，未设置时跳过
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("未设置 TEST_DATABASE_URL，跳过需要数据库的测试")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Skipf("无法连接数据库: %v", err)
+	}
+	if err := models.RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func verifyKey(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/api-keys/verify", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestVerifyAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testDB(t)
+	apiKeyService := services.NewAPIKeyService(db)
+	controller := NewAPIKeyController(nil, apiKeyService)
+	router := gin.New()
+	router.POST("/api/api-keys/verify", controller.Verify)
+
+	user := models.User{GoogleID: uuid.NewString(), Email: uuid.NewString() + "@example.com", Name: "Verify Test", Level: 2}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Delete(&user) })
+	key, err := apiKeyService.Create(&user, "verify")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := verifyKey(router, `{"api_key":"`+key.Key+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("有效密钥返回 %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		UserID uint   `json:"user_id"`
+		Email  string `json:"email"`
+		Level  int    `json:"level"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.UserID != user.ID || resp.Email != user.Email || resp.Level != 2 {
+		t.Fatalf("响应 = %+v, 期望用户 %d %s", resp, user.ID, user.Email)
+	}
+	var stored models.APIKey
+	if db.First(&stored, key.ID); stored.LastUsedAt == nil {
+		t.Error("校验后应记录 last_used_at")
+	}
+
+	// 重新生成后旧密钥失效
+	regenerated, err := apiKeyService.Update(user.ID, key.ID, services.UpdateInput{Regenerate: true, NewLevel: user.Level})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := verifyKey(router, `{"api_key":"`+key.Key+`"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("重新生成前的密钥返回 %d, 期望 404", w.Code)
+	}
+
+	// 删除后新密钥也失效
+	if err := apiKeyService.Delete(user.ID, regenerated.ID); err != nil {
+		t.Fatal(err)
+	}
+	if w := verifyKey(router, `{"api_key":"`+regenerated.Key+`"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("已删除的密钥返回 %d, 期望 404", w.Code)
+	}
+}
+
+func TestVerifyAPIKeyRequiresKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	controller := NewAPIKeyController(nil, services.NewAPIKeyService(nil))
+	router := gin.New()
+	router.POST("/api/api-keys/verify", controller.Verify)
+
+	for _, body := range []string{`{}`, `not json`} {
+		if w := verifyKey(router, body); w.Code != http.StatusBadRequest {
+			t.Errorf("请求体 %q 返回 %d, 期望 400", body, w.Code)
+		}
+	}
+}
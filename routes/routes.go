@@ -45,6 +45,8 @@ func RegisterRoutes(router *gin.Engine, cfg *config.Config, db *gorm.DB) {
 			})
 		}
 
+		api.POST("/api-keys/verify", apiKeyController.Verify) // 业务服务凭密钥校验，不需要登录令牌
+
 		apiKeys := api.Group("/api-keys")
 		apiKeys.Use(middlewares.JWTAuthMiddleware(tokenService))
 		{
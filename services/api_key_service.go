@@ -45,6 +45,22 @@ func (s *APIKeyService) List(userID uint) ([]models.APIKey, error) {
 	return keys, nil
 }
 
+// Lookup 按密钥字符串查找密钥及其所属用户，并记录使用时间。
+// 密钥不存在（已删除或已重新生成）时返回 gorm.ErrRecordNotFound。
+func (s *APIKeyService) Lookup(value string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := s.db.Preload("User").Where("key = ?", value).First(&key).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&key).UpdateColumn("last_used_at", &now).Error; err != nil {
+		return nil, err
+	}
+	key.LastUsedAt = &now
+	return &key, nil
+}
+
 // UpdateInput 表示更新请求。
 type UpdateInput struct {
 	Label      *string
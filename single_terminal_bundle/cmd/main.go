@@ -6,32 +6,149 @@ import (
 	"go-backEnd/internal/models"
 	"go-backEnd/internal/services"
 	"go-backEnd/internal/utils"
+	"go-backEnd/pkg/audio"
+	"go-backEnd/pkg/languages"
+	"go-backEnd/pkg/storage"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func main() {
 	config.Init()
 	services.InitRedis()
-	services.InitAuthService(services.RDB, config.AppConfig.AuthCode, config.AppConfig.CodeVersion)
+	services.InitCluster(services.ClusterConfig{
+		Enabled:    utils.GetEnvBool("CLUSTER_MODE", false),
+		InstanceID: utils.GetEnv("CLUSTER_INSTANCE_ID", ""),
+		LeaseTTL:   utils.GetEnvDuration("CLUSTER_LEASE_TTL", 10*time.Second),
+	})
+	if err := languages.Init(utils.GetEnv("LANGUAGES_FILE", "")); err != nil {
+		log.Fatalf("❌ 加载语言目录失败: %v", err)
+	}
+	services.Segmentation.MaxRunes = utils.GetEnvInt("SEGMENT_MAX_RUNES", services.Segmentation.MaxRunes)
+	services.Segmentation.MaxWait = utils.GetEnvDuration("SEGMENT_MAX_WAIT", services.Segmentation.MaxWait)
+	services.InitReversePool(services.ReversePoolConfig{
+		Workers:    utils.GetEnvInt("REVERSE_WORKERS", 4),
+		QueueSize:  utils.GetEnvInt("REVERSE_QUEUE_SIZE", 256),
+		JobTimeout: utils.GetEnvDuration("REVERSE_JOB_TIMEOUT", 45*time.Second),
+	})
+	if err := services.InitReverseTranslator(services.ReverseTranslatorConfig{
+		Provider:      utils.GetEnv("REVERSE_PROVIDER", "convtext"),
+		MaxRetries:    utils.GetEnvInt("REVERSE_MAX_RETRIES", 3),
+		CacheTTL:      utils.GetEnvDuration("REVERSE_CACHE_TTL", 24*time.Hour),
+		ContextWindow: utils.GetEnvInt("REVERSE_CONTEXT_WINDOW", 6),
+	}); err != nil {
+		log.Fatalf("❌ 初始化反向翻译服务失败: %v", err)
+	}
+	audio.UpstreamInput.SampleRate = utils.GetEnvInt("MIC_SAMPLE_RATE", audio.UpstreamInput.SampleRate)
+	if err := audio.UpstreamInput.Validate(); err != nil {
+		log.Fatalf("❌ 上游输入音频格式无效: %v", err)
+	}
+	services.Mixing.SampleRate = audio.UpstreamInput.SampleRate
+	services.Mixing.Frame = utils.GetEnvDuration("MIX_FRAME", services.Mixing.Frame)
+	services.Mixing.MaxLatency = utils.GetEnvDuration("MIX_MAX_LATENCY", services.Mixing.MaxLatency)
+	services.InitAuthService(services.RDB, config.AppConfig.AuthCode, config.AppConfig.CodeVersion,
+		utils.GetEnvBool("AUTH_CODE_ENABLED", true), services.SessionPolicy{
+			TTL:                 utils.GetEnvDuration("AUTH_SESSION_TTL", 30*24*time.Hour),
+			MaxSessions:         utils.GetEnvInt("AUTH_MAX_SESSIONS", 0),
+			RejectOverLimit:     utils.GetEnv("AUTH_SESSION_LIMIT_POLICY", "evict_oldest") == "reject",
+			MaxAuthCodeSessions: utils.GetEnvInt("AUTH_MAX_CODE_SESSIONS", 0),
+		})
+	services.InitPlatformAuth(services.PlatformAuthConfig{
+		JWTSecret:       utils.GetEnv("PLATFORM_JWT_SECRET", ""),
+		JWTIssuer:       utils.GetEnv("PLATFORM_JWT_ISSUER", "developer-platform-backend"),
+		APIKeyVerifyURL: utils.GetEnv("PLATFORM_API_KEY_VERIFY_URL", ""),
+		APIKeyRecheck:   utils.GetEnvDuration("PLATFORM_API_KEY_RECHECK", 10*time.Minute),
+		Timeout:         utils.GetEnvDuration("PLATFORM_TIMEOUT", 5*time.Second),
+	})
+	if !services.Auth.AuthCodeEnabled() && !services.Platform.Enabled() {
+		log.Printf("⚠️ 授权码登录已停用且未配置平台认证，无法登录")
+	}
+	services.InitRoomAccess(services.RoomAccessConfig{
+		Secret:            utils.GetEnv("ROOM_TOKEN_SECRET", ""),
+		TokenTTL:          utils.GetEnvDuration("ROOM_TOKEN_TTL", 10*time.Minute),
+		RoomTTL:           utils.GetEnvDuration("ROOM_TTL", 24*time.Hour),
+		RequireRegistered: utils.GetEnvBool("ROOM_REQUIRE_REGISTERED", false),
+	})
 
-	roomManager := models.NewRoomManager()
+	roomManager := models.NewRoomManager(func(room *models.Room) models.RoomRunner {
+		return services.NewRoomService(room)
+	}, utils.GetEnvDuration("ROOM_IDLE_GRACE", 30*time.Second))
+	roomManager.OnRoomCreated(func(room *models.Room) {
+		log.Printf("🏠 [ROOM %s] 房间已创建 (%s → %s)", room.ID, room.FromLanguage, room.ToLanguage)
+	})
+	roomManager.OnRoomClosed(func(room *models.Room) {
+		log.Printf("🏚️ [ROOM %s] 房间已关闭", room.ID)
+	})
 
-	excludePaths := []string{"/auth", "/auth-status", "/logout", "/", "/index.html", "/standard_time"}
+	excludePaths := []string{"/auth", "/auth-status", "/logout", "/", "/index.html", "/standard_time", "/languages"}
 	authMiddleware := utils.NewAuthMiddleware(excludePaths)
 
 	http.Handle("/auth", utils.WithCORS(http.HandlerFunc(handlers.HandleAuth)))
 	http.Handle("/auth-status", utils.WithCORS(http.HandlerFunc(handlers.HandleAuthStatus)))
 	http.Handle("/logout", utils.WithCORS(http.HandlerFunc(handlers.HandleLogout)))
+	http.Handle("/sessions", utils.WithCORS(authMiddleware.RequireAuth(handlers.ListSessions)))
+	http.Handle("/sessions/", utils.WithCORS(authMiddleware.RequireAuth(handlers.SessionByID)))
+	http.Handle("/languages", utils.WithCORS(http.HandlerFunc(handlers.ListLanguages)))
 	http.Handle("/standard_time", utils.WithCORS(http.HandlerFunc(handlers.HandleStandardTime)))
 
-	http.HandleFunc("/ws", handlers.ServeWS(roomManager))
+	wsConfig := handlers.DefaultWSConfig()
+	wsConfig.Pump.WriteWait = utils.GetEnvDuration("WS_WRITE_WAIT", wsConfig.Pump.WriteWait)
+	wsConfig.Pump.PongWait = utils.GetEnvDuration("WS_PONG_WAIT", wsConfig.Pump.PongWait)
+	wsConfig.Pump.PingPeriod = wsConfig.Pump.PongWait * 9 / 10
+	wsConfig.TextBuffer = utils.GetEnvInt("WS_TEXT_BUFFER", wsConfig.TextBuffer)
+	wsConfig.AudioBuffer = utils.GetEnvInt("WS_AUDIO_BUFFER", wsConfig.AudioBuffer)
+	wsConfig.AllowLegacy = utils.GetEnvBool("WS_LEGACY_PROTOCOL", wsConfig.AllowLegacy)
+	if policy, err := models.ParseSlowConsumerPolicy(utils.GetEnv("WS_SLOW_POLICY", string(wsConfig.Policy))); err == nil {
+		wsConfig.Policy = policy
+	} else {
+		log.Printf("⚠️ %v，使用默认策略 %s", err, wsConfig.Policy)
+	}
+
+	wsConfig.VADEnabled = utils.GetEnvBool("VAD_ENABLED", wsConfig.VADEnabled)
+	wsConfig.VAD.SampleRate = services.Mixing.SampleRate
+	wsConfig.VAD.Hangover = utils.GetEnvDuration("VAD_HANGOVER", wsConfig.VAD.Hangover)
+	if threshold, err := strconv.ParseFloat(utils.GetEnv("VAD_THRESHOLD", ""), 64); err == nil {
+		wsConfig.VAD.EnergyThreshold = threshold
+	}
+
+	wsConfig.Recording.Enabled = utils.GetEnvBool("RECORDING_ENABLED", wsConfig.Recording.Enabled)
+	if err := storage.Init(storage.Config{
+		Backend: utils.GetEnv("STORAGE_BACKEND", "local"),
+		Root:    utils.GetEnv("STORAGE_ROOT", wsConfig.Recording.Dir),
+		S3: storage.S3Config{
+			Endpoint:  utils.GetEnv("S3_ENDPOINT", ""),
+			Region:    utils.GetEnv("S3_REGION", "us-east-1"),
+			Bucket:    utils.GetEnv("S3_BUCKET", ""),
+			AccessKey: utils.GetEnv("S3_ACCESS_KEY", ""),
+			SecretKey: utils.GetEnv("S3_SECRET_KEY", ""),
+			Prefix:    utils.GetEnv("S3_PREFIX", ""),
+			PathStyle: utils.GetEnvBool("S3_PATH_STYLE", true),
+		},
+	}); err != nil {
+		log.Fatalf("❌ 初始化存储后端失败: %v", err)
+	}
+	wsConfig.Recording.Dir = utils.GetEnv("RECORDING_DIR", utils.GetEnv("STORAGE_ROOT", wsConfig.Recording.Dir)) // 本地存储时默认直接录到存储目录
+	wsConfig.Recording.SyncInterval = utils.GetEnvDuration("RECORDING_SYNC_INTERVAL", wsConfig.Recording.SyncInterval)
+	services.InitRecordings(wsConfig.Recording,
+		utils.GetEnvDuration("RECORDING_RETENTION", 0),
+		utils.GetEnvDuration("RECORDING_RETENTION_INTERVAL", time.Hour))
+
+	http.HandleFunc("/ws", handlers.ServeWS(roomManager, wsConfig))
+	adminMiddleware := utils.NewAdminMiddleware(utils.GetEnv("ADMIN_TOKEN", ""), utils.GetEnvList("ADMIN_USER_IDS"))
+	http.Handle("/admin/invites", utils.WithCORS(adminMiddleware.RequireAdmin(handlers.AdminInvites)))
+	http.Handle("/admin/invites/", utils.WithCORS(adminMiddleware.RequireAdmin(handlers.AdminInviteByCode)))
+
+	http.Handle("/rooms", utils.WithCORS(authMiddleware.RequireAuth(handlers.CreateRoom)))
+	http.Handle("/rooms/", utils.WithCORS(http.HandlerFunc(handlers.RoomByID))) // 接受登录会话或加入令牌，由处理函数校验
 
+	http.Handle("/recordings", utils.WithCORS(authMiddleware.RequireAuth(handlers.ListRecordings)))
+	http.Handle("/recordings/", utils.WithCORS(authMiddleware.RequireAuth(handlers.RecordingByID)))
+	http.Handle("/recording-sessions/", utils.WithCORS(authMiddleware.RequireAuth(handlers.RecordingSession)))
 	http.Handle("/audios", utils.WithCORS(authMiddleware.RequireAuth(handlers.ListAudio)))
 	http.Handle("/delete-audio", utils.WithCORS(authMiddleware.RequireAuth(handlers.DeleteAudio)))
-	http.Handle("/audio/", utils.WithCORS(authMiddleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
-		http.StripPrefix("/audio/", http.FileServer(http.Dir("audio"))).ServeHTTP(w, r)
-	})))
+	http.Handle("/audio/", utils.WithCORS(authMiddleware.RequireAuth(handlers.ServeAudio)))
 
 	http.Handle("/system/translation-status", utils.WithCORS(http.HandlerFunc(handlers.GetSystemTranslationStatus)))
 	http.Handle("/system/health", utils.WithCORS(http.HandlerFunc(handlers.GetSystemHealth)))
@@ -39,6 +156,8 @@ func main() {
 	http.HandleFunc("/system/room-status/", func(w http.ResponseWriter, r *http.Request) {
 		utils.WithCORS(http.HandlerFunc(handlers.GetRoomStatus)).ServeHTTP(w, r)
 	})
+	http.Handle("/system/room-language/", utils.WithCORS(adminMiddleware.RequireAdmin(handlers.ChangeRoomLanguage(roomManager)))) // 已登记房间的主持人和临时房间的发言人在房间内使用 change_language 控制消息
+	http.Handle("/system/room-cost/", utils.WithCORS(authMiddleware.RequireAuth(handlers.GetRoomCost)))
 	http.HandleFunc("/system/close-translation/", func(w http.ResponseWriter, r *http.Request) {
 		utils.WithCORS(http.HandlerFunc(handlers.ForceCloseTranslationConnection)).ServeHTTP(w, r)
 	})
@@ -3,29 +3,32 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
+	"go-backEnd/internal/services"
+	"go-backEnd/pkg/storage"
+	"io"
+	"log"
 	"net/http"
-	"os"
-	"path/filepath"
+	"path"
 	"strings"
 )
 
-func ListAudio(w http.ResponseWriter, r *http.Request) {
-	// 确保audio目录存在
-	if err := os.MkdirAll("audio", 0755); err != nil {
-		http.Error(w, "创建音频目录失败", http.StatusInternalServerError)
-		return
-	}
+// isAudioKey 只允许访问音频文件
+func isAudioKey(key string) bool {
+	return strings.HasSuffix(key, ".pcm") || strings.HasSuffix(key, ".wav")
+}
 
-	files, err := os.ReadDir("audio")
+func ListAudio(w http.ResponseWriter, r *http.Request) {
+	objects, err := services.Recordings.Objects(r.Context())
 	if err != nil {
 		http.Error(w, "读取音频目录失败", http.StatusInternalServerError)
+		log.Printf("[AUDIO] ❌ %v", err)
 		return
 	}
 
 	var result []string
-	for _, file := range files {
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".pcm") {
-			result = append(result, "/audio/"+file.Name())
+	for _, obj := range objects {
+		if isAudioKey(obj.Key) { // 录音按房间存放在子目录中
+			result = append(result, "/audio/"+obj.Key)
 		}
 	}
 
@@ -34,15 +37,42 @@ func ListAudio(w http.ResponseWriter, r *http.Request) {
 }
 
 func DeleteAudio(w http.ResponseWriter, r *http.Request) {
-	filename := r.URL.Query().Get("filename")
-	if filename == "" || !strings.HasSuffix(filename, ".pcm") {
+	filename, err := storage.CleanKey(r.URL.Query().Get("filename"))
+	if err != nil || !isAudioKey(filename) {
 		http.Error(w, "缺少或非法文件名", http.StatusBadRequest)
 		return
 	}
-	path := filepath.Join("audio", filename)
-	if err := os.Remove(path); err != nil {
+	if err := services.Recordings.DeleteObject(r.Context(), filename); err != nil { // 已被索引的录音同时删除索引条目
 		http.Error(w, "删除失败", http.StatusInternalServerError)
 		return
 	}
 	fmt.Fprintf(w, "删除成功: %s", filename)
 }
+
+// ServeAudio GET /audio/{key} 从存储后端下载音频文件，支持 Range
+func ServeAudio(w http.ResponseWriter, r *http.Request) {
+	key, err := storage.CleanKey(strings.TrimPrefix(r.URL.Path, "/audio/"))
+	if err != nil || !isAudioKey(key) {
+		http.NotFound(w, r)
+		return
+	}
+	obj, err := storage.Default.Open(r.Context(), key)
+	if err == storage.ErrNotFound {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "读取音频失败", http.StatusInternalServerError)
+		log.Printf("[AUDIO] ❌ %v", err)
+		return
+	}
+	defer obj.Close()
+
+	info := obj.Info()
+	contentType := "application/octet-stream"
+	if strings.HasSuffix(key, ".wav") {
+		contentType = "audio/wav"
+	}
+	w.Header().Set("Content-Type", contentType)
+	http.ServeContent(w, r, path.Base(key), info.ModTime, io.NewSectionReader(obj, 0, info.Size))
+}
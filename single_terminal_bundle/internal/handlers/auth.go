@@ -3,12 +3,18 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
+	"time"
 
 	"go-backEnd/internal/services"
+	"go-backEnd/internal/utils"
 )
 
+// AuthRequest 登录凭证，三者任选其一；平台凭证也可通过 Authorization: Bearer 传入
 type AuthRequest struct {
-	AuthCode string `json:"auth_code"`
+	Token    string `json:"token"`     // 开发者平台登录令牌
+	APIKey   string `json:"api_key"`   // 开发者平台 KF- API 密钥
+	AuthCode string `json:"auth_code"` // 共享授权码，仅在未停用时可用
 }
 
 type AuthResponse struct {
@@ -16,20 +22,34 @@ type AuthResponse struct {
 	Message     string `json:"message"`
 	SessionID   string `json:"session_id,omitempty"`
 	CodeVersion string `json:"code_version,omitempty"`
+	Method      string `json:"method,omitempty"`
+	UserID      string `json:"user_id,omitempty"`
+}
+
+// verifyCredential 优先校验平台凭证，未提供时回退到授权码
+func verifyCredential(r *http.Request, req AuthRequest) (*services.SessionData, error) {
+	credential := req.Token
+	if credential == "" {
+		credential = req.APIKey
+	}
+	if credential == "" {
+		if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+			credential = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+		}
+	}
+	if credential != "" {
+		identity, err := services.Platform.Verify(r.Context(), credential)
+		if err != nil {
+			return nil, err
+		}
+		return services.Auth.CreateUserSession(identity, credential, utils.SessionMeta(r))
+	}
+	return services.Auth.VerifyAuthCode(req.AuthCode, utils.SessionMeta(r))
 }
 
 // createAuthCookie 创建认证Cookie，根据环境配置设置安全属性
 func createAuthCookie(sessionID string, maxAge int) *http.Cookie {
-	return &http.Cookie{
-		Name:     "auth_session",
-		Value:    sessionID,
-		Path:     "/",
-		Domain:   "session.glot.world", // 只针对当前域名
-		HttpOnly: true,
-		Secure:   true, // SameSite=None时必须为true
-		SameSite: http.SameSiteNoneMode,
-		MaxAge:   maxAge,
-	}
+	return utils.AuthCookie(sessionID, maxAge)
 }
 
 // HandleAuth 处理授权码验证
@@ -63,7 +83,7 @@ func HandleAuth(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "https://glot.world")
 	}
 	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 	w.Header().Set("Access-Control-Allow-Credentials", "true")
 	w.Header().Set("Content-Type", "application/json")
 
@@ -95,30 +115,34 @@ func HandleAuth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.AuthCode == "" {
+	if req.Token == "" && req.APIKey == "" && req.AuthCode == "" && r.Header.Get("Authorization") == "" {
 		response := AuthResponse{
 			Success: false,
-			Message: "授权码不能为空",
+			Message: "登录凭证不能为空",
 		}
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	// 验证授权码
-	sessionData, err := services.Auth.VerifyAuthCode(req.AuthCode)
+	// 验证平台凭证或授权码
+	sessionData, err := verifyCredential(r, req)
 	if err != nil {
 		response := AuthResponse{
 			Success: false,
 			Message: err.Error(),
 		}
-		w.WriteHeader(http.StatusUnauthorized)
+		status := http.StatusUnauthorized
+		if err == services.ErrTooManySessions {
+			status = http.StatusTooManyRequests
+		}
+		w.WriteHeader(status)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
 	// 设置Cookie，根据环境配置设置安全属性
-	cookie := createAuthCookie(sessionData.SessionID, 30*24*60*60) // 30天
+	cookie := createAuthCookie(sessionData.SessionID, int(time.Until(sessionData.ExpiresAt).Seconds()))
 	http.SetCookie(w, cookie)
 
 	response := AuthResponse{
@@ -126,6 +150,8 @@ func HandleAuth(w http.ResponseWriter, r *http.Request) {
 		Message:     "认证成功",
 		SessionID:   sessionData.SessionID,
 		CodeVersion: sessionData.CodeVersion,
+		Method:      sessionData.Method,
+		UserID:      sessionData.UserID,
 	}
 	
 	w.WriteHeader(http.StatusOK)
@@ -283,6 +309,8 @@ func HandleAuthStatus(w http.ResponseWriter, r *http.Request) {
 		Message:     "认证有效",
 		SessionID:   sessionData.SessionID,
 		CodeVersion: sessionData.CodeVersion,
+		Method:      sessionData.Method,
+		UserID:      sessionData.UserID,
 	}
 	
 	w.WriteHeader(http.StatusOK)
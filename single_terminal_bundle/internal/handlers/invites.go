@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-backEnd/internal/services"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// createInviteRequest POST /admin/invites 请求体
+type createInviteRequest struct {
+	Code           string     `json:"code"` // 为空时随机生成
+	Label          string     `json:"label"`
+	MaxRedemptions int        `json:"max_redemptions"` // 0 表示不限次数
+	ExpiresAt      *time.Time `json:"expires_at"`      // RFC3339，为空表示不过期
+}
+
+// AdminInvites 邀请码列表与创建：
+//
+//	GET  /admin/invites   列出邀请码
+//	POST /admin/invites   创建邀请码
+func AdminInvites(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var result interface{}
+	status := http.StatusOK
+	switch r.Method {
+	case "GET":
+		invites, err := services.Auth.ListInvites(r.Context())
+		if err != nil {
+			http.Error(w, "Failed to list invites", http.StatusInternalServerError)
+			log.Printf("❌ [INVITE] %v", err)
+			return
+		}
+		result = map[string]interface{}{
+			"invites": invites,
+			"count":   len(invites),
+		}
+	case "POST":
+		var req createInviteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		invite, err := services.Auth.CreateInvite(r.Context(), req.Code, req.Label, req.MaxRedemptions, req.ExpiresAt)
+		if err == services.ErrInviteExists {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, status = invite, http.StatusCreated
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(status)
+	w.Write(jsonData)
+}
+
+// AdminInviteByCode 单个邀请码：
+//
+//	GET    /admin/invites/{code}            邀请码信息
+//	DELETE /admin/invites/{code}            吊销邀请码，已创建的会话保留
+//	GET    /admin/invites/{code}/sessions   由该邀请码创建的会话
+//	DELETE /admin/invites/{code}/sessions   结束由该邀请码创建的全部会话
+func AdminInviteByCode(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/admin/invites/"), "/")
+	if parts[0] == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "sessions") {
+		http.NotFound(w, r)
+		return
+	}
+	code := parts[0]
+
+	invite, err := services.Auth.GetInvite(r.Context(), code)
+	if err != nil {
+		http.Error(w, "Failed to load invite", http.StatusInternalServerError)
+		log.Printf("❌ [INVITE] %v", err)
+		return
+	}
+	if invite == nil {
+		http.Error(w, "Invite not found", http.StatusNotFound)
+		return
+	}
+
+	var result interface{}
+	switch {
+	case len(parts) == 1 && r.Method == "GET":
+		result = invite
+	case len(parts) == 1 && r.Method == "DELETE":
+		if result, err = services.Auth.RevokeInvite(r.Context(), code); err != nil {
+			http.Error(w, "Failed to revoke invite", http.StatusInternalServerError)
+			log.Printf("❌ [INVITE] %v", err)
+			return
+		}
+	case len(parts) == 2 && r.Method == "GET":
+		sessions, err := services.Auth.InviteSessions(r.Context(), code)
+		if err != nil {
+			http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+			log.Printf("❌ [INVITE] %v", err)
+			return
+		}
+		result = map[string]interface{}{
+			"code":     code,
+			"sessions": sessions,
+			"count":    len(sessions),
+		}
+	case len(parts) == 2 && r.Method == "DELETE":
+		killed, err := services.Auth.KillInviteSessions(r.Context(), code)
+		if err != nil {
+			http.Error(w, "Failed to delete sessions", http.StatusInternalServerError)
+			log.Printf("❌ [INVITE] 结束邀请码 %s 的会话失败（已结束 %d 个）: %v", code, killed, err)
+			return
+		}
+		log.Printf("🔒 [INVITE] 已结束邀请码 %s 的 %d 个会话", code, killed)
+		result = map[string]interface{}{
+			"code":   code,
+			"killed": killed,
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
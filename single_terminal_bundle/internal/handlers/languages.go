@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-backEnd/pkg/languages"
+	"net/http"
+)
+
+// languageEntry GET /languages 返回的单个语言，targets 已展开为具体语言代码
+type languageEntry struct {
+	languages.Language
+	Targets []string `json:"targets"`
+}
+
+// ListLanguages 返回支持的语言目录
+func ListLanguages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	all := languages.Default.All()
+	entries := make([]languageEntry, 0, len(all))
+	for _, lang := range all {
+		entries = append(entries, languageEntry{Language: lang, Targets: languages.Default.TargetsOf(lang.Code)})
+	}
+
+	jsonData, err := json.MarshalIndent(map[string]interface{}{
+		"languages": entries,
+		"count":     len(entries),
+	}, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
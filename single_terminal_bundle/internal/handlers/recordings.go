@@ -0,0 +1,336 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-backEnd/internal/services"
+	"go-backEnd/pkg/audio"
+	"go-backEnd/pkg/storage"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 录音列表分页参数
+const (
+	defaultRecordingPageSize = 50
+	maxRecordingPageSize     = 200
+)
+
+// ListRecordings GET /recordings 按房间、客户端、语言和创建时间筛选录音，按创建时间倒序分页返回；
+// 只按房间和时间筛选时返回总数 total，否则只返回 has_more
+func ListRecordings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseRecordingFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := services.Recordings.List(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to list recordings", http.StatusInternalServerError)
+		log.Printf("❌ [RECORDING] 获取录音列表失败: %v", err)
+		return
+	}
+
+	result := map[string]interface{}{
+		"recordings": page.Recordings,
+		"offset":     filter.Offset,
+		"limit":      filter.Limit,
+		"has_more":   page.HasMore,
+	}
+	if page.Total >= 0 { // 按客户端、类型、会话或语言筛选时不统计总数
+		result["total"] = page.Total
+	}
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// parseRecordingFilter 解析列表查询参数，since/until 为 RFC3339 时间
+func parseRecordingFilter(r *http.Request) (services.RecordingFilter, error) {
+	q := r.URL.Query()
+	filter := services.RecordingFilter{
+		RoomID:    q.Get("room_id"),
+		ClientID:  q.Get("client_id"),
+		Kind:      q.Get("kind"),
+		SessionID: q.Get("session_id"),
+		Language:  q.Get("language"),
+		Limit:     defaultRecordingPageSize,
+	}
+	for name, dst := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("%s 必须为 RFC3339 时间", name)
+			}
+			*dst = t
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("offset 必须为非负整数")
+		}
+		filter.Offset = n
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxRecordingPageSize {
+			return filter, fmt.Errorf("limit 必须在 1-%d 之间", maxRecordingPageSize)
+		}
+		filter.Limit = n
+	}
+	return filter, nil
+}
+
+// RecordingByID GET /recordings/{id} 下载录音（支持 Range），?format=wav（默认）或 pcm；DELETE 删除录音
+func RecordingByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/recordings/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Missing recording id", http.StatusBadRequest)
+		return
+	}
+
+	rec, err := services.Recordings.Get(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Failed to load recording", http.StatusInternalServerError)
+		log.Printf("❌ [RECORDING] %v", err)
+		return
+	}
+	if rec == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET", "HEAD":
+		serveRecording(w, r, *rec)
+	case "DELETE":
+		if err := services.Recordings.Delete(r.Context(), *rec); err != nil {
+			http.Error(w, "Failed to delete recording", http.StatusInternalServerError)
+			log.Printf("❌ [RECORDING] %v", err)
+			return
+		}
+		log.Printf("🗑️ [RECORDING] 已删除录音 %s", rec.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// serveRecording 以请求的格式输出录音，WAV 头部按文件当前长度即时生成，录制中的文件也能下载
+func serveRecording(w http.ResponseWriter, r *http.Request, rec services.Recording) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.ContainerWAV
+	}
+	if format != services.ContainerWAV && format != services.ContainerPCM {
+		http.Error(w, "format 必须为 wav 或 pcm", http.StatusBadRequest)
+		return
+	}
+
+	obj, err := services.Recordings.Open(r.Context(), rec)
+	if err == storage.ErrNotFound {
+		http.Error(w, "Recording file not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to open recording", http.StatusInternalServerError)
+		log.Printf("❌ [RECORDING] %v", err)
+		return
+	}
+	defer obj.Close()
+	info := obj.Info()
+
+	dataSize := info.Size - rec.DataOffset()
+	if dataSize < 0 {
+		dataSize = 0
+	}
+	dataSize -= dataSize % int64(rec.Format.BlockAlign()) // 录制中的文件可能停在半个采样上
+	pcm := io.NewSectionReader(obj, rec.DataOffset(), dataSize)
+
+	var content io.ReadSeeker = pcm
+	contentType := "application/octet-stream"
+	if format == services.ContainerWAV {
+		content = audio.WAVReader(rec.Format, pcm)
+		contentType = "audio/wav"
+	}
+	name := fmt.Sprintf("%s.%s", rec.ID, format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("X-Audio-Format", rec.Format.String())
+	http.ServeContent(w, r, name, info.ModTime, content)
+}
+
+// RecordingSession 房间会话录音：
+//
+//	GET /recording-sessions/{id}/clips                      按转写消息划分的片段索引
+//	GET /recording-sessions/{id}/clips/{messageId}?track=   下载单条消息的原声（默认）或译音片段
+//	GET /recording-sessions/{id}/stereo                     立体声导出，左声道原声，右声道译音
+func RecordingSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != "GET" && r.Method != "HEAD" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/recording-sessions/"), "/")
+	switch {
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "clips":
+		listClips(w, r, parts[0])
+	case len(parts) == 3 && parts[0] != "" && parts[1] == "clips" && parts[2] != "":
+		serveClip(w, r, parts[0], parts[2])
+	case len(parts) == 2 && parts[0] != "" && parts[1] == "stereo":
+		serveStereo(w, r, parts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// listClips 返回会话的片段索引
+func listClips(w http.ResponseWriter, r *http.Request, sessionID string) {
+	w.Header().Set("Content-Type", "application/json")
+	clips, err := services.Recordings.Clips(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "Failed to load clips", http.StatusInternalServerError)
+		log.Printf("❌ [RECORDING] %v", err)
+		return
+	}
+	jsonData, err := json.MarshalIndent(map[string]interface{}{
+		"session_id": sessionID,
+		"clips":      clips,
+		"count":      len(clips),
+	}, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// serveClip 以 WAV 输出单条消息在某条音轨中的片段，支持 Range
+func serveClip(w http.ResponseWriter, r *http.Request, sessionID, messageID string) {
+	track := r.URL.Query().Get("track")
+	if track == "" {
+		track = services.TrackSource
+	}
+	if track != services.TrackSource && track != services.TrackTranslation {
+		http.Error(w, "track 必须为 source 或 translation", http.StatusBadRequest)
+		return
+	}
+
+	clips, err := services.Recordings.Clips(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "Failed to load clips", http.StatusInternalServerError)
+		log.Printf("❌ [RECORDING] %v", err)
+		return
+	}
+	var clip *services.Clip
+	for i := range clips {
+		if clips[i].MessageID == messageID {
+			clip = &clips[i]
+			break
+		}
+	}
+	if clip == nil {
+		http.Error(w, "Clip not found", http.StatusNotFound)
+		return
+	}
+	startMs, endMs := clip.SourceStartMs, clip.SourceEndMs
+	if track == services.TrackTranslation {
+		startMs, endMs = clip.TranslationStartMs, clip.TranslationEndMs
+	}
+	if startMs < 0 || endMs <= startMs {
+		http.Error(w, "Clip has no audio on this track", http.StatusNotFound)
+		return
+	}
+
+	rec, err := services.Recordings.SessionTrack(r.Context(), sessionID, track)
+	if err != nil || rec == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+	obj, err := services.Recordings.Open(r.Context(), *rec)
+	if err != nil {
+		http.Error(w, "Recording file not found", http.StatusNotFound)
+		return
+	}
+	defer obj.Close()
+
+	dataSize := obj.Info().Size - rec.DataOffset()
+	start := rec.Format.BytesAt(time.Duration(startMs) * time.Millisecond)
+	end := rec.Format.BytesAt(time.Duration(endMs) * time.Millisecond)
+	if end > dataSize {
+		end = dataSize - dataSize%int64(rec.Format.BlockAlign())
+	}
+	if start >= end {
+		http.Error(w, "Clip has no audio on this track", http.StatusNotFound)
+		return
+	}
+
+	name := fmt.Sprintf("%s_%s.wav", messageID, track)
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	content := audio.WAVReader(rec.Format, io.NewSectionReader(obj, rec.DataOffset()+start, end-start))
+	http.ServeContent(w, r, name, obj.Info().ModTime, content)
+}
+
+// serveStereo 边转换边输出立体声 WAV，长度预先确定，不支持 Range
+func serveStereo(w http.ResponseWriter, r *http.Request, sessionID string) {
+	exp, err := services.Recordings.OpenStereoExport(r.Context(), sessionID)
+	if err != nil {
+		http.Error(w, "Failed to open recording", http.StatusInternalServerError)
+		log.Printf("❌ [RECORDING] %v", err)
+		return
+	}
+	if exp == nil {
+		http.Error(w, "Recording not found", http.StatusNotFound)
+		return
+	}
+	defer exp.Close()
+
+	header := audio.WAVHeader(exp.Format, exp.Size)
+	w.Header().Set("Content-Type", "audio/wav")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", sessionID+"_stereo.wav"))
+	w.Header().Set("Content-Length", strconv.FormatInt(int64(len(header))+exp.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "HEAD" {
+		return
+	}
+	if _, err := w.Write(header); err != nil {
+		return
+	}
+	if _, err := exp.WriteTo(w); err != nil {
+		log.Printf("❌ [RECORDING] 导出会话 %s 立体声失败: %v", sessionID, err)
+	}
+}
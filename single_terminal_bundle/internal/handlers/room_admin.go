@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-backEnd/internal/models"
+	"go-backEnd/internal/services"
+	"go-backEnd/pkg/protocol"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ChangeRoomLanguage 管理端切换房间语言对，房间在本实例时经房间控制通道交由房间服务执行，
+// 集群模式下房间在其他实例时发布语言对切换广播
+func ChangeRoomLanguage(manager *models.RoomManager) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		roomID := strings.TrimPrefix(r.URL.Path, "/system/room-language/")
+		if roomID == "" {
+			http.Error(w, "Missing roomId parameter", http.StatusBadRequest)
+			return
+		}
+
+		var payload protocol.ChangeLanguagePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		body, _ := json.Marshal(payload)
+
+		room := manager.Lookup(roomID)
+		if room == nil {
+			err := services.PublishLanguageChange(r.Context(), roomID, payload.FromLanguage, payload.ToLanguage)
+			switch {
+			case err == services.ErrRoomNotActive:
+				http.Error(w, "Room not found", http.StatusNotFound)
+			case err != nil:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				writeLanguageChanged(w, roomID, payload)
+			}
+			return
+		}
+
+		req := models.ControlRequest{
+			Type:    protocol.ControlChangeLanguage,
+			Payload: body,
+			Reply:   make(chan error, 1),
+		}
+		timeout := time.After(5 * time.Second)
+		select {
+		case room.Control <- req:
+		case <-room.Done():
+			http.Error(w, "Room not found", http.StatusNotFound)
+			return
+		case <-timeout:
+			http.Error(w, "Room busy", http.StatusServiceUnavailable)
+			return
+		}
+
+		var err error
+		select {
+		case err = <-req.Reply:
+		case <-timeout:
+			http.Error(w, "Room busy", http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		writeLanguageChanged(w, roomID, payload)
+	}
+}
+
+// writeLanguageChanged 记录并返回语言对切换结果
+func writeLanguageChanged(w http.ResponseWriter, roomID string, payload protocol.ChangeLanguagePayload) {
+	log.Printf("✅ [SYSTEM_MONITOR] 房间 %s 语言对切换为 %s → %s", roomID, payload.FromLanguage, payload.ToLanguage)
+	jsonData, _ := json.MarshalIndent(map[string]interface{}{
+		"success":       true,
+		"room_id":       roomID,
+		"from_language": payload.FromLanguage,
+		"to_language":   payload.ToLanguage,
+		"timestamp":     time.Now(),
+	}, "", "  ")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
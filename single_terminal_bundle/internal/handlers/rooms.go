@@ -0,0 +1,280 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-backEnd/internal/models"
+	"go-backEnd/internal/services"
+	"go-backEnd/internal/utils"
+	"go-backEnd/pkg/languages"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// createRoomRequest POST /rooms 请求体
+type createRoomRequest struct {
+	Mode         string `json:"mode"`
+	FromLanguage string `json:"from_language"`
+	ToLanguage   string `json:"to_language"`
+	Private      *bool  `json:"private"` // 未指定时为私有房间
+}
+
+// CreateRoom POST /rooms 登记房间，返回服务端生成的房间ID及主持人、发言人、听众三种加入令牌
+func CreateRoom(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req createRoomRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	mode, err := models.ParseRoomMode(req.Mode)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := languages.Default.ValidatePair(req.FromLanguage, req.ToLanguage); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	private := req.Private == nil || *req.Private
+
+	session, err := currentSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	info, err := services.Rooms.Create(r.Context(), session.Principal(), string(mode), req.FromLanguage, req.ToLanguage, private)
+	if err != nil {
+		http.Error(w, "Failed to create room", http.StatusInternalServerError)
+		log.Printf("❌ [ROOMS] %v", err)
+		return
+	}
+
+	// 只返回创建者自己的主持人令牌，发言人和听众令牌通过 POST /rooms/{id}/tokens 为每位参与者单独签发
+	token, expiresAt, err := services.Rooms.IssueToken(r.Context(), info.ID, services.TokenRoleHost)
+	if err != nil {
+		http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		log.Printf("❌ [ROOMS] %v", err)
+		return
+	}
+	log.Printf("🏠 [ROOMS] 已登记房间 %s (%s, %s → %s, private=%v)", info.ID, info.Mode, info.FromLanguage, info.ToLanguage, info.Private)
+
+	jsonData, err := json.MarshalIndent(map[string]interface{}{
+		"room":             info,
+		"tokens":           map[string]string{services.TokenRoleHost: token},
+		"token_expires_at": expiresAt,
+	}, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	w.Write(jsonData)
+}
+
+// RoomByID 已登记房间，凭登录会话或该房间的加入令牌（Authorization: Bearer 或 ?token=）访问：
+//
+//	GET  /rooms/{id}          房间信息
+//	GET  /rooms/{id}/tokens   已签发的加入令牌记录
+//	POST /rooms/{id}/tokens   为一位参与者签发加入令牌，请求体 {"role": "host|speaker|listener"}
+//
+// 令牌接口只允许房间创建者或主持人令牌持有者调用
+func RoomByID(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/rooms/"), "/")
+	if parts[0] == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "tokens") {
+		http.NotFound(w, r)
+		return
+	}
+
+	info, err := services.Rooms.Get(r.Context(), parts[0])
+	if err != nil {
+		http.Error(w, "Failed to load room", http.StatusInternalServerError)
+		log.Printf("❌ [ROOMS] %v", err)
+		return
+	}
+	if info == nil {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	caller, status, err := roomCaller(r, info)
+	if err != nil {
+		if status == http.StatusInternalServerError {
+			log.Printf("❌ [ROOMS] %v", err)
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	if len(parts) == 2 && !caller.manager {
+		http.Error(w, "只有房间创建者或主持人可以管理加入令牌", http.StatusForbidden)
+		return
+	}
+
+	var result interface{}
+	switch {
+	case len(parts) == 1 && r.Method == "GET":
+		result = info
+	case len(parts) == 2 && r.Method == "GET":
+		tokens, err := services.Rooms.ListTokens(r.Context(), info.ID)
+		if err != nil {
+			http.Error(w, "Failed to list tokens", http.StatusInternalServerError)
+			log.Printf("❌ [ROOMS] %v", err)
+			return
+		}
+		result = map[string]interface{}{
+			"room_id": info.ID,
+			"tokens":  tokens,
+			"count":   len(tokens),
+		}
+	case len(parts) == 2 && r.Method == "POST":
+		var body struct {
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		token, expiresAt, err := services.Rooms.IssueToken(r.Context(), info.ID, body.Role)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result = map[string]interface{}{
+			"room_id":    info.ID,
+			"role":       body.Role,
+			"token":      token,
+			"expires_at": expiresAt,
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// roomAccess 访问房间接口的调用方
+type roomAccess struct {
+	manager bool // 房间创建者或主持人令牌持有者
+}
+
+// roomCaller 校验调用方：登录会话，或属于该房间且未被移出的加入令牌
+func roomCaller(r *http.Request, info *services.RoomInfo) (roomAccess, int, error) {
+	var caller roomAccess
+	token := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); token == "" && strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if token != "" {
+		claims, err := services.Rooms.ParseToken(token)
+		if err != nil {
+			return caller, http.StatusUnauthorized, err
+		}
+		if claims.Room != info.ID {
+			return caller, http.StatusForbidden, fmt.Errorf("加入令牌不属于房间 %s", info.ID)
+		}
+		kicked, err := services.Rooms.Kicked(r.Context(), info.ID, services.TokenIdentity(claims.ID))
+		if err != nil {
+			return caller, http.StatusInternalServerError, err
+		}
+		if kicked {
+			return caller, http.StatusForbidden, fmt.Errorf("已被移出房间")
+		}
+		caller.manager = claims.Role == services.TokenRoleHost
+		return caller, http.StatusOK, nil
+	}
+
+	session, err := currentSession(r)
+	if err != nil {
+		return caller, http.StatusUnauthorized, fmt.Errorf("认证失败: %w", err)
+	}
+	caller.manager = info.Owner != "" && session.Principal() == info.Owner
+	return caller, http.StatusOK, nil
+}
+
+// joinGrant 加入房间时校验得到的房间信息和身份
+type joinGrant struct {
+	info      *services.RoomInfo   // 已登记房间的信息，临时房间为 nil
+	claims    *services.JoinClaims // 加入令牌声明，使用登录会话加入时为 nil
+	principal string               // 登录会话身份，凭令牌加入且未登录时为空
+}
+
+// authorizeJoin 校验加入权限：携带 token 时凭加入令牌加入，否则需要登录会话；
+// 私有房间必须使用令牌，锁定的房间只允许主持人加入，被移出的令牌或会话不能重新加入。失败时返回对应的 HTTP 状态码
+func authorizeJoin(r *http.Request, roomID string) (joinGrant, int, error) {
+	var grant joinGrant
+	if cookie, err := r.Cookie("auth_session"); err == nil { // 凭令牌加入时登录会话可选，用于移出后阻止凭会话重新加入
+		if session, err := utils.RefreshAuthSession(nil, r, cookie.Value); err == nil {
+			grant.principal = session.Principal()
+		}
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		claims, err := services.Rooms.ParseToken(token)
+		if err != nil {
+			return grant, http.StatusUnauthorized, err
+		}
+		if claims.Room != roomID {
+			return grant, http.StatusForbidden, fmt.Errorf("加入令牌不属于房间 %s", roomID)
+		}
+		grant.claims = claims
+	} else if grant.principal == "" {
+		return grant, http.StatusUnauthorized, fmt.Errorf("认证失败: 未找到有效的登录会话")
+	}
+
+	var tokenIdentity string
+	if grant.claims != nil {
+		tokenIdentity = services.TokenIdentity(grant.claims.ID)
+	}
+	kicked, err := services.Rooms.Kicked(r.Context(), roomID, tokenIdentity, grant.principal)
+	if err != nil {
+		return grant, http.StatusInternalServerError, err
+	}
+	if kicked {
+		return grant, http.StatusForbidden, fmt.Errorf("已被移出房间")
+	}
+
+	info, err := services.Rooms.Get(r.Context(), roomID)
+	if err != nil {
+		return grant, http.StatusInternalServerError, err
+	}
+	if info == nil {
+		if grant.claims != nil || services.Rooms.RequireRegistered() { // 令牌对应的房间已过期
+			return grant, http.StatusNotFound, fmt.Errorf("房间 %s 不存在", roomID)
+		}
+		return grant, http.StatusOK, nil
+	}
+	grant.info = info
+
+	if grant.claims == nil && info.Private {
+		return grant, http.StatusForbidden, fmt.Errorf("私有房间需要加入令牌")
+	}
+	if info.Locked && (grant.claims == nil || grant.claims.Role != services.TokenRoleHost) {
+		return grant, http.StatusLocked, fmt.Errorf("房间已锁定")
+	}
+	return grant, http.StatusOK, nil
+}
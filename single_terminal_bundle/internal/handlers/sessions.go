@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"go-backEnd/internal/services"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// sessionView 会话列表项，标记发起请求的会话
+type sessionView struct {
+	services.SessionData
+	Current bool `json:"current"`
+}
+
+// currentSession 读取发起请求的会话，认证中间件已校验过
+func currentSession(r *http.Request) (*services.SessionData, error) {
+	cookie, err := r.Cookie("auth_session")
+	if err != nil {
+		return nil, err
+	}
+	return services.Auth.ValidateSession(cookie.Value)
+}
+
+// ListSessions GET /sessions 列出当前用户已登录的设备，最近创建的在前
+func ListSessions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	current, err := currentSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	sessions, err := services.Auth.OwnSessions(current)
+	if err != nil {
+		http.Error(w, "Failed to list sessions", http.StatusInternalServerError)
+		log.Printf("❌ [SESSION] %v", err)
+		return
+	}
+
+	views := make([]sessionView, len(sessions))
+	for i, s := range sessions {
+		views[i] = sessionView{SessionData: s, Current: s.SessionID == current.SessionID}
+	}
+	jsonData, err := json.MarshalIndent(map[string]interface{}{
+		"sessions": views,
+		"count":    len(views),
+	}, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// SessionByID DELETE /sessions/{id} 注销当前用户的某个设备，注销当前会话时同时清除Cookie
+func SessionByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/sessions/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Missing session id", http.StatusBadRequest)
+		return
+	}
+
+	current, err := currentSession(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	err = services.Auth.DeleteOwnSession(current, id)
+	if err == services.ErrSessionNotFound {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Failed to delete session", http.StatusInternalServerError)
+		log.Printf("❌ [SESSION] %v", err)
+		return
+	}
+
+	if id == current.SessionID {
+		http.SetCookie(w, createAuthCookie("", -1))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -93,6 +93,45 @@ func GetRoomStatus(w http.ResponseWriter, r *http.Request) {
 	log.Printf("✅ [SYSTEM_MONITOR] 房间状态查询成功: %s", roomID)
 }
 
+// GetRoomCost 获取房间累计的反向翻译费用，房间关闭后仍可查询
+func GetRoomCost(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "OPTIONS" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	roomID := strings.TrimPrefix(r.URL.Path, "/system/room-cost/")
+	if roomID == "" {
+		http.Error(w, "Missing roomId parameter", http.StatusBadRequest)
+		return
+	}
+
+	cost, err := services.RoomCost(r.Context(), roomID)
+	if err != nil {
+		http.Error(w, "Failed to load room cost", http.StatusInternalServerError)
+		log.Printf("❌ [SYSTEM_MONITOR] 读取房间费用失败: %v", err)
+		return
+	}
+	if len(cost) == 0 {
+		http.Error(w, "Room not found", http.StatusNotFound)
+		return
+	}
+
+	jsonData, err := json.MarshalIndent(map[string]interface{}{
+		"room_id": roomID,
+		"cost":    cost,
+	}, "", "  ")
+	if err != nil {
+		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
 // ForceCloseTranslationConnection 强制关闭特定房间的翻译连接
 func ForceCloseTranslationConnection(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -1,51 +1,330 @@
 package handlers
 
 import (
+	"fmt"
 	"go-backEnd/internal/models"
 	"go-backEnd/internal/services"
-	"go-backEnd/internal/utils"
+	"go-backEnd/pkg/audio"
+	"go-backEnd/pkg/languages"
+	"go-backEnd/pkg/protocol"
 	websocketPkg "go-backEnd/pkg/websocket"
 	"log"
 	"net/http"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+var upgrader = websocket.Upgrader{
+	CheckOrigin:  func(r *http.Request) bool { return true },
+	Subprotocols: []string{protocol.SubprotocolV1},
+}
+
+// WSConfig 客户端 WebSocket 连接配置
+type WSConfig struct {
+	Pump        websocketPkg.PumpConfig   // 读写超时与保活
+	TextBuffer  int                       // 文本消息发送缓冲
+	AudioBuffer int                       // 音频帧发送缓冲
+	Policy      models.SlowConsumerPolicy // 默认慢消费者策略，可通过 slow_policy 参数覆盖
+	AllowLegacy bool                      // 是否允许未协商子协议的客户端使用旧格式
+	VADEnabled  bool                      // 是否在送往上游前抑制静音
+	VAD         audio.VADConfig           // 语音活动检测配置
+	Recording   audio.RecordingConfig     // 麦克风录音配置
+}
+
+// DefaultWSConfig 返回默认配置
+func DefaultWSConfig() WSConfig {
+	return WSConfig{
+		Pump:        websocketPkg.DefaultPumpConfig(),
+		TextBuffer:  256,
+		AudioBuffer: 256,
+		Policy:      models.PolicyDropAudio,
+		AllowLegacy: true,
+		VADEnabled:  true,
+		VAD:         audio.DefaultVADConfig(),
+		Recording:   audio.DefaultRecordingConfig(),
+	}
+}
 
-func ServeWS(manager *models.RoomManager) http.HandlerFunc {
+func ServeWS(manager *models.RoomManager, cfg WSConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// 验证认证状态
-		if err := utils.ValidateWebSocketAuth(r); err != nil {
-			log.Printf("❌ [WS] 认证失败: %v", err)
-			http.Error(w, "认证失败", http.StatusUnauthorized)
+		roomID := strings.TrimPrefix(r.URL.Query().Get("room_id"), "/")
+
+		// 验证加入权限：加入令牌或登录会话
+		grant, status, err := authorizeJoin(r, roomID)
+		if err != nil {
+			log.Printf("❌ [WS] 房间 %s 拒绝加入: %v", roomID, err)
+			if status == http.StatusInternalServerError {
+				http.Error(w, "服务暂不可用", status)
+			} else {
+				http.Error(w, err.Error(), status)
+			}
 			return
 		}
 
-		roomID := strings.TrimPrefix(r.URL.Query().Get("room_id"), "/")
 		fromLang := strings.TrimPrefix(r.URL.Query().Get("from_language"), "/")
 		toLang := r.URL.Query().Get("to_language")
+		if grant.info != nil { // 已登记房间可省略语言参数，按登记的语言对加入
+			if fromLang == "" {
+				fromLang = grant.info.FromLanguage
+			}
+			if toLang == "" {
+				toLang = grant.info.ToLanguage
+			}
+		}
+
+		// role=listener 只接收 to_language 的转写和译音，加入已有房间时可省略 from_language
+		role, err := models.ParseClientRole(r.URL.Query().Get("role"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if grant.claims != nil && grant.claims.Role == services.TokenRoleListener { // 听众令牌只能收听
+			role = models.RoleListener
+		}
+		if role == models.RoleListener && fromLang == "" {
+			if existing := manager.Lookup(roomID); existing != nil {
+				fromLang, _ = existing.Languages()
+			}
+		}
 
 		if roomID == "" || fromLang == "" || toLang == "" {
 			http.Error(w, "缺少参数（room_id, from_language, to_language）", http.StatusBadRequest)
 			return
 		}
 
+		// 建立连接前校验语言对，避免上游以 4001 关闭后才发现不支持
+		if err := languages.Default.ValidatePair(fromLang, toLang); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		displayName, err := parseDisplayName(r.URL.Query().Get("display_name"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		input, output, err := parseAudioFormats(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		codec, decoder, err := negotiateCodec(r.URL.Query().Get("codec"), input, output)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		policy := cfg.Policy
+		if name := r.URL.Query().Get("slow_policy"); name != "" {
+			p, err := models.ParseSlowConsumerPolicy(name)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			policy = p
+		}
+
+		// mode=dual 创建双终端房间，两个终端各说语言对中的一种语言；显式指定时必须与已有房间一致，已登记房间使用登记的类型
+		modeParam := r.URL.Query().Get("mode")
+		if grant.info != nil && modeParam == "" {
+			modeParam = grant.info.Mode
+		}
+		mode, err := models.ParseRoomMode(modeParam)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if grant.info != nil && mode != models.RoomMode(grant.info.Mode) {
+			http.Error(w, fmt.Sprintf("房间类型为 %s，与请求不一致", grant.info.Mode), http.StatusConflict)
+			return
+		}
+		if modeParam != "" {
+			if existing := manager.Lookup(roomID); existing != nil && existing.Mode != mode {
+				http.Error(w, fmt.Sprintf("房间类型为 %s，与请求不一致", existing.Mode), http.StatusConflict)
+				return
+			}
+		}
+
+		// 双终端房间没有听众会话，听众只能收听两个方向之一
+		if role == models.RoleListener {
+			if existing := manager.Lookup(roomID); existing != nil {
+				if _, ok := existing.ListenerUpstream(toLang); !ok {
+					from, to := existing.Languages()
+					http.Error(w, fmt.Sprintf("双终端房间的听众只能选择 %s 或 %s", from, to), http.StatusConflict)
+					return
+				}
+			}
+		}
+
+		// strict_language=true 时拒绝发言人加入语言对不一致的已有房间（双终端房间接受相反方向），否则按房间语言对加入并提示
+		if r.URL.Query().Get("strict_language") == "true" && role == models.RoleSpeaker {
+			if existing := manager.Lookup(roomID); existing != nil {
+				from, to := existing.Languages()
+				reversed := existing.Mode == models.ModeDual && from == toLang && to == fromLang
+				if (from != fromLang || to != toLang) && !reversed {
+					http.Error(w, fmt.Sprintf("房间语言对为 %s → %s，与请求不一致", from, to), http.StatusConflict)
+					return
+				}
+			}
+		}
+
+		proto, err := protocol.Negotiate(websocket.Subprotocols(r), cfg.AllowLegacy)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
 			log.Println("WebSocket 升级失败:", err)
 			return
 		}
-		client := &models.Client{Conn: conn, Send: make(chan []byte, 256)}
-		room := manager.GetRoom(roomID, fromLang, toLang)
+		client := models.NewClient(uuid.New().String(), conn, proto, policy, cfg.TextBuffer, cfg.AudioBuffer)
+		client.RequestedFrom, client.RequestedTo = fromLang, toLang
+		client.Name = displayName
+		client.Role = role
+		if grant.claims != nil {
+			client.Host = grant.claims.Role == services.TokenRoleHost
+			client.TokenID = grant.claims.ID
+		}
+		client.Principal = grant.principal
+		client.SetFormats(input, output)
+		client.Codec, client.Decoder = codec, decoder
+		if cfg.VADEnabled && role == models.RoleSpeaker {
+			client.VAD = audio.NewVAD(cfg.VAD)
+		}
+		recording := startRecording(cfg.Recording, client, roomID, fromLang, toLang)
+		room := manager.Join(roomID, mode, fromLang, toLang) // 房间服务由 RoomManager 统一创建，每个房间只有一个
+		client.Upstream = room.UpstreamFor(fromLang)         // 双终端房间按发言语言分配到对应方向
+		if role == models.RoleListener {                     // 听众收听所选语言的会话，同一语言的听众共用
+			if key, ok := room.ListenerUpstream(toLang); ok {
+				client.Upstream = key
+			}
+		}
 
-		// 创建房间服务并启动运行协程
-		roomService := services.NewRoomService(room)
-		go roomService.Run()
+		select {
+		case room.Register <- client:
+		case <-room.Done(): // 房间服务已退出
+			manager.Leave(room)
+			if client.Recorder != nil {
+				_ = client.Recorder.Close()
+			}
+			finishRecording(recording)
+			_ = conn.Close()
+			return
+		}
+		go websocketPkg.WritePump(client, cfg.Pump)
+		go func() {
+			websocketPkg.ReadPump(client, room, cfg.Pump)
+			manager.Leave(room)
+			finishRecording(recording)
+		}()
+	}
+}
+
+// activeRecording 客户端本次连接的录音及其索引条目
+type activeRecording struct {
+	recorder *audio.Recorder
+	entry    services.Recording
+}
 
-		room.Register <- client
-		go websocketPkg.WritePump(client)
-		go websocketPkg.ReadPump(client, room)
+// startRecording 为客户端创建录音文件并写入索引，录音打不开时返回 nil，照常翻译
+func startRecording(cfg audio.RecordingConfig, client *models.Client, roomID, fromLang, toLang string) *activeRecording {
+	if !cfg.Enabled || client.Role == models.RoleListener { // 听众不发送音频
+		return nil
+	}
+	now := time.Now()
+	recorder, err := audio.NewRecorder(cfg.Path(roomID, client.ID, now), audio.UpstreamInput, cfg.SyncInterval)
+	if err != nil {
+		log.Printf("[AUDIO] ❌ %v", err)
+		return nil
+	}
+	client.Recorder = recorder
+
+	entry := services.Recording{
+		ID:           uuid.New().String(),
+		Kind:         services.KindClient,
+		RoomID:       roomID,
+		ClientID:     client.ID,
+		Speaker:      client.Name,
+		FromLanguage: fromLang,
+		ToLanguage:   toLang,
+		Format:       recorder.Format(),
+		Container:    services.ContainerWAV,
+		Path:         services.Recordings.RelPath(recorder.Path()),
+		CreatedAt:    now,
+	}
+	if err := services.Recordings.Save(services.Ctx, entry); err != nil { // 索引失败不影响录音文件
+		log.Printf("[AUDIO] ❌ %v", err)
+	}
+	return &activeRecording{recorder: recorder, entry: entry}
+}
+
+// finishRecording 录音关闭后把时长和大小写入索引
+func finishRecording(rec *activeRecording) {
+	if rec == nil {
+		return
+	}
+	if err := services.Recordings.Finish(services.Ctx, rec.entry, rec.recorder.Size(), rec.recorder.Duration()); err != nil {
+		log.Printf("[AUDIO] ❌ %v", err)
+	}
+}
+
+// maxDisplayNameRunes 显示名称最大字符数
+const maxDisplayNameRunes = 64
+
+// parseDisplayName 校验客户端显示名称，未提供时返回空串，由转写消息按语言推断发言人
+func parseDisplayName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxDisplayNameRunes {
+		return "", fmt.Errorf("display_name 不能超过 %d 个字符", maxDisplayNameRunes)
+	}
+	for _, ch := range name {
+		if unicode.IsControl(ch) || ch == ':' { // 冒号用作上下文中的发言人分隔符
+			return "", fmt.Errorf("display_name 包含非法字符")
+		}
+	}
+	return name, nil
+}
+
+// parseAudioFormats 解析客户端声明的音频格式：sample_rate/channels/encoding 为麦克风输入，
+// output_rate/output_channels/output_encoding 为播放输出，未声明时分别使用上游输入格式和默认输出格式
+func parseAudioFormats(r *http.Request) (input, output audio.Format, err error) {
+	q := r.URL.Query()
+	input, err = audio.ParseFormat(q.Get("sample_rate"), q.Get("channels"), q.Get("encoding"), audio.UpstreamInput)
+	if err != nil {
+		return input, output, fmt.Errorf("输入音频格式: %w", err)
+	}
+	output, err = audio.ParseFormat(q.Get("output_rate"), q.Get("output_channels"), q.Get("output_encoding"), audio.DefaultClientOutput)
+	if err != nil {
+		return input, output, fmt.Errorf("输出音频格式: %w", err)
+	}
+	return input, output, nil
+}
+
+// negotiateCodec 校验客户端请求的音频编码，codec=opus 时麦克风和播放方向均使用 Opus
+func negotiateCodec(codec string, input, output audio.Format) (string, audio.PacketDecoder, error) {
+	if codec == "" || codec == audio.CodecPCM {
+		return audio.CodecPCM, nil, nil
+	}
+	if err := audio.ValidateCodec(codec, input); err != nil {
+		return "", nil, fmt.Errorf("输入音频: %w", err)
+	}
+	if err := audio.ValidateCodec(codec, output); err != nil {
+		return "", nil, fmt.Errorf("输出音频: %w", err)
+	}
+	decoder, err := audio.NewOpusDecoder(input)
+	if err != nil {
+		return "", nil, err
+	}
+	if _, err := audio.NewOpusEncoder(output); err != nil { // 房间按输出格式创建编码器，握手时先确认可用
+		return "", nil, fmt.Errorf("输出音频: %w", err)
 	}
+	return codec, decoder, nil
 }
@@ -1,10 +1,154 @@
 package models
 
 import (
+	"fmt"
+	"go-backEnd/pkg/audio"
+	"sync/atomic"
+
 	"github.com/gorilla/websocket"
 )
 
+// SlowConsumerPolicy 客户端发送缓冲区写满时的处理策略
+type SlowConsumerPolicy string
+
+const (
+	// PolicyDropAudio 优先丢弃音频帧，文本消息缓冲区写满时才断开
+	PolicyDropAudio SlowConsumerPolicy = "drop_audio"
+	// PolicyDisconnect 任意缓冲区写满立即断开
+	PolicyDisconnect SlowConsumerPolicy = "disconnect"
+)
+
+// ParseSlowConsumerPolicy 解析策略名称
+func ParseSlowConsumerPolicy(name string) (SlowConsumerPolicy, error) {
+	switch SlowConsumerPolicy(name) {
+	case PolicyDropAudio, PolicyDisconnect:
+		return SlowConsumerPolicy(name), nil
+	default:
+		return "", fmt.Errorf("未知的慢消费者策略: %s", name)
+	}
+}
+
+// ClientRole 客户端在房间中的角色
+type ClientRole string
+
+const (
+	// RoleSpeaker 发言人：麦克风音频送往上游，接收房间的转写和译音
+	RoleSpeaker ClientRole = "speaker"
+	// RoleListener 听众：只接收所选语言的转写和译音，不发送音频
+	RoleListener ClientRole = "listener"
+)
+
+// ParseClientRole 解析客户端角色，空串表示发言人
+func ParseClientRole(name string) (ClientRole, error) {
+	switch ClientRole(name) {
+	case "":
+		return RoleSpeaker, nil
+	case RoleSpeaker, RoleListener:
+		return ClientRole(name), nil
+	default:
+		return "", fmt.Errorf("未知的客户端角色: %s", name)
+	}
+}
+
 type Client struct {
-	Conn *websocket.Conn
-	Send chan []byte
+	ID        string
+	Name      string // 显示名称，用作转写消息的发言人
+	Conn      *websocket.Conn
+	Send      chan Outbound // 文本消息通道，WritePump 优先写出
+	Audio     chan Outbound // 音频帧通道，按策略可丢弃
+	Policy    SlowConsumerPolicy
+	Protocol  string // 协商的协议，见 protocol.SubprotocolV1 / protocol.Legacy
+	Role      ClientRole
+	Host      bool   // 凭主持人令牌加入，可移出参与者和锁定房间
+	TokenID   string // 加入令牌ID，未使用令牌时为空
+	Principal string // 登录会话身份，见 services.SessionData.Principal，未登录时为空
+
+	Input          audio.Format        // 客户端声明的麦克风音频格式
+	Output         audio.Format        // 客户端请求的播放音频格式
+	Codec          string              // 双向音频编码，见 audio.CodecPCM / audio.CodecOpus
+	Decoder        audio.PacketDecoder // 麦克风音频解码器，Codec 为 PCM 时为 nil，只由 ReadPump 使用
+	InputConverter *audio.Processor    // 麦克风音频到上游输入格式的转换器，只由 ReadPump 使用
+	VAD            *audio.VAD          // 语音活动检测，nil 表示不做静音抑制，只由 ReadPump 使用
+	Recorder       *audio.Recorder     // 麦克风录音（上游输入格式），nil 表示不录音，只由 ReadPump 写入和关闭
+
+	Upstream      string // 发言人的麦克风音频送往、听众所收听的上游会话，加入房间前设置
+	RequestedFrom string // 加入时请求的源语言
+	RequestedTo   string // 加入时请求的目标语言
+
+	muted        atomic.Bool  // 客户端是否静音（静音时不转发音频）
+	droppedAudio atomic.Int64 // 已丢弃的音频帧数
+}
+
+// NewClient 创建客户端，textBuffer/audioBuffer 为两条发送通道的容量
+func NewClient(id string, conn *websocket.Conn, proto string, policy SlowConsumerPolicy, textBuffer, audioBuffer int) *Client {
+	return &Client{
+		ID:       id,
+		Conn:     conn,
+		Send:     make(chan Outbound, textBuffer),
+		Audio:    make(chan Outbound, audioBuffer),
+		Policy:   policy,
+		Protocol: proto,
+		Role:     RoleSpeaker,
+		Codec:    audio.CodecPCM,
+		Input:    audio.UpstreamInput,
+		Output:   audio.DefaultClientOutput,
+	}
+}
+
+// SetFormats 设置客户端声明的输入输出格式，输入格式与上游不一致时创建转换器
+func (c *Client) SetFormats(input, output audio.Format) {
+	c.Input, c.Output = input, output
+	c.InputConverter = nil
+	if input != audio.UpstreamInput {
+		c.InputConverter = audio.NewFormatProcessor(input, audio.UpstreamInput)
+	}
+}
+
+// Speaker 返回客户端对应的发言人
+func (c *Client) Speaker() Speaker {
+	return Speaker{ID: c.ID, Name: c.Name}
+}
+
+// SetMuted 设置静音状态
+func (c *Client) SetMuted(muted bool) {
+	c.muted.Store(muted)
+}
+
+// Muted 返回是否静音
+func (c *Client) Muted() bool {
+	return c.muted.Load()
+}
+
+// EnqueueResult 投递结果
+type EnqueueResult int
+
+const (
+	Enqueued EnqueueResult = iota // 已放入发送通道
+	Dropped                       // 按策略丢弃
+	Overflow                      // 缓冲区写满，需要断开客户端
+)
+
+// Enqueue 按客户端策略投递已编码的消息，只能由房间主循环调用
+func (c *Client) Enqueue(kind FrameKind, out Outbound) EnqueueResult {
+	ch := c.Send
+	if kind == FrameAudio {
+		ch = c.Audio
+	}
+
+	select {
+	case ch <- out:
+		return Enqueued
+	default:
+	}
+
+	if kind == FrameAudio && c.Policy == PolicyDropAudio {
+		c.droppedAudio.Add(1)
+		return Dropped
+	}
+	return Overflow
+}
+
+// DroppedAudioFrames 返回已丢弃的音频帧数
+func (c *Client) DroppedAudioFrames() int64 {
+	return c.droppedAudio.Load()
 }
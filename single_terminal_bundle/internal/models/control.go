@@ -0,0 +1,12 @@
+package models
+
+import "encoding/json"
+
+// ControlRequest 客户端（或管理接口）发往房间服务的控制请求，由房间主循环串行处理
+type ControlRequest struct {
+	Client  *Client         // 发起请求的客户端，管理接口发起时为 nil
+	Type    string          // 控制类型，见 protocol.Control*
+	Payload json.RawMessage // 控制负载
+	Err     error           // 控制消息解析失败时的错误，房间服务据此回复 error 事件
+	Reply   chan error      // 非 nil 时房间服务处理完成后回写结果
+}
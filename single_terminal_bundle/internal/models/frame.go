@@ -0,0 +1,97 @@
+package models
+
+import (
+	"go-backEnd/pkg/protocol"
+
+	"github.com/gorilla/websocket"
+)
+
+// FrameKind 广播帧类型
+type FrameKind int
+
+const (
+	FrameText  FrameKind = iota // JSON 文本消息
+	FrameAudio                  // PCM 音频
+)
+
+// Frame 房间广播帧，与客户端协议无关，写出前按客户端协商的协议编码
+type Frame struct {
+	Kind   FrameKind `json:"kind"`
+	Type   string    `json:"type"`             // 协议事件类型，见 protocol.Event*
+	Seq    uint64    `json:"seq"`              // 房间广播序号，单播回复为 0
+	Data   []byte    `json:"data"`             // 文本帧为事件负载 JSON，音频帧为 PCM
+	Legacy []byte    `json:"legacy,omitempty"` // 旧格式客户端收到的内容，文本帧为 nil 时不发给旧格式客户端
+
+	Upstream string `json:"upstream,omitempty"` // 产生该帧的上游会话，房间事件为空，投递时按客户端角色和所属会话过滤
+}
+
+// TextFrame 构造负载与旧格式一致的文本帧
+func TextFrame(eventType string, payload []byte) Frame {
+	return Frame{Kind: FrameText, Type: eventType, Data: payload, Legacy: payload}
+}
+
+// EventFrame 构造文本帧，legacy 为旧格式客户端收到的内容（nil 表示不发送）
+func EventFrame(eventType string, payload, legacy []byte) Frame {
+	return Frame{Kind: FrameText, Type: eventType, Data: payload, Legacy: legacy}
+}
+
+// AudioFrame 构造音频帧
+func AudioFrame(pcm []byte) Frame {
+	return Frame{Kind: FrameAudio, Type: protocol.EventAudio, Data: pcm}
+}
+
+// Outbound 已按客户端协议编码、等待 WritePump 写出的消息
+type Outbound struct {
+	MessageType int
+	Data        []byte
+}
+
+// Encode 按协议编码帧，返回 false 表示该协议的客户端不接收此帧
+func (f Frame) Encode(proto string) (Outbound, bool) {
+	if proto == protocol.Legacy {
+		if f.Kind == FrameAudio {
+			return Outbound{MessageType: websocket.BinaryMessage, Data: f.Data}, true
+		}
+		if f.Legacy == nil {
+			return Outbound{}, false
+		}
+		return Outbound{MessageType: websocket.BinaryMessage, Data: f.Legacy}, true
+	}
+
+	if f.Kind == FrameAudio {
+		return Outbound{MessageType: websocket.BinaryMessage, Data: protocol.EncodeAudio(f.Seq, f.Data)}, true
+	}
+	data, err := protocol.EncodeEvent(f.Type, f.Seq, f.Data)
+	if err != nil {
+		return Outbound{}, false
+	}
+	return Outbound{MessageType: websocket.TextMessage, Data: data}, true
+}
+
+// FrameEncoder 广播时按协议缓存同一帧的编码结果
+type FrameEncoder struct {
+	frame Frame
+	cache map[string]*Outbound
+}
+
+// NewFrameEncoder 创建帧编码缓存
+func NewFrameEncoder(frame Frame) *FrameEncoder {
+	return &FrameEncoder{frame: frame, cache: make(map[string]*Outbound, 2)}
+}
+
+// For 返回指定协议的编码结果
+func (e *FrameEncoder) For(proto string) (Outbound, bool) {
+	if out, ok := e.cache[proto]; ok {
+		if out == nil {
+			return Outbound{}, false
+		}
+		return *out, true
+	}
+	out, ok := e.frame.Encode(proto)
+	if !ok {
+		e.cache[proto] = nil
+		return Outbound{}, false
+	}
+	e.cache[proto] = &out
+	return out, true
+}
@@ -1,60 +1,185 @@
 package models
 
 import (
+	"go-backEnd/pkg/audio"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/dh1tw/gosamplerate"
-	"github.com/gorilla/websocket"
 )
 
 type Room struct {
 	ID           string
-	FromLanguage string
-	ToLanguage   string
+	FromLanguage string       // 源语言，房间创建后通过 Languages/SetLanguages 读写
+	ToLanguage   string       // 目标语言
+	LangMu       sync.RWMutex // 保护语言对，会话中可切换
 
 	Clients    map[*Client]bool
 	Register   chan *Client
 	Unregister chan *Client
-	Broadcast  chan []byte
+	Broadcast  chan Frame
+	Control    chan ControlRequest
 
-	ClientAudioBuffers sync.Map
+	Mode            RoomMode          // 房间类型，创建后不变
+	OnVoiceActivity VoiceActivityHook // 客户端语音活动回调，由房间服务设置
+
+	upstreamMu    sync.RWMutex
+	upstreams     map[string]*Upstream                     // 按会话标识索引的上游翻译会话，由房间服务按房间类型创建
+	RelayMu       sync.Mutex                               // 保护 UpstreamRelay
+	UpstreamRelay func(Speaker, string, []byte, time.Time) // 集群模式下非 owner 实例用于转发音频的函数，参数为发言人、上游会话标识、音频和采集时间
 
-	TranslationWS   *websocket.Conn
-	TranslationMux  sync.Mutex
-	TranslationLock sync.Mutex
-	ReconnectLock   sync.Mutex
-	IsReconnecting  bool
 	ShouldStopTrans bool
 
-	Src                  *gosamplerate.Src
-	SrcMu                sync.Mutex
-	TranslationQueueLock sync.Mutex
+	Src   *gosamplerate.Src
+	SrcMu sync.Mutex
+
+	Service   RoomRunner    // 房间唯一的服务实例，由 RoomManager 创建
+	done      chan struct{} // 房间服务退出后关闭
+	refs      int           // 已加入且尚未离开的连接数，受 RoomManager.Mu 保护
+	idleTimer *time.Timer   // 空闲关闭计时器，受 RoomManager.Mu 保护
 }
 
-type RoomManager struct {
-	Rooms map[string]*Room
-	Mu    sync.RWMutex
+// VoiceActivityHook 语音活动回调：状态变化及被静音抑制的音频时长
+type VoiceActivityHook func(sp Speaker, event audio.VADEvent, suppressed time.Duration)
+
+// ReportVoiceActivity 上报客户端语音活动
+func (r *Room) ReportVoiceActivity(sp Speaker, event audio.VADEvent, suppressed time.Duration) {
+	if r.OnVoiceActivity == nil || (event == audio.VADNone && suppressed == 0) {
+		return
+	}
+	r.OnVoiceActivity(sp, event, suppressed)
 }
 
-func NewRoomManager() *RoomManager {
-	return &RoomManager{Rooms: make(map[string]*Room)}
+// Languages 返回当前语言对
+func (r *Room) Languages() (from, to string) {
+	r.LangMu.RLock()
+	defer r.LangMu.RUnlock()
+	return r.FromLanguage, r.ToLanguage
 }
 
-func (rm *RoomManager) GetRoom(id, fromLang, toLang string) *Room {
-	rm.Mu.Lock()
-	defer rm.Mu.Unlock()
-	if room, ok := rm.Rooms[id]; ok {
-		return room
+// SetLanguages 切换语言对，返回是否发生变化
+func (r *Room) SetLanguages(from, to string) bool {
+	r.LangMu.Lock()
+	defer r.LangMu.Unlock()
+	if r.FromLanguage == from && r.ToLanguage == to {
+		return false
 	}
-	room := &Room{
-		ID:           id,
-		FromLanguage: fromLang,
-		ToLanguage:   toLang,
-		Clients:      make(map[*Client]bool),
-		Register:     make(chan *Client),
-		Unregister:   make(chan *Client),
-		Broadcast:    make(chan []byte),
+	r.FromLanguage, r.ToLanguage = from, to
+	return true
+}
+
+// AddUpstream 登记一路上游翻译会话，已存在同名会话时不做处理，返回是否新增
+func (r *Room) AddUpstream(up *Upstream) bool {
+	r.upstreamMu.Lock()
+	defer r.upstreamMu.Unlock()
+	if r.upstreams == nil {
+		r.upstreams = make(map[string]*Upstream)
+	}
+	if _, ok := r.upstreams[up.Key]; ok {
+		return false
 	}
-	rm.Rooms[id] = room
-	return room
+	r.upstreams[up.Key] = up
+	return true
+}
+
+// Upstream 按标识查找上游翻译会话，不存在时返回 nil
+func (r *Room) Upstream(key string) *Upstream {
+	r.upstreamMu.RLock()
+	defer r.upstreamMu.RUnlock()
+	return r.upstreams[key]
+}
+
+// RemoveUpstream 移除并关闭上游翻译会话，返回会话是否存在
+func (r *Room) RemoveUpstream(key string) bool {
+	r.upstreamMu.Lock()
+	up, ok := r.upstreams[key]
+	delete(r.upstreams, key)
+	r.upstreamMu.Unlock()
+	if ok {
+		up.Close()
+	}
+	return ok
+}
+
+// Upstreams 返回房间的所有上游翻译会话，按标识排序
+func (r *Room) Upstreams() []*Upstream {
+	r.upstreamMu.RLock()
+	defer r.upstreamMu.RUnlock()
+	ups := make([]*Upstream, 0, len(r.upstreams))
+	for _, up := range r.upstreams {
+		ups = append(ups, up)
+	}
+	sort.Slice(ups, func(i, j int) bool { return ups[i].Key < ups[j].Key })
+	return ups
+}
+
+// UpstreamFor 返回以指定语言发言的客户端对应的上游会话标识：
+// 双终端房间中说目标语言的一方为 B，其余情况为 A
+func (r *Room) UpstreamFor(fromLang string) string {
+	if r.Mode != ModeDual {
+		return UpstreamA
+	}
+	if _, toLang := r.Languages(); fromLang == toLang {
+		return UpstreamB
+	}
+	return UpstreamA
+}
+
+// ListenerUpstream 返回选择指定语言的听众所收听的上游会话：与已有方向的目标语言相同时直接收听该方向，
+// 单终端房间的其他语言使用对应的听众会话，双终端房间只能收听两种房间语言之一
+func (r *Room) ListenerUpstream(toLang string) (string, bool) {
+	from, to := r.Languages()
+	switch {
+	case toLang == to:
+		return UpstreamA, true
+	case r.Mode == ModeDual && toLang == from:
+		return UpstreamB, true
+	case r.Mode == ModeDual:
+		return "", false
+	default:
+		return ListenerUpstream(toLang), true
+	}
+}
+
+// UpstreamLanguages 返回上游会话的翻译方向，B 与房间语言对相反，听众会话从源语言翻译为所选语言
+func (r *Room) UpstreamLanguages(key string) (from, to string) {
+	from, to = r.Languages()
+	switch {
+	case key == UpstreamB:
+		return to, from
+	case IsListenerUpstream(key):
+		return from, strings.TrimPrefix(key, listenerUpstreamPrefix)
+	}
+	return from, to
+}
+
+// ForwardAudio 将发言人的音频发送到指定上游会话（送往 A 的音频同时送往所有听众会话），
+// 本实例未持有连接时交给 UpstreamRelay 转发。captured 为音频最后一个采样的采集时间，混音时据此对齐各发言人
+func (r *Room) ForwardAudio(sp Speaker, key string, data []byte, captured time.Time) {
+	if key == "" {
+		key = UpstreamA
+	}
+	forwarded := false
+	r.upstreamMu.RLock()
+	for k, up := range r.upstreams {
+		if k == key || (key == UpstreamA && IsListenerUpstream(k)) {
+			forwarded = up.Forward(sp, data, captured) || forwarded
+		}
+	}
+	r.upstreamMu.RUnlock()
+	if forwarded {
+		return
+	}
+	r.RelayMu.Lock()
+	defer r.RelayMu.Unlock()
+	if r.UpstreamRelay != nil {
+		r.UpstreamRelay(sp, key, data, captured)
+	}
+}
+
+// Done 返回房间服务退出后关闭的通道，向 Register/Unregister 发送时用于避免永久阻塞
+func (r *Room) Done() <-chan struct{} {
+	return r.done
 }
@@ -0,0 +1,181 @@
+package models
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// RoomRunner 房间服务接口，每个房间恰好持有一个实例
+type RoomRunner interface {
+	Run()  // 房间主循环，阻塞直到 Stop 被调用
+	Stop() // 停止房间服务及其所有协程
+}
+
+// RoomHook 房间创建/关闭回调
+type RoomHook func(room *Room)
+
+// RoomManager 管理进程内的所有房间及其服务生命周期
+//
+// 每个房间在首次 Join 时创建并启动唯一的 RoomService；最后一个连接 Leave 后
+// 经过 idleGrace 宽限期仍无人加入，则停止服务并从 Rooms 中删除。
+type RoomManager struct {
+	Rooms map[string]*Room
+	Mu    sync.RWMutex
+
+	newService func(*Room) RoomRunner
+	idleGrace  time.Duration
+	onCreate   []RoomHook
+	onClose    []RoomHook
+}
+
+// NewRoomManager 创建房间管理器，newService 用于为新房间创建服务
+func NewRoomManager(newService func(*Room) RoomRunner, idleGrace time.Duration) *RoomManager {
+	return &RoomManager{
+		Rooms:      make(map[string]*Room),
+		newService: newService,
+		idleGrace:  idleGrace,
+	}
+}
+
+// OnRoomCreated 注册房间创建回调，回调在房间服务启动后执行
+func (rm *RoomManager) OnRoomCreated(hook RoomHook) {
+	rm.Mu.Lock()
+	defer rm.Mu.Unlock()
+	rm.onCreate = append(rm.onCreate, hook)
+}
+
+// OnRoomClosed 注册房间关闭回调，回调在房间服务停止后执行
+func (rm *RoomManager) OnRoomClosed(hook RoomHook) {
+	rm.Mu.Lock()
+	defer rm.Mu.Unlock()
+	rm.onClose = append(rm.onClose, hook)
+}
+
+// Lookup 查找已存在的房间，不存在时返回 nil
+func (rm *RoomManager) Lookup(id string) *Room {
+	rm.Mu.RLock()
+	defer rm.Mu.RUnlock()
+	return rm.Rooms[id]
+}
+
+// Join 获取或创建房间并登记一个连接，调用方断开时必须调用 Leave；mode 只在创建房间时生效
+func (rm *RoomManager) Join(id string, mode RoomMode, fromLang, toLang string) *Room {
+	rm.Mu.Lock()
+	if room, ok := rm.Rooms[id]; ok {
+		room.refs++
+		if room.idleTimer != nil { // 宽限期内有人重新加入，取消关闭
+			room.idleTimer.Stop()
+			room.idleTimer = nil
+		}
+		rm.Mu.Unlock()
+		return room
+	}
+
+	room := &Room{
+		ID:           id,
+		Mode:         mode,
+		FromLanguage: fromLang,
+		ToLanguage:   toLang,
+		Clients:      make(map[*Client]bool),
+		Register:     make(chan *Client),
+		Unregister:   make(chan *Client),
+		Broadcast:    make(chan Frame),
+		Control:      make(chan ControlRequest),
+		done:         make(chan struct{}),
+		refs:         1,
+	}
+	room.Service = rm.newService(room)
+	rm.Rooms[id] = room
+	hooks := append([]RoomHook(nil), rm.onCreate...)
+	rm.Mu.Unlock()
+
+	go rm.runService(room)
+	for _, hook := range hooks {
+		hook(room)
+	}
+	return room
+}
+
+// Leave 注销一个连接，房间无连接时启动空闲关闭计时
+func (rm *RoomManager) Leave(room *Room) {
+	rm.Mu.Lock()
+	defer rm.Mu.Unlock()
+
+	if room.refs > 0 {
+		room.refs--
+	}
+	if room.refs > 0 || rm.Rooms[room.ID] != room {
+		return
+	}
+
+	if room.idleTimer != nil {
+		room.idleTimer.Stop()
+	}
+	room.idleTimer = time.AfterFunc(rm.idleGrace, func() {
+		rm.closeIfIdle(room)
+	})
+}
+
+// CloseRoom 立即关闭房间（无论是否仍有连接），返回房间是否存在
+func (rm *RoomManager) CloseRoom(id string) bool {
+	rm.Mu.Lock()
+	room, ok := rm.Rooms[id]
+	if !ok {
+		rm.Mu.Unlock()
+		return false
+	}
+	rm.detachLocked(room)
+	rm.Mu.Unlock()
+
+	rm.stopRoom(room)
+	return true
+}
+
+// closeIfIdle 宽限期结束后仍无连接时关闭房间
+func (rm *RoomManager) closeIfIdle(room *Room) {
+	rm.Mu.Lock()
+	if room.refs > 0 || rm.Rooms[room.ID] != room {
+		rm.Mu.Unlock()
+		return
+	}
+	rm.detachLocked(room)
+	rm.Mu.Unlock()
+
+	log.Printf("🧹 [ROOM %s] 空闲超过 %s，关闭房间", room.ID, rm.idleGrace)
+	rm.stopRoom(room)
+}
+
+// detachLocked 从管理器中移除房间，调用方需持有 Mu
+func (rm *RoomManager) detachLocked(room *Room) {
+	if room.idleTimer != nil {
+		room.idleTimer.Stop()
+		room.idleTimer = nil
+	}
+	delete(rm.Rooms, room.ID)
+}
+
+// stopRoom 停止房间服务并执行关闭回调
+func (rm *RoomManager) stopRoom(room *Room) {
+	room.Service.Stop()
+
+	rm.Mu.RLock()
+	hooks := append([]RoomHook(nil), rm.onClose...)
+	rm.Mu.RUnlock()
+	for _, hook := range hooks {
+		hook(room)
+	}
+}
+
+// runService 运行房间服务，服务意外退出时将房间移出管理器，后续加入者会得到新房间
+func (rm *RoomManager) runService(room *Room) {
+	room.Service.Run()
+	close(room.done)
+
+	rm.Mu.Lock()
+	defer rm.Mu.Unlock()
+	if rm.Rooms[room.ID] == room {
+		log.Printf("⚠️ [ROOM %s] 房间服务已退出，移出房间管理器", room.ID)
+		rm.detachLocked(room)
+	}
+}
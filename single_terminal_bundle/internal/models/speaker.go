@@ -0,0 +1,85 @@
+package models
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Speaker 发言人，随每条转写消息下发
+type Speaker struct {
+	ID   string `json:"id"`   // 客户端ID
+	Name string `json:"name"` // 加入时提供的显示名称
+}
+
+// speakerActivity 单个发言人的近期音频能量
+type speakerActivity struct {
+	speaker Speaker
+	score   float64   // 按时间衰减的累计能量
+	last    time.Time // 最近一次更新时间
+}
+
+// SpeakerTracker 根据各客户端近期送往上游的音频能量推断当前发言人
+//
+// 上游翻译结果不携带音频来源，这里按能量做指数衰减累计，能量最高者视为当前发言人。
+type SpeakerTracker struct {
+	mu       sync.Mutex
+	window   time.Duration // 衰减时间常数，超过该时长未发声的客户端不再参与推断
+	activity map[string]*speakerActivity
+}
+
+// NewSpeakerTracker 创建发言人推断器
+func NewSpeakerTracker(window time.Duration) *SpeakerTracker {
+	if window <= 0 {
+		window = 3 * time.Second
+	}
+	return &SpeakerTracker{window: window, activity: make(map[string]*speakerActivity)}
+}
+
+// Observe 记录发言人一帧音频的能量（RMS）
+func (t *SpeakerTracker) Observe(sp Speaker, energy float64, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	act, ok := t.activity[sp.ID]
+	if !ok {
+		act = &speakerActivity{}
+		t.activity[sp.ID] = act
+	}
+	act.speaker = sp
+	act.score = t.decayed(act, now) + energy
+	act.last = now
+}
+
+// Dominant 返回当前能量最高的发言人
+func (t *SpeakerTracker) Dominant(now time.Time) (Speaker, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var best *speakerActivity
+	var bestScore float64
+	for _, act := range t.activity {
+		if now.Sub(act.last) > t.window {
+			continue
+		}
+		if score := t.decayed(act, now); score > bestScore {
+			best, bestScore = act, score
+		}
+	}
+	if best == nil {
+		return Speaker{}, false
+	}
+	return best.speaker, true
+}
+
+// Forget 客户端离开时移除其记录
+func (t *SpeakerTracker) Forget(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.activity, id)
+}
+
+func (t *SpeakerTracker) decayed(act *speakerActivity, now time.Time) float64 {
+	if act.last.IsZero() {
+		return 0
+	}
+	return act.score * math.Exp(-float64(now.Sub(act.last))/float64(t.window))
+}
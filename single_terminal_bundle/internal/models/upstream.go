@@ -0,0 +1,188 @@
+package models
+
+import (
+	"fmt"
+	"go-backEnd/pkg/audio"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// RoomMode 房间类型
+type RoomMode string
+
+const (
+	// ModeSingle 单终端：所有客户端说源语言，共用一路上游翻译会话
+	ModeSingle RoomMode = "single"
+	// ModeDual 双终端：终端 A 说源语言、终端 B 说目标语言，两个方向各有一路上游翻译会话
+	ModeDual RoomMode = "dual"
+)
+
+// ParseRoomMode 解析房间类型，空串表示单终端
+func ParseRoomMode(name string) (RoomMode, error) {
+	switch RoomMode(name) {
+	case "":
+		return ModeSingle, nil
+	case ModeSingle, ModeDual:
+		return RoomMode(name), nil
+	default:
+		return "", fmt.Errorf("未知的房间类型: %s", name)
+	}
+}
+
+// MonitorType 返回监控中使用的房间类型名称
+func (m RoomMode) MonitorType() string {
+	if m == ModeDual {
+		return "dual_terminal"
+	}
+	return "single"
+}
+
+// 上游翻译会话标识
+const (
+	UpstreamA = "a" // 源语言 → 目标语言，单终端房间只有这一路
+	UpstreamB = "b" // 目标语言 → 源语言，只在双终端房间中存在
+)
+
+// listenerUpstreamPrefix 听众会话标识前缀，后接目标语言
+const listenerUpstreamPrefix = "to:"
+
+// ListenerUpstream 返回把源语言翻译为指定语言的听众会话标识
+//
+// 听众会话与 A 共用发言人的音频，只为选择其他语言的听众翻译，同一语言的听众共用一路。
+func ListenerUpstream(toLang string) string {
+	return listenerUpstreamPrefix + toLang
+}
+
+// IsListenerUpstream 判断是否为听众会话
+func IsListenerUpstream(key string) bool {
+	return strings.HasPrefix(key, listenerUpstreamPrefix)
+}
+
+// upstreamWriteWait 向上游写入一帧的超时时间，超时后关闭连接，由读取协程重连
+const upstreamWriteWait = 5 * time.Second
+
+// Upstream 房间内的一路上游翻译会话：一条上游连接、送往该连接的混音及其发言人推断
+type Upstream struct {
+	Key      string          // 房间内唯一的会话标识，见 UpstreamA / UpstreamB / ListenerUpstream
+	Speakers *SpeakerTracker // 按送往该会话的音频能量推断当前发言人
+	Mixer    *audio.Mixer    // 将该会话的多个客户端麦克风混为一路，由混音协程按帧写入上游
+
+	DialLock sync.Mutex // 串行化建立连接
+
+	writeMu    sync.Mutex // 串行化向上游连接的写入，写入期间不持有 mu，避免阻塞 Forward
+	mu         sync.Mutex // 保护 conn 和 connecting
+	conn       *websocket.Conn
+	connecting bool
+}
+
+// NewUpstream 创建上游翻译会话，连接由房间服务建立
+func NewUpstream(key string, mixing audio.MixerConfig) *Upstream {
+	return &Upstream{
+		Key:      key,
+		Speakers: NewSpeakerTracker(0),
+		Mixer:    audio.NewMixer(mixing),
+	}
+}
+
+// Conn 返回当前上游连接，未连接时返回 nil
+func (u *Upstream) Conn() *websocket.Conn {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.conn
+}
+
+// SetConn 保存新建立的上游连接
+func (u *Upstream) SetConn(conn *websocket.Conn) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.conn = conn
+}
+
+// Detach 清空指定连接，连接已被替换时不做处理（切换语言时可能已建立新连接）
+func (u *Upstream) Detach(conn *websocket.Conn) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.conn == conn {
+		u.conn = nil
+	}
+}
+
+// Is 判断指定连接是否仍为当前上游连接
+func (u *Upstream) Is(conn *websocket.Conn) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.conn == conn
+}
+
+// BeginConnect 标记开始建立连接，已连接或正在连接时返回 false
+func (u *Upstream) BeginConnect() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.conn != nil || u.connecting {
+		return false
+	}
+	u.connecting = true
+	return true
+}
+
+// EndConnect 清除连接中标记
+func (u *Upstream) EndConnect() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.connecting = false
+}
+
+// Forward 将发言人的音频按采集时间送入混音，未持有上游连接时返回 false
+func (u *Upstream) Forward(sp Speaker, data []byte, captured time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.conn == nil {
+		return false
+	}
+	u.Speakers.Observe(sp, audio.RMS(data), captured) // 只在持有上游连接的实例上推断发言人
+	u.Mixer.Write(sp.ID, data, captured)
+	return true
+}
+
+// Write 向指定上游连接写入音频，连接已被替换或关闭时返回 false
+//
+// 写入失败或超时时关闭连接，读取协程随之出错并负责重连。
+func (u *Upstream) Write(conn *websocket.Conn, data []byte) bool {
+	if !u.Is(conn) {
+		return false
+	}
+	if err := u.writeTo(conn, data); err != nil {
+		_ = conn.Close()
+		return false
+	}
+	return true
+}
+
+// writeTo 带超时写入一条二进制消息
+func (u *Upstream) writeTo(conn *websocket.Conn, data []byte) error {
+	u.writeMu.Lock()
+	defer u.writeMu.Unlock()
+	_ = conn.SetWriteDeadline(time.Now().Add(upstreamWriteWait))
+	return conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// Close 向上游发送结束消息并关闭连接
+func (u *Upstream) Close() {
+	u.mu.Lock()
+	conn := u.conn
+	u.conn = nil
+	u.mu.Unlock()
+	if conn != nil {
+		_ = u.writeTo(conn, []byte("END")) // 发送结束消息
+		_ = conn.Close()
+	}
+}
+
+// Forget 客户端离开时移除其发言人记录和混音来源
+func (u *Upstream) Forget(clientID string) {
+	u.Speakers.Forget(clientID)
+	u.Mixer.Remove(clientID)
+}
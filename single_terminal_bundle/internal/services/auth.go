@@ -3,8 +3,10 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"go-backEnd/internal/config"
@@ -13,21 +15,143 @@ import (
 )
 
 type AuthService struct {
-	rdbAuth     *redis.Client // 专门用于认证的Redis客户端（DB1）
-	authCode    string
-	codeVersion string
+	rdbAuth       *redis.Client // 专门用于认证的Redis客户端（DB1）
+	authCode      string
+	codeVersion   string
+	allowAuthCode bool // 是否仍接受共享授权码登录
+	policy        SessionPolicy
 }
 
+// SessionPolicy 会话有效期与并发上限
+type SessionPolicy struct {
+	TTL                 time.Duration // 空闲过期时间，每次使用会话时顺延
+	MaxSessions         int           // 同一平台用户同时有效的会话数上限，0 表示不限；邀请码会话由邀请码的兑换次数限制
+	RejectOverLimit     bool          // 超出上限时拒绝登录，否则注销最久未使用的会话
+	MaxAuthCodeSessions int           // 共享授权码会话的全局上限，0 表示不限；持有者互不相识，达到上限时只拒绝登录
+}
+
+// SessionMeta 创建或使用会话的客户端信息
+type SessionMeta struct {
+	IP        string
+	UserAgent string
+}
+
+// ErrTooManySessions 同时有效的会话数已达上限
+var ErrTooManySessions = errors.New("同时登录的设备数已达上限，请先退出其他设备")
+
+// ErrSessionNotFound 会话不存在或不属于当前用户
+var ErrSessionNotFound = errors.New("会话不存在")
+
 type SessionData struct {
 	SessionID   string    `json:"session_id"`
 	CodeVersion string    `json:"code_version"`
+	Method      string    `json:"method,omitempty"`  // 认证方式，旧会话为空，视为授权码
+	UserID      string    `json:"user_id,omitempty"` // 平台用户ID，授权码会话为空
+	Email       string    `json:"email,omitempty"`
+	InviteCode  string    `json:"invite_code,omitempty"` // 创建该会话的邀请码
+	IP          string    `json:"ip,omitempty"`
+	UserAgent   string    `json:"user_agent,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
 	ExpiresAt   time.Time `json:"expires_at"`
+
+	CredentialExpiresAt *time.Time `json:"credential_expires_at,omitempty"` // 平台令牌的过期时间，会话有效期不超过它
+	VerifiedAt          *time.Time `json:"verified_at,omitempty"`           // API 密钥会话最近一次向平台校验的时间
+}
+
+// storedSession 会话在 Redis 中的存储格式，额外保存用于重新校验的 API 密钥，不出现在任何响应中
+type storedSession struct {
+	SessionData
+	Credential string `json:"credential,omitempty"`
 }
 
+// sessionTouchInterval 使用会话时最多每隔这么久写回一次最近使用时间和有效期
+const sessionTouchInterval = time.Minute
+
+// authCodeSessionsKey 共享授权码会话ID集合
+const authCodeSessionsKey = "auth_code_sessions"
+
+// createSessionScript 原子地检查并发上限并写入会话
+//
+// 会话集合为 Sorted Set，分数为最近使用时间（毫秒）。先移除已过期的会话，数量达到上限时拒绝（返回 false）
+// 或注销最久未使用的会话，返回被注销的会话ID。会话有效期可能被凭证过期时间截短，集合按空闲过期时间清理。
+// KEYS: 会话集合, 新会话；ARGV: 会话数据, 有效期毫秒, 当前时间毫秒, 上限, 是否拒绝, 会话ID, 空闲过期毫秒
+var createSessionScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', tonumber(ARGV[3]) - tonumber(ARGV[7]))
+local max = tonumber(ARGV[4])
+local evicted = {}
+if max > 0 then
+	for _, id in ipairs(redis.call('ZRANGE', KEYS[1], 0, -1)) do
+		if redis.call('EXISTS', 'auth_session:' .. id) == 0 then redis.call('ZREM', KEYS[1], id) end
+	end
+	local excess = redis.call('ZCARD', KEYS[1]) - max + 1
+	if excess > 0 then
+		if ARGV[5] == '1' then return false end
+		evicted = redis.call('ZRANGE', KEYS[1], 0, excess - 1)
+		for _, id in ipairs(evicted) do
+			redis.call('DEL', 'auth_session:' .. id)
+			redis.call('ZREM', KEYS[1], id)
+		end
+	end
+end
+redis.call('SET', KEYS[2], ARGV[1], 'PX', ARGV[2])
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[6])
+redis.call('PEXPIRE', KEYS[1], ARGV[7])
+return evicted
+`)
+
+// refreshSessionScript 只在会话仍存在时写回，避免已被删除的会话在刷新时复活
+//
+// KEYS: 会话, 会话集合；ARGV: 会话数据, 有效期毫秒, 当前时间毫秒, 会话ID, 空闲过期毫秒
+var refreshSessionScript = redis.NewScript(`
+if not redis.call('SET', KEYS[1], ARGV[1], 'XX', 'PX', ARGV[2]) then return 0 end
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[4])
+redis.call('PEXPIRE', KEYS[2], ARGV[5])
+return 1
+`)
+
 var Auth *AuthService
 
-func InitAuthService(rdb *redis.Client, authCode, codeVersion string) {
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("auth_session:%s", sessionID)
+}
+
+// userSessionsKey 用户的会话ID集合
+func userSessionsKey(userID string) string {
+	return fmt.Sprintf("auth_user_sessions:%s", userID)
+}
+
+// isAuthCodeSession 授权码会话受 codeVersion 约束，平台账号会话不受影响
+func (s *SessionData) isAuthCodeSession() bool {
+	return s.Method == "" || s.Method == AuthMethodCode
+}
+
+// isAnonymous 授权码和邀请码会话没有账号，同一授权码或邀请码的持有者互不相识，只能看到和注销自己的会话
+func (s *SessionData) isAnonymous() bool {
+	return s.UserID == ""
+}
+
+// ownerSessionsKey 会话所属的会话集合：平台用户、邀请码或共享授权码，平台用户的并发上限按集合计算
+func (s *SessionData) ownerSessionsKey() string {
+	switch {
+	case s.UserID != "":
+		return userSessionsKey(s.UserID)
+	case s.InviteCode != "":
+		return inviteSessionsKey(s.InviteCode)
+	default:
+		return authCodeSessionsKey
+	}
+}
+
+// Principal 会话所属的身份：平台用户为 user:<用户ID>，授权码和邀请码会话没有账号，为 session:<会话ID>
+func (s *SessionData) Principal() string {
+	if s.UserID != "" {
+		return "user:" + s.UserID
+	}
+	return "session:" + s.SessionID
+}
+
+func InitAuthService(rdb *redis.Client, authCode, codeVersion string, allowAuthCode bool, policy SessionPolicy) {
 	// 创建专门用于认证的Redis客户端（连接到DB1）
 	rdbAuth := redis.NewClient(&redis.Options{
 		Addr:     config.AppConfig.RedisAddr,
@@ -36,55 +160,149 @@ func InitAuthService(rdb *redis.Client, authCode, codeVersion string) {
 	})
 
 	Auth = &AuthService{
-		rdbAuth:     rdbAuth,
-		authCode:    authCode,
-		codeVersion: codeVersion,
+		rdbAuth:       rdbAuth,
+		authCode:      authCode,
+		codeVersion:   codeVersion,
+		allowAuthCode: allowAuthCode && authCode != "",
+		policy:        policy,
 	}
-	log.Printf("✅ 认证服务已初始化 - 版本: %s", codeVersion)
+	log.Printf("✅ 认证服务已初始化 - 版本: %s, 授权码登录: %v, 会话有效期: %s, 并发上限: %d, 授权码会话上限: %d",
+		codeVersion, Auth.allowAuthCode, policy.TTL, policy.MaxSessions, policy.MaxAuthCodeSessions)
 }
 
-// VerifyAuthCode 验证授权码并生成session
-func (a *AuthService) VerifyAuthCode(inputCode string) (*SessionData, error) {
+// expiresAt 会话顺延后的过期时间：空闲过期时间，但不超过凭证本身的过期时间
+func (a *AuthService) expiresAt(s *SessionData, now time.Time) time.Time {
+	expires := now.Add(a.policy.TTL)
+	if s.CredentialExpiresAt != nil && s.CredentialExpiresAt.Before(expires) {
+		return *s.CredentialExpiresAt
+	}
+	return expires
+}
+
+// AuthCodeEnabled 是否接受共享授权码登录
+func (a *AuthService) AuthCodeEnabled() bool {
+	return a.allowAuthCode
+}
+
+// VerifyAuthCode 验证授权码并生成session，优先按邀请码兑换，不是邀请码时再与共享授权码比较
+func (a *AuthService) VerifyAuthCode(inputCode string, meta SessionMeta) (*SessionData, error) {
+	ctx := context.Background()
+	found, err := a.redeemInvite(ctx, inputCode)
+	if err != nil {
+		return nil, err
+	}
+	if found {
+		sessionData, err := a.createSession(&SessionData{
+			CodeVersion: a.codeVersion,
+			Method:      AuthMethodInvite,
+			InviteCode:  inputCode,
+		}, meta)
+		if err != nil {
+			a.rdbAuth.HIncrBy(ctx, inviteKey(inputCode), "redemptions", -1) // 会话未创建，退回兑换次数
+			return nil, err
+		}
+		return sessionData, nil
+	}
+
+	if !a.allowAuthCode {
+		return nil, fmt.Errorf("授权码登录已停用，请使用平台账号登录")
+	}
 	if inputCode != a.authCode {
 		return nil, fmt.Errorf("授权码错误")
 	}
 
-	sessionID := uuid.New().String()
-	now := time.Now()
-	expiresAt := now.Add(30 * 24 * time.Hour) // 30天过期
-
-	sessionData := &SessionData{
-		SessionID:   sessionID,
+	return a.createSession(&SessionData{
 		CodeVersion: a.codeVersion,
-		CreatedAt:   now,
-		ExpiresAt:   expiresAt,
+		Method:      AuthMethodCode,
+	}, meta)
+}
+
+// CreateUserSession 为通过平台凭证认证的用户生成session
+//
+// 会话不会比平台令牌更久；API 密钥会话保存密钥，使用期间按 APIKeyRecheck 间隔重新校验。
+func (a *AuthService) CreateUserSession(identity *Identity, credential string, meta SessionMeta) (*SessionData, error) {
+	session := &storedSession{SessionData: SessionData{
+		CodeVersion:         a.codeVersion,
+		Method:              identity.Method,
+		UserID:              identity.UserID,
+		Email:               identity.Email,
+		CredentialExpiresAt: identity.ExpiresAt,
+	}}
+	if identity.Method == AuthMethodAPIKey {
+		now := time.Now()
+		session.Credential = credential
+		session.VerifiedAt = &now
 	}
+	return a.createStoredSession(session, meta)
+}
+
+// createSession 为授权码和邀请码登录创建会话
+func (a *AuthService) createSession(sessionData *SessionData, meta SessionMeta) (*SessionData, error) {
+	return a.createStoredSession(&storedSession{SessionData: *sessionData}, meta)
+}
 
-	// 存储到Redis DB1
+// createStoredSession 填充会话ID和有效期后存储到Redis DB1，并发上限的检查与写入在同一脚本中完成
+func (a *AuthService) createStoredSession(stored *storedSession, meta SessionMeta) (*SessionData, error) {
 	ctx := context.Background()
-	data, err := json.Marshal(sessionData)
+	now := time.Now()
+	sessionData := &stored.SessionData
+	sessionData.SessionID = uuid.New().String()
+	sessionData.IP = meta.IP
+	sessionData.UserAgent = meta.UserAgent
+	sessionData.CreatedAt = now
+	sessionData.LastSeenAt = now
+	sessionData.ExpiresAt = a.expiresAt(sessionData, now)
+	ttl := sessionData.ExpiresAt.Sub(now)
+	if ttl <= 0 {
+		return nil, fmt.Errorf("凭证已过期")
+	}
+
+	data, err := json.Marshal(stored)
 	if err != nil {
 		return nil, fmt.Errorf("序列化session数据失败: %v", err)
 	}
-
-	key := fmt.Sprintf("auth_session:%s", sessionID)
-	err = a.rdbAuth.Set(ctx, key, data, 30*24*time.Hour).Err()
+	limit, reject := a.policy.MaxSessions, a.policy.RejectOverLimit
+	switch {
+	case sessionData.isAuthCodeSession():
+		limit, reject = a.policy.MaxAuthCodeSessions, true
+	case sessionData.Method == AuthMethodInvite: // 兑换时已按邀请码的 max_redemptions 限制，不注销其他兑换者的会话
+		limit = 0
+	}
+	owner := sessionData.ownerSessionsKey()
+	evicted, err := createSessionScript.Run(ctx, a.rdbAuth, []string{owner, sessionKey(sessionData.SessionID)},
+		data, ttl.Milliseconds(), now.UnixMilli(), limit, reject, sessionData.SessionID, a.policy.TTL.Milliseconds()).StringSlice()
+	if err == redis.Nil {
+		return nil, ErrTooManySessions
+	}
 	if err != nil {
 		return nil, fmt.Errorf("保存session失败: %v", err)
 	}
+	for _, id := range evicted {
+		log.Printf("🔒 会话数超出上限，已注销最久未使用的会话 - SessionID: %s", id)
+	}
 
-	log.Printf("✅ 新会话已创建 - SessionID: %s, 过期时间: %s", sessionID, expiresAt.Format("2006-01-02 15:04:05"))
+	log.Printf("✅ 新会话已创建 - SessionID: %s, 方式: %s, 用户: %s, 过期时间: %s",
+		sessionData.SessionID, sessionData.Method, sessionData.UserID, sessionData.ExpiresAt.Format("2006-01-02 15:04:05"))
 	return sessionData, nil
 }
 
 // ValidateSession 验证session
 func (a *AuthService) ValidateSession(sessionID string) (*SessionData, error) {
+	stored, err := a.loadSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &stored.SessionData, nil
+}
+
+// loadSession 读取并验证session，保留存储格式中的凭证
+func (a *AuthService) loadSession(sessionID string) (*storedSession, error) {
 	if sessionID == "" {
 		return nil, fmt.Errorf("session ID为空")
 	}
 
 	ctx := context.Background()
-	key := fmt.Sprintf("auth_session:%s", sessionID)
+	key := sessionKey(sessionID)
 
 	data, err := a.rdbAuth.Get(ctx, key).Result()
 	if err == redis.Nil {
@@ -94,7 +312,7 @@ func (a *AuthService) ValidateSession(sessionID string) (*SessionData, error) {
 		return nil, fmt.Errorf("获取session失败: %v", err)
 	}
 
-	var sessionData SessionData
+	var sessionData storedSession
 	err = json.Unmarshal([]byte(data), &sessionData)
 	if err != nil {
 		return nil, fmt.Errorf("解析session数据失败: %v", err)
@@ -107,9 +325,14 @@ func (a *AuthService) ValidateSession(sessionID string) (*SessionData, error) {
 		return nil, fmt.Errorf("session已过期")
 	}
 
-	// 检查版本是否匹配
-	if sessionData.CodeVersion != a.codeVersion {
-		return nil, fmt.Errorf("代码版本不匹配")
+	// 授权码会话：检查是否仍允许授权码登录、版本是否匹配
+	if sessionData.isAuthCodeSession() {
+		if !a.allowAuthCode {
+			return nil, fmt.Errorf("授权码登录已停用")
+		}
+		if sessionData.CodeVersion != a.codeVersion {
+			return nil, fmt.Errorf("代码版本不匹配")
+		}
 	}
 
 	return &sessionData, nil
@@ -122,7 +345,12 @@ func (a *AuthService) DeleteSession(sessionID string) error {
 	}
 
 	ctx := context.Background()
-	key := fmt.Sprintf("auth_session:%s", sessionID)
+	key := sessionKey(sessionID)
+
+	var sessionData SessionData
+	if data, err := a.rdbAuth.Get(ctx, key).Bytes(); err == nil && json.Unmarshal(data, &sessionData) == nil {
+		a.rdbAuth.ZRem(ctx, sessionData.ownerSessionsKey(), sessionID)
+	}
 
 	err := a.rdbAuth.Del(ctx, key).Err()
 	if err != nil {
@@ -133,27 +361,122 @@ func (a *AuthService) DeleteSession(sessionID string) error {
 	return nil
 }
 
-// RefreshSession 刷新session过期时间
-func (a *AuthService) RefreshSession(sessionID string) error {
-	sessionData, err := a.ValidateSession(sessionID)
+// loadSessions 读取会话集合中的会话，已过期的会话ID从集合中移除
+func (a *AuthService) loadSessions(ctx context.Context, setKey string) ([]SessionData, error) {
+	ids, err := a.rdbAuth.ZRange(ctx, setKey, 0, -1).Result()
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("获取会话列表失败: %v", err)
+	}
+	if len(ids) == 0 {
+		return []SessionData{}, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = sessionKey(id)
+	}
+	values, err := a.rdbAuth.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取会话失败: %v", err)
 	}
 
-	ctx := context.Background()
-	key := fmt.Sprintf("auth_session:%s", sessionID)
+	sessions := make([]SessionData, 0, len(ids))
+	var stale []interface{}
+	for i, v := range values {
+		str, ok := v.(string)
+		var sessionData SessionData
+		if !ok || json.Unmarshal([]byte(str), &sessionData) != nil {
+			stale = append(stale, ids[i])
+			continue
+		}
+		sessions = append(sessions, sessionData)
+	}
+	if len(stale) > 0 {
+		a.rdbAuth.ZRem(ctx, setKey, stale...)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
+}
 
-	// 更新过期时间
-	sessionData.ExpiresAt = time.Now().Add(30 * 24 * time.Hour)
-	data, err := json.Marshal(sessionData)
+// RefreshSession 验证session并顺延过期时间，同时记录最近使用时间和客户端信息
+//
+// 为避免每个请求都写 Redis，距上次写回不足 sessionTouchInterval 且客户端信息未变时不写，
+// 返回的 bool 表示本次是否顺延了有效期。API 密钥会话到了重新校验的时间时先向平台校验，
+// 平台答复密钥无效则删除会话；平台暂时不可用时保留会话，下次使用时再校验。
+func (a *AuthService) RefreshSession(sessionID string, meta SessionMeta) (*SessionData, bool, error) {
+	stored, err := a.loadSession(sessionID)
 	if err != nil {
-		return fmt.Errorf("序列化session数据失败: %v", err)
+		return nil, false, err
+	}
+	sessionData := &stored.SessionData
+
+	now := time.Now()
+	recheck := sessionData.Method == AuthMethodAPIKey && sessionData.VerifiedAt != nil &&
+		Platform.apiKeyRecheckDue(*sessionData.VerifiedAt, now)
+	if !recheck && now.Sub(sessionData.LastSeenAt) < sessionTouchInterval && sessionData.IP == meta.IP && sessionData.UserAgent == meta.UserAgent {
+		return sessionData, false, nil
 	}
 
-	err = a.rdbAuth.Set(ctx, key, data, 30*24*time.Hour).Err()
+	if recheck {
+		identity, err := Platform.VerifyAPIKey(context.Background(), stored.Credential) // 超时由平台客户端控制
+		switch {
+		case errors.Is(err, ErrCredentialRevoked) || (err == nil && identity.UserID != sessionData.UserID):
+			log.Printf("🔒 API 密钥已失效，会话已注销 - SessionID: %s, 用户: %s", sessionID, sessionData.UserID)
+			a.DeleteSession(sessionID)
+			return nil, false, ErrCredentialRevoked
+		case err != nil:
+			log.Printf("⚠️ 重新校验 API 密钥失败，稍后重试 - SessionID: %s: %v", sessionID, err)
+		default:
+			sessionData.Email = identity.Email
+			sessionData.VerifiedAt = &now
+		}
+	}
+
+	sessionData.IP = meta.IP
+	sessionData.UserAgent = meta.UserAgent
+	sessionData.LastSeenAt = now
+	sessionData.ExpiresAt = a.expiresAt(sessionData, now)
+	ttl := sessionData.ExpiresAt.Sub(now)
+	if ttl <= 0 {
+		return nil, false, fmt.Errorf("session已过期")
+	}
+	data, err := json.Marshal(stored)
 	if err != nil {
-		return fmt.Errorf("刷新session失败: %v", err)
+		return nil, false, fmt.Errorf("序列化session数据失败: %v", err)
+	}
+	ok, err := refreshSessionScript.Run(context.Background(), a.rdbAuth,
+		[]string{sessionKey(sessionID), sessionData.ownerSessionsKey()},
+		data, ttl.Milliseconds(), now.UnixMilli(), sessionID, a.policy.TTL.Milliseconds()).Int()
+	if err != nil {
+		return nil, false, fmt.Errorf("刷新session失败: %v", err)
+	}
+	if ok == 0 { // 读取之后会话已被删除
+		return nil, false, fmt.Errorf("session不存在或已过期")
 	}
+	return sessionData, true, nil
+}
 
-	return nil
+// OwnSessions 列出与当前会话属于同一用户的会话；授权码和邀请码会话只返回当前会话
+func (a *AuthService) OwnSessions(current *SessionData) ([]SessionData, error) {
+	if current.isAnonymous() {
+		return []SessionData{*current}, nil
+	}
+	return a.loadSessions(context.Background(), current.ownerSessionsKey())
+}
+
+// DeleteOwnSession 删除当前用户的某个会话，不属于当前用户的会话返回 ErrSessionNotFound
+func (a *AuthService) DeleteOwnSession(current *SessionData, sessionID string) error {
+	if sessionID == current.SessionID {
+		return a.DeleteSession(sessionID)
+	}
+	if current.isAnonymous() {
+		return ErrSessionNotFound
+	}
+	err := a.rdbAuth.ZScore(context.Background(), current.ownerSessionsKey(), sessionID).Err()
+	if err == redis.Nil {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("查询会话失败: %v", err)
+	}
+	return a.DeleteSession(sessionID)
 }
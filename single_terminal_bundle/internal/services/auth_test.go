@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// 同一邀请码的两个兑换者互不相识：既看不到也不能注销对方的会话，上限策略也不会注销对方
+func TestInviteRedeemersAreIsolated(t *testing.T) {
+	ctx := context.Background()
+	a := &AuthService{
+		rdbAuth:     testRedis(t),
+		codeVersion: "v1",
+		policy:      SessionPolicy{TTL: time.Hour, MaxSessions: 1}, // 超出上限时注销最久未使用的会话
+	}
+	if _, err := a.CreateInvite(ctx, "customer-a", "客户A", 0, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := a.VerifyAuthCode("customer-a", SessionMeta{IP: "10.0.0.1", UserAgent: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := a.VerifyAuthCode("customer-a", SessionMeta{IP: "10.0.0.2", UserAgent: "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := a.ValidateSession(first.SessionID); err != nil {
+		t.Fatalf("第二个兑换者登录后第一个会话被注销: %v", err)
+	}
+
+	sessions, err := a.OwnSessions(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 || sessions[0].SessionID != second.SessionID {
+		t.Fatalf("会话列表 = %+v, 期望只有当前会话", sessions)
+	}
+
+	if err := a.DeleteOwnSession(second, first.SessionID); err != ErrSessionNotFound {
+		t.Fatalf("注销其他兑换者的会话 = %v, 期望 ErrSessionNotFound", err)
+	}
+	if _, err := a.ValidateSession(first.SessionID); err != nil {
+		t.Fatalf("其他兑换者的会话被注销: %v", err)
+	}
+
+	if all, _ := a.InviteSessions(ctx, "customer-a"); len(all) != 2 { // 管理端仍能按邀请码列出和吊销
+		t.Fatalf("邀请码会话数 = %d, 期望 2", len(all))
+	}
+}
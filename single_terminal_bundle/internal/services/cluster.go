@@ -0,0 +1,233 @@
+// Package services 提供多实例部署下的房间消息扇出与上游连接选主
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ClusterConfig 集群模式配置
+type ClusterConfig struct {
+	Enabled    bool          // 是否启用集群模式
+	InstanceID string        // 当前实例ID，为空时自动生成
+	LeaseTTL   time.Duration // 上游连接所有权租约时长
+}
+
+// ClusterBus 基于 Redis Pub/Sub 的房间消息总线
+//
+// 集群模式下同一 room_id 的客户端可能分布在不同实例上：
+//   - 房间广播统一发布到 room:<id>:broadcast 频道，所有持有该房间客户端的实例订阅后投递给本地客户端；
+//     广播序号由 room:<id>:seq 在发布的同一脚本中分配，订阅方收到的序号严格递增
+//   - 各实例定期上报本地客户端数，超过 clientCountTTL 未上报的实例不再计入房间客户端数
+//   - 只有持有 room:<id>:owner 租约的实例连接上游翻译服务，其他实例把客户端音频发布到 room:<id>:audio 频道由 owner 转发
+type ClusterBus struct {
+	rdb        *redis.Client
+	instanceID string
+	leaseTTL   time.Duration
+}
+
+// Cluster 全局集群总线，未启用集群模式时为 nil
+var Cluster *ClusterBus
+
+// InitCluster 初始化集群总线，需在 InitRedis 之后调用
+func InitCluster(cfg ClusterConfig) {
+	if !cfg.Enabled {
+		return
+	}
+
+	instanceID := cfg.InstanceID
+	if instanceID == "" {
+		hostname, _ := os.Hostname()
+		instanceID = fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8])
+	}
+	leaseTTL := cfg.LeaseTTL
+	if leaseTTL <= 0 {
+		leaseTTL = 10 * time.Second
+	}
+
+	Cluster = &ClusterBus{
+		rdb:        RDB,
+		instanceID: instanceID,
+		leaseTTL:   leaseTTL,
+	}
+	log.Printf("✅ 集群模式已启用 - 实例: %s, 租约: %s", instanceID, leaseTTL)
+}
+
+// InstanceID 返回当前实例ID
+func (c *ClusterBus) InstanceID() string {
+	return c.instanceID
+}
+
+// LeaseTTL 返回租约时长
+func (c *ClusterBus) LeaseTTL() time.Duration {
+	return c.leaseTTL
+}
+
+func (c *ClusterBus) broadcastChannel(roomID string) string {
+	return fmt.Sprintf("room:%s:broadcast", roomID)
+}
+
+func (c *ClusterBus) audioChannel(roomID string) string {
+	return fmt.Sprintf("room:%s:audio", roomID)
+}
+
+func (c *ClusterBus) ownerKey(roomID string) string {
+	return fmt.Sprintf("room:%s:owner", roomID)
+}
+
+func (c *ClusterBus) clientCountKey(roomID string) string {
+	return fmt.Sprintf("room:%s:clients", roomID)
+}
+
+func (c *ClusterBus) instancesKey(roomID string) string {
+	return fmt.Sprintf("room:%s:instances", roomID)
+}
+
+func (c *ClusterBus) seqKey(roomID string) string {
+	return fmt.Sprintf("room:%s:seq", roomID)
+}
+
+// seqTTL 房间广播序号的保留时间，房间闲置超过该时间后序号从 1 重新开始
+const seqTTL = 24 * time.Hour
+
+// clientCountTTL 实例上报的客户端数的有效期，由租约循环按 LeaseTTL/3 刷新
+func (c *ClusterBus) clientCountTTL() time.Duration {
+	return 3 * c.leaseTTL
+}
+
+// publishBroadcastScript 分配房间广播序号并发布，消息格式为 "<序号>\n<帧>"
+//
+// 序号分配与发布在同一脚本中执行，Redis 按执行顺序投递，订阅方收到的序号与发布顺序一致。
+// KEYS: 序号, 广播频道；ARGV: 帧, 序号保留毫秒
+var publishBroadcastScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+redis.call('PUBLISH', KEYS[2], seq .. '\n' .. ARGV[1])
+return seq
+`)
+
+// PublishBroadcast 分配广播序号并发布房间广播消息
+func (c *ClusterBus) PublishBroadcast(ctx context.Context, roomID string, payload []byte) error {
+	return publishBroadcastScript.Run(ctx, c.rdb, []string{c.seqKey(roomID), c.broadcastChannel(roomID)},
+		payload, seqTTL.Milliseconds()).Err()
+}
+
+// ParseBroadcast 拆分广播消息中的序号和帧
+func ParseBroadcast(msg string) (uint64, string, error) {
+	seqStr, payload, ok := strings.Cut(msg, "\n")
+	if !ok {
+		return 0, "", fmt.Errorf("广播消息缺少序号")
+	}
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("广播序号格式错误: %w", err)
+	}
+	return seq, payload, nil
+}
+
+// PublishAudio 发布客户端音频，由房间 owner 转发给上游
+func (c *ClusterBus) PublishAudio(ctx context.Context, roomID string, payload []byte) error {
+	return c.rdb.Publish(ctx, c.audioChannel(roomID), payload).Err()
+}
+
+// SubscribeBroadcast 订阅房间广播频道
+func (c *ClusterBus) SubscribeBroadcast(ctx context.Context, roomID string) *redis.PubSub {
+	return c.rdb.Subscribe(ctx, c.broadcastChannel(roomID))
+}
+
+// SubscribeAudio 订阅房间音频频道
+func (c *ClusterBus) SubscribeAudio(ctx context.Context, roomID string) *redis.PubSub {
+	return c.rdb.Subscribe(ctx, c.audioChannel(roomID))
+}
+
+// acquireLeaseScript 抢占或续约租约：键不存在时写入当前实例，已属于当前实例时续期
+var acquireLeaseScript = redis.NewScript(`
+local owner = redis.call('GET', KEYS[1])
+if not owner then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+if owner == ARGV[1] then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseLeaseScript 仅当租约属于当前实例时释放
+var releaseLeaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+
+// AcquireLease 抢占或续约房间上游连接租约，返回当前实例是否为 owner
+func (c *ClusterBus) AcquireLease(ctx context.Context, roomID string) (bool, error) {
+	res, err := acquireLeaseScript.Run(ctx, c.rdb, []string{c.ownerKey(roomID)}, c.instanceID, c.leaseTTL.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+// ReleaseLease 释放房间上游连接租约
+func (c *ClusterBus) ReleaseLease(ctx context.Context, roomID string) error {
+	return releaseLeaseScript.Run(ctx, c.rdb, []string{c.ownerKey(roomID)}, c.instanceID).Err()
+}
+
+// Owner 返回当前持有房间租约的实例ID
+func (c *ClusterBus) Owner(ctx context.Context, roomID string) (string, error) {
+	owner, err := c.rdb.Get(ctx, c.ownerKey(roomID)).Result()
+	if err == redis.Nil {
+		return "", nil
+	}
+	return owner, err
+}
+
+// setClientsScript 记录实例的本地客户端数并返回全集群客户端数，过期实例的计数被移除
+//
+// KEYS: 各实例客户端数（Hash）, 各实例过期时间（Sorted Set）
+// ARGV: 实例ID, 本地客户端数, 当前时间毫秒, 有效期毫秒
+var setClientsScript = redis.NewScript(`
+local now = tonumber(ARGV[3])
+for _, id in ipairs(redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', now)) do
+	redis.call('HDEL', KEYS[1], id)
+end
+redis.call('ZREMRANGEBYSCORE', KEYS[2], '-inf', now)
+if tonumber(ARGV[2]) > 0 then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+	redis.call('ZADD', KEYS[2], now + tonumber(ARGV[4]), ARGV[1])
+else
+	redis.call('HDEL', KEYS[1], ARGV[1])
+	redis.call('ZREM', KEYS[2], ARGV[1])
+end
+local total = 0
+for _, n in ipairs(redis.call('HVALS', KEYS[1])) do
+	total = total + tonumber(n)
+end
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+redis.call('PEXPIRE', KEYS[2], ARGV[4])
+return total
+`)
+
+// SetClients 上报当前实例的房间客户端数，返回全集群仍在上报的实例的客户端总数
+//
+// 上报的是绝对数量而不是增减量，实例崩溃后其计数在 clientCountTTL 后自动失效，不会残留。
+func (c *ClusterBus) SetClients(ctx context.Context, roomID string, local int64) (int64, error) {
+	return setClientsScript.Run(ctx, c.rdb, []string{c.clientCountKey(roomID), c.instancesKey(roomID)},
+		c.instanceID, local, time.Now().UnixMilli(), c.clientCountTTL().Milliseconds()).Int64()
+}
+
+// ResetClients 清除房间客户端计数
+func (c *ClusterBus) ResetClients(ctx context.Context, roomID string) error {
+	return c.rdb.Del(ctx, c.clientCountKey(roomID), c.instancesKey(roomID)).Err()
+}
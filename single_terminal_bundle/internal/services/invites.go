@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 邀请码兑换失败的原因
+var (
+	ErrInviteRevoked   = errors.New("邀请码已被吊销")
+	ErrInviteExpired   = errors.New("邀请码已过期")
+	ErrInviteExhausted = errors.New("邀请码兑换次数已用完")
+	ErrInviteExists    = errors.New("邀请码已存在")
+)
+
+// invitesIndexKey 按创建时间排序的邀请码集合
+const invitesIndexKey = "invite_codes"
+
+// inviteCodePattern 自定义邀请码允许的字符
+var inviteCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{4,64}$`)
+
+func inviteKey(code string) string {
+	return fmt.Sprintf("invite_code:%s", code)
+}
+
+// inviteSessionsKey 由邀请码创建的会话ID集合
+func inviteSessionsKey(code string) string {
+	return fmt.Sprintf("invite_sessions:%s", code)
+}
+
+// redeemInviteScript 原子地检查邀请码状态并增加兑换次数
+//
+// 返回值：-1 不存在，-2 已吊销，-3 已过期，-4 次数已用完，否则为兑换后的次数
+var redeemInviteScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then return -1 end
+if redis.call('HGET', KEYS[1], 'revoked') == '1' then return -2 end
+local expires = tonumber(redis.call('HGET', KEYS[1], 'expires_at') or '0')
+if expires > 0 and tonumber(ARGV[1]) >= expires then return -3 end
+local max = tonumber(redis.call('HGET', KEYS[1], 'max_redemptions') or '0')
+local used = tonumber(redis.call('HGET', KEYS[1], 'redemptions') or '0')
+if max > 0 and used >= max then return -4 end
+return redis.call('HINCRBY', KEYS[1], 'redemptions', 1)
+`)
+
+// InviteCode 邀请码，不同客户使用各自的邀请码登录，可单独吊销
+type InviteCode struct {
+	Code           string     `json:"code"`
+	Label          string     `json:"label"`
+	MaxRedemptions int        `json:"max_redemptions"` // 0 表示不限次数
+	Redemptions    int        `json:"redemptions"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"` // 为空表示不过期
+	Revoked        bool       `json:"revoked"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// inviteRecord 邀请码在 Redis 哈希中的存储格式，时间为 Unix 秒，0 表示未设置
+type inviteRecord struct {
+	Label          string `redis:"label"`
+	MaxRedemptions int    `redis:"max_redemptions"`
+	Redemptions    int    `redis:"redemptions"`
+	ExpiresAt      int64  `redis:"expires_at"`
+	Revoked        bool   `redis:"revoked"`
+	RevokedAt      int64  `redis:"revoked_at"`
+	CreatedAt      int64  `redis:"created_at"`
+}
+
+func (r inviteRecord) invite(code string) InviteCode {
+	inv := InviteCode{
+		Code:           code,
+		Label:          r.Label,
+		MaxRedemptions: r.MaxRedemptions,
+		Redemptions:    r.Redemptions,
+		Revoked:        r.Revoked,
+		CreatedAt:      time.Unix(r.CreatedAt, 0),
+	}
+	if r.ExpiresAt > 0 {
+		t := time.Unix(r.ExpiresAt, 0)
+		inv.ExpiresAt = &t
+	}
+	if r.RevokedAt > 0 {
+		t := time.Unix(r.RevokedAt, 0)
+		inv.RevokedAt = &t
+	}
+	return inv
+}
+
+// newInviteCode 生成 16 位随机邀请码
+func newInviteCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成邀请码失败: %w", err)
+	}
+	return base32.StdEncoding.EncodeToString(b), nil
+}
+
+// CreateInvite 创建邀请码，code 为空时随机生成
+func (a *AuthService) CreateInvite(ctx context.Context, code, label string, maxRedemptions int, expiresAt *time.Time) (*InviteCode, error) {
+	if code == "" {
+		var err error
+		if code, err = newInviteCode(); err != nil {
+			return nil, err
+		}
+	} else if !inviteCodePattern.MatchString(code) {
+		return nil, fmt.Errorf("邀请码只能包含字母、数字、下划线和连字符，长度 4-64")
+	}
+	if maxRedemptions < 0 {
+		return nil, fmt.Errorf("max_redemptions 不能为负数")
+	}
+
+	now := time.Now()
+	rec := inviteRecord{Label: label, MaxRedemptions: maxRedemptions, CreatedAt: now.Unix()}
+	if expiresAt != nil {
+		if !expiresAt.After(now) {
+			return nil, fmt.Errorf("expires_at 必须晚于当前时间")
+		}
+		rec.ExpiresAt = expiresAt.Unix()
+	}
+
+	ok, err := a.rdbAuth.ZAddNX(ctx, invitesIndexKey, redis.Z{Score: float64(now.Unix()), Member: code}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("保存邀请码失败: %v", err)
+	}
+	if ok == 0 {
+		return nil, ErrInviteExists
+	}
+	if err := a.rdbAuth.HSet(ctx, inviteKey(code), rec).Err(); err != nil {
+		a.rdbAuth.ZRem(ctx, invitesIndexKey, code)
+		return nil, fmt.Errorf("保存邀请码失败: %v", err)
+	}
+
+	inv := rec.invite(code)
+	log.Printf("🎟️ 邀请码已创建 - %s (%s), 次数上限: %d", code, label, maxRedemptions)
+	return &inv, nil
+}
+
+// GetInvite 获取邀请码，不存在时返回 nil
+func (a *AuthService) GetInvite(ctx context.Context, code string) (*InviteCode, error) {
+	res := a.rdbAuth.HGetAll(ctx, inviteKey(code))
+	if err := res.Err(); err != nil {
+		return nil, fmt.Errorf("获取邀请码失败: %v", err)
+	}
+	if len(res.Val()) == 0 {
+		return nil, nil
+	}
+	var rec inviteRecord
+	if err := res.Scan(&rec); err != nil {
+		return nil, fmt.Errorf("解析邀请码失败: %v", err)
+	}
+	inv := rec.invite(code)
+	return &inv, nil
+}
+
+// ListInvites 按创建时间倒序列出邀请码
+func (a *AuthService) ListInvites(ctx context.Context) ([]InviteCode, error) {
+	codes, err := a.rdbAuth.ZRevRange(ctx, invitesIndexKey, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取邀请码列表失败: %v", err)
+	}
+	invites := make([]InviteCode, 0, len(codes))
+	for _, code := range codes {
+		inv, err := a.GetInvite(ctx, code)
+		if err != nil {
+			return nil, err
+		}
+		if inv != nil {
+			invites = append(invites, *inv)
+		}
+	}
+	return invites, nil
+}
+
+// RevokeInvite 吊销邀请码，之后不能再兑换；已创建的会话不受影响，可通过 KillInviteSessions 结束
+func (a *AuthService) RevokeInvite(ctx context.Context, code string) (*InviteCode, error) {
+	inv, err := a.GetInvite(ctx, code)
+	if err != nil || inv == nil {
+		return inv, err
+	}
+	if !inv.Revoked {
+		now := time.Now()
+		if err := a.rdbAuth.HSet(ctx, inviteKey(code), "revoked", true, "revoked_at", now.Unix()).Err(); err != nil {
+			return nil, fmt.Errorf("吊销邀请码失败: %v", err)
+		}
+		inv.Revoked, inv.RevokedAt = true, &now
+		log.Printf("🚫 邀请码已吊销 - %s (%s)", code, inv.Label)
+	}
+	return inv, nil
+}
+
+// InviteSessions 列出由邀请码创建且尚未过期的会话
+func (a *AuthService) InviteSessions(ctx context.Context, code string) ([]SessionData, error) {
+	return a.loadSessions(ctx, inviteSessionsKey(code))
+}
+
+// KillInviteSessions 删除由邀请码创建的全部会话，返回删除的数量
+func (a *AuthService) KillInviteSessions(ctx context.Context, code string) (int, error) {
+	sessions, err := a.InviteSessions(ctx, code)
+	if err != nil {
+		return 0, err
+	}
+	killed := 0
+	for _, s := range sessions {
+		if err := a.DeleteSession(s.SessionID); err != nil {
+			return killed, err
+		}
+		killed++
+	}
+	return killed, nil
+}
+
+// redeemInvite 兑换邀请码，code 不是邀请码时返回 false
+func (a *AuthService) redeemInvite(ctx context.Context, code string) (bool, error) {
+	if !inviteCodePattern.MatchString(code) {
+		return false, nil
+	}
+	n, err := redeemInviteScript.Run(ctx, a.rdbAuth, []string{inviteKey(code)}, time.Now().Unix()).Int()
+	if err != nil {
+		return false, fmt.Errorf("兑换邀请码失败: %v", err)
+	}
+	switch n {
+	case -1:
+		return false, nil
+	case -2:
+		return false, ErrInviteRevoked
+	case -3:
+		return false, ErrInviteExpired
+	case -4:
+		return false, ErrInviteExhausted
+	}
+	return true, nil
+}
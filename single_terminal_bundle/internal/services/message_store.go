@@ -0,0 +1,178 @@
+// Package services 提供房间消息历史的存储实现
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MessageStore 房间消息存储：每条消息一个 Redis Hash，字段名为消息字段、值为该字段的 JSON 编码，
+// Sorted Set 按写入顺序保存消息ID
+//
+// 相比原来的 List + LRange 全量扫描，按ID读写均为 O(1)，按顺序取最近N条为 O(log n + N)。
+// 合并只写入传入的字段，Redis 端不解析 JSON，空数组、大整数等值原样保存；
+// 翻译读取协程与反向翻译协程写入不同字段，不会互相覆盖。
+type MessageStore struct {
+	rdb *redis.Client
+}
+
+// NewMessageStore 创建消息存储
+func NewMessageStore(rdb *redis.Client) *MessageStore {
+	return &MessageStore{rdb: rdb}
+}
+
+// messageDeleteBatch 删除房间消息时每批删除的消息数
+const messageDeleteBatch = 500
+
+// mergeMessageScript 原子合并消息字段：消息不存在时分配顺序号，传入的字段覆盖写入，返回合并后的全部字段
+//
+// KEYS[1] 消息Hash，KEYS[2] 顺序Sorted Set，KEYS[3] 顺序号计数器
+// ARGV[1] 消息ID，ARGV[2..] 字段名与 JSON 编码的字段值交替排列
+var mergeMessageScript = redis.NewScript(`
+if #ARGV < 2 then
+	return redis.call('HGETALL', KEYS[1])
+end
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	local seq = redis.call('INCR', KEYS[3])
+	redis.call('ZADD', KEYS[2], seq, ARGV[1])
+end
+redis.call('HSET', KEYS[1], unpack(ARGV, 2))
+return redis.call('HGETALL', KEYS[1])
+`)
+
+func (s *MessageStore) messageKey(roomID, messageID string) string {
+	return fmt.Sprintf("room:%s:msg:%s", roomID, messageID)
+}
+
+func (s *MessageStore) orderKey(roomID string) string {
+	return fmt.Sprintf("room:%s:msg_order", roomID)
+}
+
+func (s *MessageStore) seqKey(roomID string) string {
+	return fmt.Sprintf("room:%s:msg_seq", roomID)
+}
+
+// decodeMessage 将消息 Hash 的字段还原为消息，数字保留为 json.Number 以免丢失精度
+func decodeMessage(fields map[string]string) (map[string]interface{}, error) {
+	msg := make(map[string]interface{}, len(fields))
+	for k, raw := range fields {
+		dec := json.NewDecoder(strings.NewReader(raw))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("解析消息字段 %s 失败: %w", k, err)
+		}
+		msg[k] = v
+	}
+	return msg, nil
+}
+
+// Merge 合并消息字段并返回合并后的完整消息
+func (s *MessageStore) Merge(ctx context.Context, roomID, messageID string, fields map[string]interface{}) (map[string]interface{}, error) {
+	args := make([]interface{}, 0, 1+2*len(fields))
+	args = append(args, messageID)
+	for k, v := range fields {
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("序列化消息字段 %s 失败: %w", k, err)
+		}
+		args = append(args, k, encoded)
+	}
+
+	keys := []string{s.messageKey(roomID, messageID), s.orderKey(roomID), s.seqKey(roomID)}
+	flat, err := mergeMessageScript.Run(ctx, s.rdb, keys, args...).StringSlice()
+	if err != nil {
+		return nil, fmt.Errorf("合并消息失败: %w", err)
+	}
+
+	merged := make(map[string]string, len(flat)/2)
+	for i := 0; i+1 < len(flat); i += 2 {
+		merged[flat[i]] = flat[i+1]
+	}
+	return decodeMessage(merged)
+}
+
+// Get 按ID获取消息，不存在时返回 nil
+func (s *MessageStore) Get(ctx context.Context, roomID, messageID string) (map[string]interface{}, error) {
+	fields, err := s.rdb.HGetAll(ctx, s.messageKey(roomID, messageID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取消息失败: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return decodeMessage(fields)
+}
+
+// Recent 按写入顺序返回最近 n 条消息（旧的在前）
+func (s *MessageStore) Recent(ctx context.Context, roomID string, n int64) ([]map[string]interface{}, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	ids, err := s.rdb.ZRange(ctx, s.orderKey(roomID), -n, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取消息顺序失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	pipe := s.rdb.Pipeline()
+	cmds := make([]*redis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		cmds[i] = pipe.HGetAll(ctx, s.messageKey(roomID, id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("批量获取消息失败: %w", err)
+	}
+
+	messages := make([]map[string]interface{}, 0, len(ids))
+	for _, cmd := range cmds {
+		fields := cmd.Val()
+		if len(fields) == 0 { // 顺序集合与消息不一致时跳过
+			continue
+		}
+		msg, err := decodeMessage(fields)
+		if err != nil {
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
+
+// Count 返回房间消息条数
+func (s *MessageStore) Count(ctx context.Context, roomID string) (int64, error) {
+	return s.rdb.ZCard(ctx, s.orderKey(roomID)).Result()
+}
+
+// Delete 删除房间全部消息历史，按批删除各条消息后删除顺序集合
+func (s *MessageStore) Delete(ctx context.Context, roomID string) error {
+	for {
+		ids, err := s.rdb.ZRange(ctx, s.orderKey(roomID), 0, messageDeleteBatch-1).Result()
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		keys := make([]string, len(ids))
+		members := make([]interface{}, len(ids))
+		for i, id := range ids {
+			keys[i] = s.messageKey(roomID, id)
+			members[i] = id
+		}
+		pipe := s.rdb.TxPipeline()
+		pipe.Del(ctx, keys...)
+		pipe.ZRem(ctx, s.orderKey(roomID), members...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return s.rdb.Del(ctx, s.orderKey(roomID), s.seqKey(roomID)).Err()
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// testRedis 连接 TEST_REDIS_ADDR 指定的 Redis（使用 DB15 并在结束时清空），未设置时跳过
+func testRedis(tb testing.TB) *redis.Client {
+	tb.Helper()
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		tb.Skip("未设置 TEST_REDIS_ADDR，跳过需要 Redis 的测试")
+	}
+	rdb := redis.NewClient(&redis.Options{Addr: addr, DB: 15})
+	if err := rdb.Ping(context.Background()).Err(); err != nil {
+		tb.Skipf("无法连接 Redis %s: %v", addr, err)
+	}
+	tb.Cleanup(func() {
+		rdb.FlushDB(context.Background())
+		rdb.Close()
+	})
+	return rdb
+}
+
+func TestMessageStoreMergeKeepsValues(t *testing.T) {
+	ctx := context.Background()
+	store := NewMessageStore(testRedis(t))
+
+	_, err := store.Merge(ctx, "r1", "m1", map[string]interface{}{
+		"id":        "m1",
+		"timestamp": int64(1734567890123456789),
+		"segments":  []string{},
+		"speaker":   map[string]interface{}{"name": "Alice"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := store.Merge(ctx, "r1", "m1", map[string]interface{}{"reverseTranslation": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := json.Marshal(merged)
+	want := `{"id":"m1","reverseTranslation":"hello","segments":[],"speaker":{"name":"Alice"},"timestamp":1734567890123456789}`
+	if string(got) != want {
+		t.Fatalf("合并结果 = %s, 期望 %s", got, want)
+	}
+	if n, _ := store.Count(ctx, "r1"); n != 1 {
+		t.Fatalf("消息条数 = %d, 期望 1", n)
+	}
+}
+
+func TestMessageStoreRecentAndDelete(t *testing.T) {
+	ctx := context.Background()
+	store := NewMessageStore(testRedis(t))
+
+	for i := 0; i < 5; i++ {
+		id := fmt.Sprintf("m%d", i)
+		if _, err := store.Merge(ctx, "r1", id, map[string]interface{}{"id": id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Merge(ctx, "r1", "m1", map[string]interface{}{"translation": "updated"}) // 更新不改变顺序
+
+	recent, err := store.Recent(ctx, "r1", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range recent {
+		ids = append(ids, m["id"].(string))
+	}
+	if fmt.Sprint(ids) != "[m2 m3 m4]" {
+		t.Fatalf("最近消息 = %v, 期望 [m2 m3 m4]", ids)
+	}
+
+	if err := store.Delete(ctx, "r1"); err != nil {
+		t.Fatal(err)
+	}
+	if msg, _ := store.Get(ctx, "r1", "m1"); msg != nil {
+		t.Fatalf("删除后仍能读取消息: %v", msg)
+	}
+}
+
+// legacyUpsert 原实现：LRange 取出全部消息逐条解析找到ID后 LSet，找不到时 RPush
+func legacyUpsert(ctx context.Context, rdb *redis.Client, key, id string, payload []byte) error {
+	messages, err := rdb.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	for i, message := range messages {
+		var existing map[string]interface{}
+		if err := json.Unmarshal([]byte(message), &existing); err != nil {
+			continue
+		}
+		if existing["id"] == id {
+			return rdb.LSet(ctx, key, int64(i), payload).Err()
+		}
+	}
+	return rdb.RPush(ctx, key, payload).Err()
+}
+
+// BenchmarkMessageUpdate 对比在已有大量消息的房间中更新最新一条消息的开销
+func BenchmarkMessageUpdate(b *testing.B) {
+	ctx := context.Background()
+	rdb := testRedis(b)
+	store := NewMessageStore(rdb)
+
+	for _, size := range []int{100, 1000, 5000} {
+		room := fmt.Sprintf("bench%d", size)
+		legacyKey := fmt.Sprintf("room:%s:messages", room)
+		pipe := rdb.Pipeline()
+		for i := 0; i < size; i++ {
+			id := fmt.Sprintf("m%d", i)
+			msg := map[string]interface{}{"id": id, "translation": "text", "language": "en"}
+			payload, _ := json.Marshal(msg)
+			pipe.RPush(ctx, legacyKey, payload)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < size; i++ {
+			id := fmt.Sprintf("m%d", i)
+			if _, err := store.Merge(ctx, room, id, map[string]interface{}{"id": id, "translation": "text", "language": "en"}); err != nil {
+				b.Fatal(err)
+			}
+		}
+		last := fmt.Sprintf("m%d", size-1)
+
+		b.Run(fmt.Sprintf("list/%d", size), func(b *testing.B) {
+			payload, _ := json.Marshal(map[string]interface{}{"id": last, "translation": "updated", "language": "en"})
+			for i := 0; i < b.N; i++ {
+				if err := legacyUpsert(ctx, rdb, legacyKey, last, payload); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("hash/%d", size), func(b *testing.B) {
+			fields := map[string]interface{}{"translation": "updated"}
+			for i := 0; i < b.N; i++ {
+				if _, err := store.Merge(ctx, room, last, fields); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// 会话的认证方式
+const (
+	AuthMethodCode        = "auth_code"    // 共享授权码（兼容旧版本）
+	AuthMethodInvite      = "invite_code"  // 管理员发放的邀请码
+	AuthMethodPlatformJWT = "platform_jwt" // 开发者平台登录令牌
+	AuthMethodAPIKey      = "api_key"      // 开发者平台 KF- API 密钥
+)
+
+// apiKeyPrefix 开发者平台 API 密钥前缀，格式为 KF-<等级>-<UUID>
+const apiKeyPrefix = "KF-"
+
+// ErrCredentialRevoked 平台明确答复凭证无效（已删除或停用），区别于网络错误等暂时性失败
+var ErrCredentialRevoked = errors.New("API 密钥无效")
+
+// PlatformAuthConfig 开发者平台账号认证配置
+type PlatformAuthConfig struct {
+	JWTSecret       string        // 平台签发 JWT 的 HS256 密钥，为空时不接受平台令牌
+	JWTIssuer       string        // 平台令牌的 iss，为空时不校验
+	APIKeyVerifyURL string        // 平台校验 API 密钥的接口（POST /api/api-keys/verify 的完整地址），为空时不接受 API 密钥
+	APIKeyRecheck   time.Duration // API 密钥会话使用期间重新向平台校验的间隔，0 表示不重新校验
+	Timeout         time.Duration // 调用平台接口的超时时间
+}
+
+// Identity 平台账号身份
+type Identity struct {
+	UserID    string     `json:"user_id"`
+	Email     string     `json:"email,omitempty"`
+	Name      string     `json:"name,omitempty"`
+	Method    string     `json:"method"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // 凭证的过期时间（JWT 的 exp），会话不会比它更久
+}
+
+// platformClaims 开发者平台签发的 JWT 声明
+type platformClaims struct {
+	UserID uint   `json:"user_id"`
+	Email  string `json:"email"`
+	Name   string `json:"name"`
+	jwt.RegisteredClaims
+}
+
+// PlatformAuth 校验开发者平台签发的凭证
+type PlatformAuth struct {
+	cfg    PlatformAuthConfig
+	client *http.Client
+}
+
+// Platform 全局平台认证，由 InitPlatformAuth 初始化
+var Platform *PlatformAuth
+
+// InitPlatformAuth 初始化平台认证
+func InitPlatformAuth(cfg PlatformAuthConfig) {
+	Platform = &PlatformAuth{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+	log.Printf("✅ 平台认证已初始化 - JWT: %v, API密钥: %v, 重新校验间隔: %s", cfg.JWTSecret != "", cfg.APIKeyVerifyURL != "", cfg.APIKeyRecheck)
+}
+
+// apiKeyRecheckDue API 密钥会话距上次校验是否已超过重新校验间隔
+func (p *PlatformAuth) apiKeyRecheckDue(verifiedAt time.Time, now time.Time) bool {
+	return p != nil && p.cfg.APIKeyRecheck > 0 && now.Sub(verifiedAt) >= p.cfg.APIKeyRecheck
+}
+
+// Enabled 是否配置了任一平台凭证
+func (p *PlatformAuth) Enabled() bool {
+	return p.cfg.JWTSecret != "" || p.cfg.APIKeyVerifyURL != ""
+}
+
+// Verify 按凭证格式校验平台 JWT 或 KF- API 密钥
+func (p *PlatformAuth) Verify(ctx context.Context, credential string) (*Identity, error) {
+	if strings.HasPrefix(credential, apiKeyPrefix) {
+		return p.VerifyAPIKey(ctx, credential)
+	}
+	return p.VerifyToken(credential)
+}
+
+// VerifyToken 校验平台 JWT 的签名、有效期和签发方
+func (p *PlatformAuth) VerifyToken(token string) (*Identity, error) {
+	if p.cfg.JWTSecret == "" {
+		return nil, fmt.Errorf("未启用平台令牌登录")
+	}
+	opts := []jwt.ParserOption{jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired()}
+	if p.cfg.JWTIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(p.cfg.JWTIssuer))
+	}
+	var claims platformClaims
+	if _, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(p.cfg.JWTSecret), nil
+	}, opts...); err != nil {
+		return nil, fmt.Errorf("平台令牌无效: %w", err)
+	}
+	if claims.UserID == 0 {
+		return nil, fmt.Errorf("平台令牌缺少用户ID")
+	}
+	return &Identity{
+		UserID:    strconv.FormatUint(uint64(claims.UserID), 10),
+		Email:     claims.Email,
+		Name:      claims.Name,
+		Method:    AuthMethodPlatformJWT,
+		ExpiresAt: &claims.ExpiresAt.Time,
+	}, nil
+}
+
+// VerifyAPIKey 检查密钥格式后调用平台接口确认密钥有效并取得所属用户
+//
+// 平台的 POST /api/api-keys/verify 接收 {"api_key": "..."}，密钥有效时返回 200 和 {"user_id": 1, "email": "...", "name": "..."}，
+// 密钥已删除或已重新生成时返回 404，此时返回 ErrCredentialRevoked。
+func (p *PlatformAuth) VerifyAPIKey(ctx context.Context, key string) (*Identity, error) {
+	if p.cfg.APIKeyVerifyURL == "" {
+		return nil, fmt.Errorf("未启用 API 密钥登录")
+	}
+	if !validAPIKeyFormat(key) {
+		return nil, fmt.Errorf("API 密钥格式错误")
+	}
+
+	body, err := json.Marshal(map[string]string{"api_key": key})
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.cfg.APIKeyVerifyURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("调用平台校验 API 密钥失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return nil, ErrCredentialRevoked
+	default:
+		return nil, fmt.Errorf("平台校验 API 密钥失败: HTTP %d", resp.StatusCode)
+	}
+
+	var result struct {
+		UserID uint64 `json:"user_id"`
+		Email  string `json:"email"`
+		Name   string `json:"name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析平台响应失败: %w", err)
+	}
+	if result.UserID == 0 {
+		return nil, fmt.Errorf("平台响应缺少用户ID")
+	}
+	return &Identity{
+		UserID: strconv.FormatUint(result.UserID, 10),
+		Email:  result.Email,
+		Name:   result.Name,
+		Method: AuthMethodAPIKey,
+	}, nil
+}
+
+// validAPIKeyFormat 检查 KF-<等级>-<UUID> 格式，格式错误的密钥不必请求平台
+func validAPIKeyFormat(key string) bool {
+	parts := strings.SplitN(strings.TrimPrefix(key, apiKeyPrefix), "-", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	if _, err := strconv.Atoi(parts[0]); err != nil {
+		return false
+	}
+	_, err := uuid.Parse(parts[1])
+	return err == nil
+}
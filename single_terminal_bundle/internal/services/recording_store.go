@@ -0,0 +1,497 @@
+// Package services 提供录音索引的存储与保留策略
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-backEnd/pkg/audio"
+	"go-backEnd/pkg/storage"
+	"log"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 录音文件的容器格式
+const (
+	ContainerWAV = "wav" // 带 44 字节 WAV 头部
+	ContainerPCM = "pcm" // 裸 PCM
+)
+
+// 录音类型
+const (
+	KindClient      = "client"      // 单个客户端的麦克风
+	KindSource      = "source"      // 房间送往上游的混音原声
+	KindTranslation = "translation" // 上游返回的译音
+)
+
+// Recording 录音索引条目
+type Recording struct {
+	ID           string       `json:"id"`
+	Kind         string       `json:"kind"`
+	RoomID       string       `json:"room_id"`
+	SessionID    string       `json:"session_id,omitempty"` // 房间音轨所属的翻译会话
+	ClientID     string       `json:"client_id,omitempty"`
+	Speaker      string       `json:"speaker,omitempty"`
+	FromLanguage string       `json:"from_language"`
+	ToLanguage   string       `json:"to_language"`
+	Format       audio.Format `json:"format"`
+	Container    string       `json:"container"`
+	Path         string       `json:"path"`        // 存储后端中的对象名，录制中时为本地录音目录下的相对路径
+	Size         int64        `json:"size"`        // 音频数据字节数（不含头部），录制中为 0
+	DurationMs   int64        `json:"duration_ms"` // 录制中为 0
+	CreatedAt    time.Time    `json:"created_at"`
+	EndedAt      *time.Time   `json:"ended_at,omitempty"`
+}
+
+// DataOffset 返回音频数据在文件中的起始偏移
+func (r Recording) DataOffset() int64 {
+	if r.Container == ContainerWAV {
+		return 44
+	}
+	return 0
+}
+
+// RecordingFilter 录音列表筛选条件，零值字段不参与筛选
+type RecordingFilter struct {
+	RoomID    string
+	ClientID  string
+	Kind      string
+	SessionID string
+	Language  string // 匹配源语言或目标语言
+	Since     time.Time
+	Until     time.Time
+	Offset    int
+	Limit     int // 0 表示不分页
+}
+
+// filtered 是否有只能读出条目后判断的条件
+func (f RecordingFilter) filtered() bool {
+	return f.ClientID != "" || f.Kind != "" || f.SessionID != "" || f.Language != ""
+}
+
+// match 判断条目是否符合按客户端、类型、会话和语言的筛选条件
+func (f RecordingFilter) match(rec Recording) bool {
+	if f.ClientID != "" && rec.ClientID != f.ClientID {
+		return false
+	}
+	if f.Kind != "" && rec.Kind != f.Kind {
+		return false
+	}
+	if f.SessionID != "" && rec.SessionID != f.SessionID {
+		return false
+	}
+	if f.Language != "" && rec.FromLanguage != f.Language && rec.ToLanguage != f.Language {
+		return false
+	}
+	return true
+}
+
+// RecordingPage 一页录音
+type RecordingPage struct {
+	Recordings []Recording
+	Total      int  // 符合条件的总数，有客户端、类型、会话或语言条件时不统计，为 -1
+	HasMore    bool // 之后是否还有符合条件的录音
+}
+
+// RecordingStore 录音索引：Redis Hash 按ID保存条目，Sorted Set 按创建时间排序（全局及按房间）
+//
+// 录制中的文件写在本地录音目录（spool），结束后交给存储后端；本地存储根目录与录音目录相同时不发生移动。
+type RecordingStore struct {
+	rdb   *redis.Client
+	blobs storage.BlobStore
+	spool *storage.LocalStore
+}
+
+// NewRecordingStore 创建录音索引
+func NewRecordingStore(rdb *redis.Client, blobs storage.BlobStore, spoolDir string) *RecordingStore {
+	return &RecordingStore{rdb: rdb, blobs: blobs, spool: storage.NewLocalStore(spoolDir)}
+}
+
+// Recordings 录音索引，由 InitRecordings 创建
+var Recordings *RecordingStore
+
+// InitRecordings 初始化录音索引，需在 InitRedis 之后调用；retention 大于 0 时启动过期清理协程
+func InitRecordings(cfg audio.RecordingConfig, retention, interval time.Duration) {
+	Recordings = NewRecordingStore(RDB, storage.Default, cfg.Dir)
+	roomRecording = cfg
+	if retention > 0 {
+		go Recordings.runRetention(retention, interval)
+		log.Printf("🗂️ 录音保留 %s，每 %s 清理一次", retention, interval)
+	}
+}
+
+const (
+	recordingHashKey  = "recordings"
+	recordingOrderKey = "recordings:created"
+	recordingPathKey  = "recordings:paths" // 对象名 → 录音ID
+)
+
+// recordingScanBatch 有附加筛选条件时每次从有序集合读取的条目数
+const recordingScanBatch = 200
+
+func recordingRoomKey(roomID string) string {
+	return fmt.Sprintf("room:%s:recordings", roomID)
+}
+
+// RelPath 将本地录音文件路径转换为索引中保存的对象名
+func (s *RecordingStore) RelPath(path string) string {
+	rel, err := filepath.Rel(s.spool.Root(), path)
+	if err != nil {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// localPath 返回录制中文件的本地路径
+func (s *RecordingStore) localPath(rec Recording) string {
+	return filepath.Join(s.spool.Root(), filepath.FromSlash(rec.Path))
+}
+
+// sharesSpool 存储后端是否就是本地录音目录
+func (s *RecordingStore) sharesSpool() bool {
+	local, ok := s.blobs.(*storage.LocalStore)
+	if !ok {
+		return false
+	}
+	a, _ := filepath.Abs(local.Root())
+	b, _ := filepath.Abs(s.spool.Root())
+	return a == b
+}
+
+// Open 打开录音文件：已结束的从存储后端读取，录制中或尚未上传的从本地录音目录读取
+func (s *RecordingStore) Open(ctx context.Context, rec Recording) (storage.Object, error) {
+	if rec.EndedAt != nil {
+		obj, err := s.blobs.Open(ctx, rec.Path)
+		if err != storage.ErrNotFound {
+			return obj, err
+		}
+	}
+	return s.spool.Open(ctx, rec.Path)
+}
+
+// Save 写入或覆盖录音条目
+func (s *RecordingStore) Save(ctx context.Context, rec Recording) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("序列化录音条目失败: %w", err)
+	}
+	score := float64(rec.CreatedAt.UnixMilli())
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, recordingHashKey, rec.ID, data)
+	pipe.HSet(ctx, recordingPathKey, rec.Path, rec.ID)
+	pipe.ZAdd(ctx, recordingOrderKey, redis.Z{Score: score, Member: rec.ID})
+	pipe.ZAdd(ctx, recordingRoomKey(rec.RoomID), redis.Z{Score: score, Member: rec.ID})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("保存录音条目失败: %w", err)
+	}
+	return nil
+}
+
+// Get 按ID获取录音条目，不存在时返回 nil
+func (s *RecordingStore) Get(ctx context.Context, id string) (*Recording, error) {
+	data, err := s.rdb.HGet(ctx, recordingHashKey, id).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取录音条目失败: %w", err)
+	}
+	var rec Recording
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return nil, fmt.Errorf("解析录音条目失败: %w", err)
+	}
+	return &rec, nil
+}
+
+// List 按创建时间倒序分页返回符合条件的录音，分页在 Redis 中完成
+//
+// 只按房间和时间筛选时直接按偏移读取一页；有其他条件时按批读取并筛选，凑满一页即停止。
+func (s *RecordingStore) List(ctx context.Context, f RecordingFilter) (RecordingPage, error) {
+	key := recordingOrderKey
+	if f.RoomID != "" {
+		key = recordingRoomKey(f.RoomID)
+	}
+	rng := redis.ZRangeBy{Min: "-inf", Max: "+inf"}
+	if !f.Since.IsZero() {
+		rng.Min = strconv.FormatInt(f.Since.UnixMilli(), 10)
+	}
+	if !f.Until.IsZero() {
+		rng.Max = strconv.FormatInt(f.Until.UnixMilli(), 10)
+	}
+
+	if !f.filtered() {
+		total, err := s.rdb.ZCount(ctx, key, rng.Min, rng.Max).Result()
+		if err != nil {
+			return RecordingPage{}, fmt.Errorf("统计录音数量失败: %w", err)
+		}
+		rng.Offset, rng.Count = int64(f.Offset), int64(f.Limit)
+		if f.Limit <= 0 {
+			rng.Count = -1
+		}
+		ids, err := s.rdb.ZRevRangeByScore(ctx, key, &rng).Result()
+		if err != nil {
+			return RecordingPage{}, fmt.Errorf("获取录音顺序失败: %w", err)
+		}
+		recs, err := s.load(ctx, ids)
+		if err != nil {
+			return RecordingPage{}, err
+		}
+		return RecordingPage{Recordings: recs, Total: int(total), HasMore: int64(f.Offset+len(ids)) < total}, nil
+	}
+
+	page := RecordingPage{Recordings: []Recording{}, Total: -1}
+	skipped := 0
+	for offset := int64(0); ; offset += recordingScanBatch {
+		rng.Offset, rng.Count = offset, recordingScanBatch
+		ids, err := s.rdb.ZRevRangeByScore(ctx, key, &rng).Result()
+		if err != nil {
+			return RecordingPage{}, fmt.Errorf("获取录音顺序失败: %w", err)
+		}
+		recs, err := s.load(ctx, ids)
+		if err != nil {
+			return RecordingPage{}, err
+		}
+		for _, rec := range recs {
+			if !f.match(rec) {
+				continue
+			}
+			if skipped < f.Offset {
+				skipped++
+				continue
+			}
+			if f.Limit > 0 && len(page.Recordings) == f.Limit {
+				page.HasMore = true
+				return page, nil
+			}
+			page.Recordings = append(page.Recordings, rec)
+		}
+		if len(ids) < recordingScanBatch {
+			return page, nil
+		}
+	}
+}
+
+// load 批量读取录音条目，索引与Hash不一致的条目跳过
+func (s *RecordingStore) load(ctx context.Context, ids []string) ([]Recording, error) {
+	if len(ids) == 0 {
+		return []Recording{}, nil
+	}
+	values, err := s.rdb.HMGet(ctx, recordingHashKey, ids...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("批量获取录音条目失败: %w", err)
+	}
+	recs := make([]Recording, 0, len(values))
+	for _, v := range values {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var rec Recording
+		if err := json.Unmarshal([]byte(str), &rec); err != nil {
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	return recs, nil
+}
+
+// Delete 删除录音文件及索引条目，房间音轨连同会话的片段索引一起删除
+func (s *RecordingStore) Delete(ctx context.Context, rec Recording) error {
+	return s.remove(ctx, rec, true)
+}
+
+// remove 删除录音文件及索引条目，dropClips 为 true 时同时删除会话的片段索引
+func (s *RecordingStore) remove(ctx context.Context, rec Recording, dropClips bool) error {
+	if err := s.blobs.Delete(ctx, rec.Path); err != nil {
+		return fmt.Errorf("删除录音文件失败: %w", err)
+	}
+	if !s.sharesSpool() {
+		if err := s.spool.Delete(ctx, rec.Path); err != nil {
+			return fmt.Errorf("删除录音文件失败: %w", err)
+		}
+	}
+	pipe := s.rdb.TxPipeline()
+	pipe.HDel(ctx, recordingHashKey, rec.ID)
+	pipe.HDel(ctx, recordingPathKey, rec.Path)
+	pipe.ZRem(ctx, recordingOrderKey, rec.ID)
+	pipe.ZRem(ctx, recordingRoomKey(rec.RoomID), rec.ID)
+	if dropClips && rec.SessionID != "" {
+		pipe.Del(ctx, clipKey(rec.SessionID))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("删除录音条目失败: %w", err)
+	}
+	return nil
+}
+
+// ByPath 按对象名查找录音条目，未被索引时返回 nil
+func (s *RecordingStore) ByPath(ctx context.Context, key string) (*Recording, error) {
+	id, err := s.rdb.HGet(ctx, recordingPathKey, key).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查找录音条目失败: %w", err)
+	}
+	return s.Get(ctx, id)
+}
+
+// Objects 列出存储后端中的音频文件，包括录音和 Save* 保存的旧版音频
+func (s *RecordingStore) Objects(ctx context.Context) ([]storage.Info, error) {
+	return s.blobs.List(ctx, "")
+}
+
+// DeleteObject 按对象名删除音频文件，已被索引的录音连同索引条目一起删除
+func (s *RecordingStore) DeleteObject(ctx context.Context, key string) error {
+	rec, err := s.ByPath(ctx, key)
+	if err != nil {
+		return err
+	}
+	if rec != nil {
+		return s.Delete(ctx, *rec)
+	}
+	if err := s.blobs.Delete(ctx, key); err != nil {
+		return fmt.Errorf("删除音频文件失败: %w", err)
+	}
+	return nil
+}
+
+// Finish 录音结束后把文件交给存储后端并更新时长与大小，没有录到音频（文件已被删除）时移除条目
+//
+// 空音轨（如会话中没有译音）只删除自身，片段索引仍属于另一条音轨；会话的音轨都已删除时片段索引一并删除，
+// 否则它不再被任何条目引用，保留期清理也无法找到。上传失败时文件留在本地录音目录，仍可通过 Open 下载。
+func (s *RecordingStore) Finish(ctx context.Context, rec Recording, size int64, duration time.Duration) error {
+	if size == 0 {
+		last, err := s.lastOfSession(ctx, rec)
+		if err != nil {
+			return err
+		}
+		return s.remove(ctx, rec, last)
+	}
+	var uploadErr error
+	if !s.sharesSpool() {
+		uploadErr = s.blobs.PutFile(ctx, rec.Path, s.localPath(rec))
+	}
+	now := time.Now()
+	rec.Size, rec.DurationMs, rec.EndedAt = size, duration.Milliseconds(), &now
+	if err := s.Save(ctx, rec); err != nil {
+		return err
+	}
+	if uploadErr != nil {
+		return fmt.Errorf("上传录音 %s 失败: %w", rec.ID, uploadErr)
+	}
+	return nil
+}
+
+// lastOfSession 录音是否为所属会话中仅剩的条目
+func (s *RecordingStore) lastOfSession(ctx context.Context, rec Recording) (bool, error) {
+	if rec.SessionID == "" {
+		return false, nil
+	}
+	page, err := s.List(ctx, RecordingFilter{RoomID: rec.RoomID, SessionID: rec.SessionID})
+	if err != nil {
+		return false, err
+	}
+	for _, other := range page.Recordings {
+		if other.ID != rec.ID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// runRetention 定期删除超过保留期的录音
+func (s *RecordingStore) runRetention(retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		s.purge(time.Now().Add(-retention))
+		<-ticker.C
+	}
+}
+
+// purge 删除 cutoff 之前创建的录音，以及录音目录中未被索引且修改时间早于 cutoff 的文件
+func (s *RecordingStore) purge(cutoff time.Time) {
+	ctx, cancel := context.WithTimeout(Ctx, time.Minute)
+	defer cancel()
+
+	ids, err := s.rdb.ZRangeByScore(ctx, recordingOrderKey, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(cutoff.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		log.Printf("❌ [RECORDING] 读取过期录音失败: %v", err)
+		return
+	}
+	recs, err := s.load(ctx, ids)
+	if err != nil {
+		log.Printf("❌ [RECORDING] %v", err)
+		return
+	}
+	deleted := 0
+	for _, rec := range recs {
+		if rec.EndedAt == nil && time.Since(rec.CreatedAt) < 24*time.Hour { // 可能仍在录制
+			continue
+		}
+		if err := s.Delete(ctx, rec); err != nil {
+			log.Printf("❌ [RECORDING] 删除录音 %s 失败: %v", rec.ID, err)
+			continue
+		}
+		deleted++
+	}
+
+	stores := []storage.BlobStore{s.blobs}
+	if !s.sharesSpool() {
+		stores = append(stores, s.spool)
+	}
+	for _, store := range stores {
+		n, err := s.purgeOrphans(ctx, store, cutoff)
+		if err != nil {
+			log.Printf("❌ [RECORDING] %v", err)
+		}
+		deleted += n
+	}
+	if deleted > 0 {
+		log.Printf("🗑️ [RECORDING] 已清理 %d 个过期录音", deleted)
+	}
+}
+
+// purgeOrphans 删除录音目录中未被索引且修改时间早于 cutoff 的文件（如进程崩溃时未完成登记的录音），
+// 录音目录之外的文件（Save* 保存的旧版音频等）不处理
+func (s *RecordingStore) purgeOrphans(ctx context.Context, store storage.BlobStore, cutoff time.Time) (int, error) {
+	objects, err := store.List(ctx, audio.RecordingsPrefix+"/")
+	if err != nil {
+		return 0, err
+	}
+	var keys []string
+	for _, obj := range objects {
+		if obj.ModTime.Before(cutoff) {
+			keys = append(keys, obj.Key)
+		}
+	}
+	deleted := 0
+	for len(keys) > 0 {
+		batch := keys
+		if len(batch) > recordingScanBatch {
+			batch = batch[:recordingScanBatch]
+		}
+		keys = keys[len(batch):]
+		ids, err := s.rdb.HMGet(ctx, recordingPathKey, batch...).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("查询录音索引失败: %w", err)
+		}
+		for i, id := range ids {
+			if id != nil { // 已被索引，由保留策略按条目删除
+				continue
+			}
+			if store.Delete(ctx, batch[i]) == nil {
+				deleted++
+			}
+		}
+	}
+	return deleted, nil
+}
@@ -3,11 +3,14 @@ package services
 import (
 	"context"
 	"go-backEnd/internal/config"
+
+	"github.com/redis/go-redis/v9"
 )
 
 var (
-	RDB *redis.Client
-	Ctx = context.Background()
+	RDB      *redis.Client
+	Ctx      = context.Background()
+	Messages *MessageStore // 房间消息历史存储
 )
 
 func InitRedis() {
@@ -16,4 +19,5 @@ func InitRedis() {
 		Password: config.AppConfig.RedisPassword,
 		DB:       config.AppConfig.RedisDB,
 	})
+	Messages = NewMessageStore(RDB)
 }
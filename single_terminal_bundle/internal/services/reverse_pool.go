@@ -0,0 +1,160 @@
+// Package services 提供反向翻译的全局工作池与房间内有序队列
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// ReversePoolConfig 反向翻译工作池配置
+type ReversePoolConfig struct {
+	Workers    int           // 并发工作协程数
+	QueueSize  int           // 全局待处理任务上限，写满时新任务被丢弃
+	JobTimeout time.Duration // 单个任务（含重试）的截止时间
+}
+
+// reverseJob 工作池任务
+type reverseJob struct {
+	ctx context.Context           // 房间上下文，房间关闭时任务随之取消
+	run func(ctx context.Context) // 任务本体，无论成功与否都必须返回
+}
+
+// ReversePool 全局反向翻译工作池，所有房间共享固定数量的工作协程
+type ReversePool struct {
+	cfg  ReversePoolConfig
+	jobs chan reverseJob
+}
+
+var (
+	reversePool     *ReversePool
+	reversePoolOnce sync.Once
+)
+
+// InitReversePool 按配置启动全局工作池，需在创建房间前调用
+func InitReversePool(cfg ReversePoolConfig) {
+	reversePoolOnce.Do(func() {
+		reversePool = newReversePool(cfg)
+	})
+}
+
+// GetReversePool 获取全局工作池，未初始化时使用默认配置
+func GetReversePool() *ReversePool {
+	reversePoolOnce.Do(func() {
+		reversePool = newReversePool(ReversePoolConfig{})
+	})
+	return reversePool
+}
+
+func newReversePool(cfg ReversePoolConfig) *ReversePool {
+	if cfg.Workers <= 0 {
+		cfg.Workers = 4
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 256
+	}
+	if cfg.JobTimeout <= 0 {
+		cfg.JobTimeout = 45 * time.Second
+	}
+
+	p := &ReversePool{cfg: cfg, jobs: make(chan reverseJob, cfg.QueueSize)}
+	for i := 0; i < cfg.Workers; i++ {
+		go p.worker()
+	}
+	log.Printf("✅ 反向翻译工作池已启动 - 并发: %d, 队列: %d, 超时: %s", cfg.Workers, cfg.QueueSize, cfg.JobTimeout)
+	return p
+}
+
+// worker 依次执行任务，每个任务在房间上下文之上附加截止时间
+func (p *ReversePool) worker() {
+	for job := range p.jobs {
+		ctx, cancel := context.WithTimeout(job.ctx, p.cfg.JobTimeout)
+		job.run(ctx)
+		cancel()
+	}
+}
+
+// submit 提交任务，队列已满时立即返回 false
+func (p *ReversePool) submit(job reverseJob) bool {
+	select {
+	case p.jobs <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+// reverseResult 反向翻译结果，fields 为 nil 表示任务失败、取消或被同一消息的更新任务取代
+type reverseResult struct {
+	messageID string
+	fields    map[string]interface{}
+}
+
+// reverseQueue 房间内的反向翻译有序队列：任务并发执行，结果按提交顺序应用
+type reverseQueue struct {
+	mu      sync.Mutex
+	next    uint64                   // 下一个任务序号
+	apply   uint64                   // 下一个待应用的序号
+	done    map[uint64]reverseResult // 已完成但尚未轮到应用的结果
+	latest  map[string]uint64        // 每条消息最新一个已提交任务的序号，旧任务开始前发现已过时则跳过
+	applyFn func(reverseResult)
+}
+
+func newReverseQueue(applyFn func(reverseResult)) *reverseQueue {
+	return &reverseQueue{
+		done:    make(map[uint64]reverseResult),
+		latest:  make(map[string]uint64),
+		applyFn: applyFn,
+	}
+}
+
+// reserve 分配任务序号
+func (q *reverseQueue) reserve() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	seq := q.next
+	q.next++
+	return seq
+}
+
+// submitted 任务成功提交到工作池后记为该消息的最新任务；提交失败的任务不会取代仍在执行的旧任务
+func (q *reverseQueue) submitted(messageID string, seq uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if seq < q.apply { // 任务已执行完并应用
+		return
+	}
+	if cur, ok := q.latest[messageID]; !ok || seq > cur {
+		q.latest[messageID] = seq
+	}
+}
+
+// superseded 同一消息是否已有更新的已提交任务
+func (q *reverseQueue) superseded(messageID string, seq uint64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	latest, ok := q.latest[messageID]
+	return ok && latest > seq
+}
+
+// complete 记录任务结果，并按序应用所有已就绪的结果；每个序号都必须 complete 一次，否则后续结果会一直等待
+func (q *reverseQueue) complete(seq uint64, result reverseResult) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.done[seq] = result
+	for {
+		res, ok := q.done[q.apply]
+		if !ok {
+			return
+		}
+		delete(q.done, q.apply)
+		if q.latest[res.messageID] == q.apply { // 该消息已无后续任务
+			delete(q.latest, res.messageID)
+		}
+		q.apply++
+		if res.fields != nil {
+			q.applyFn(res)
+		}
+	}
+}
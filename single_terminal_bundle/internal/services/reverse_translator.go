@@ -0,0 +1,200 @@
+// Package services 提供可替换的反向翻译实现
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	translateconvtext "github.com/proveden/translate-conv-text"
+	"github.com/redis/go-redis/v9"
+)
+
+// ReverseRequest 反向翻译请求
+type ReverseRequest struct {
+	ToLanguage string // 译回的语言
+	Context    string // 对话上下文，每行一条 "A:"/"B:" 前缀的消息
+	Text       string // 待翻译文本
+}
+
+// ReverseResponse 反向翻译结果
+type ReverseResponse struct {
+	Text   string  // 译文
+	Cost   float64 // 本次调用产生的费用，失败时也可能产生费用
+	Cached bool    // 是否命中缓存
+}
+
+// ReverseTranslator 反向翻译服务
+type ReverseTranslator interface {
+	Translate(ctx context.Context, req ReverseRequest) (ReverseResponse, error)
+}
+
+// ReverseTranslatorConfig 反向翻译配置
+type ReverseTranslatorConfig struct {
+	Provider      string        // "convtext"（默认）或 "stub"
+	MaxRetries    int           // convtext 最大重试次数
+	CacheTTL      time.Duration // 结果缓存时长，0 表示不缓存
+	ContextWindow int           // 作为上下文的最近消息条数
+}
+
+var (
+	// Reverse 全局反向翻译服务
+	Reverse ReverseTranslator = &ConvTextTranslator{MaxRetries: 3}
+	// ReverseContextWindow 反向翻译上下文的消息条数
+	ReverseContextWindow = 6
+)
+
+// InitReverseTranslator 按配置初始化反向翻译服务，需在 InitRedis 之后调用
+func InitReverseTranslator(cfg ReverseTranslatorConfig) error {
+	var translator ReverseTranslator
+	switch cfg.Provider {
+	case "", "convtext":
+		retries := cfg.MaxRetries
+		if retries <= 0 {
+			retries = 3
+		}
+		translator = &ConvTextTranslator{MaxRetries: retries}
+	case "stub":
+		translator = StubTranslator{}
+	default:
+		return fmt.Errorf("未知的反向翻译服务: %s", cfg.Provider)
+	}
+
+	if cfg.CacheTTL > 0 {
+		translator = NewCachedTranslator(translator, RDB, cfg.CacheTTL)
+	}
+	if cfg.ContextWindow > 0 {
+		ReverseContextWindow = cfg.ContextWindow
+	}
+	Reverse = translator
+	log.Printf("✅ 反向翻译服务: %T, 上下文: %d 条, 缓存: %s", translator, ReverseContextWindow, cfg.CacheTTL)
+	return nil
+}
+
+// ConvTextTranslator 基于 translate-conv-text 的反向翻译，带重试和无上下文降级
+type ConvTextTranslator struct {
+	MaxRetries int
+}
+
+// Translate 实现 ReverseTranslator，超时不超过 ctx 的剩余时间，费用为所有尝试的累计值
+func (t *ConvTextTranslator) Translate(ctx context.Context, req ReverseRequest) (ReverseResponse, error) {
+	var total float64
+	var lastErr error
+
+	for i := 0; i < t.MaxRetries; i++ { // 重试循环
+		timeout := 15 + float64(i*5)            // 计算超时时间
+		if deadline, ok := ctx.Deadline(); ok { // 不超过任务剩余时间
+			remaining := time.Until(deadline).Seconds()
+			if remaining <= 0 {
+				return ReverseResponse{Cost: total}, context.DeadlineExceeded
+			}
+			timeout = math.Min(timeout, remaining)
+		}
+
+		translated, cost, err := translateconvtext.Translate(req.ToLanguage, req.Context, req.Text, timeout)
+		total += cost
+		if err == nil {
+			return ReverseResponse{Text: translated, Cost: total}, nil
+		}
+		lastErr = err
+		log.Printf("[REVERSE] ⚠️ 翻译尝试 %d 失败: %v (cost=%v)", i+1, err, cost)
+
+		if strings.Contains(err.Error(), "unexpected end of JSON input") { // 上下文导致响应解析失败时去掉上下文重试
+			translated, cost, err = translateconvtext.Translate(req.ToLanguage, "", req.Text, timeout)
+			total += cost
+			if err == nil {
+				return ReverseResponse{Text: translated, Cost: total}, nil
+			}
+			lastErr = err
+		}
+
+		select { // 等待后重试，任务取消或超时时立即放弃
+		case <-ctx.Done():
+			return ReverseResponse{Cost: total}, ctx.Err()
+		case <-time.After(time.Second * time.Duration(i+1)):
+		}
+	}
+	return ReverseResponse{Cost: total}, lastErr
+}
+
+// StubTranslator 本地桩实现，不调用外部服务，用于离线调试
+type StubTranslator struct{}
+
+// Translate 实现 ReverseTranslator，原文加语言前缀返回
+func (StubTranslator) Translate(ctx context.Context, req ReverseRequest) (ReverseResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return ReverseResponse{}, err
+	}
+	return ReverseResponse{Text: fmt.Sprintf("[%s] %s", req.ToLanguage, req.Text)}, nil
+}
+
+// CachedTranslator 按 (目标语言, 文本) 内容哈希缓存翻译结果，重复句子不再调用下游服务
+type CachedTranslator struct {
+	next ReverseTranslator
+	rdb  *redis.Client
+	ttl  time.Duration
+}
+
+// NewCachedTranslator 创建带缓存的反向翻译服务
+func NewCachedTranslator(next ReverseTranslator, rdb *redis.Client, ttl time.Duration) *CachedTranslator {
+	return &CachedTranslator{next: next, rdb: rdb, ttl: ttl}
+}
+
+func (c *CachedTranslator) cacheKey(req ReverseRequest) string {
+	sum := sha256.Sum256([]byte(req.ToLanguage + "\x00" + strings.TrimSpace(req.Text)))
+	return "reverse:cache:" + hex.EncodeToString(sum[:])
+}
+
+// Translate 实现 ReverseTranslator，缓存读写失败时直接调用下游服务
+func (c *CachedTranslator) Translate(ctx context.Context, req ReverseRequest) (ReverseResponse, error) {
+	key := c.cacheKey(req)
+	if cached, err := c.rdb.Get(ctx, key).Result(); err == nil {
+		return ReverseResponse{Text: cached, Cached: true}, nil
+	} else if err != redis.Nil {
+		log.Printf("[REVERSE] ⚠️ 读取缓存失败: %v", err)
+	}
+
+	resp, err := c.next.Translate(ctx, req)
+	if err != nil {
+		return resp, err
+	}
+	if err := c.rdb.Set(ctx, key, resp.Text, c.ttl).Err(); err != nil {
+		log.Printf("[REVERSE] ⚠️ 写入缓存失败: %v", err)
+	}
+	return resp, nil
+}
+
+// roomCostKey 房间累计费用，房间关闭后保留以便统计每场会议的费用
+func roomCostKey(roomID string) string {
+	return fmt.Sprintf("room:%s:cost", roomID)
+}
+
+// roomCostTTL 房间费用记录保留时长
+const roomCostTTL = 30 * 24 * time.Hour
+
+// RecordRoomCost 累加房间的反向翻译费用与调用次数
+func RecordRoomCost(ctx context.Context, roomID string, resp ReverseResponse) {
+	GetTranslationMonitor().RecordReverseCost(roomID, resp.Cost, resp.Cached)
+
+	key := roomCostKey(roomID)
+	pipe := RDB.TxPipeline()
+	pipe.HIncrByFloat(ctx, key, "reverse_translation", resp.Cost)
+	pipe.HIncrBy(ctx, key, "reverse_requests", 1)
+	if resp.Cached {
+		pipe.HIncrBy(ctx, key, "reverse_cache_hits", 1)
+	}
+	pipe.Expire(ctx, key, roomCostTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("[REVERSE %s] ❌ 记录费用失败: %v", roomID, err)
+	}
+}
+
+// RoomCost 读取房间累计费用
+func RoomCost(ctx context.Context, roomID string) (map[string]string, error) {
+	return RDB.HGetAll(ctx, roomCostKey(roomID)).Result()
+}
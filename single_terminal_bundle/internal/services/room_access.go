@@ -0,0 +1,288 @@
+// Package services 提供房间登记、加入令牌与主持人权限
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// 加入令牌角色
+const (
+	TokenRoleHost     = "host"     // 主持人：发言，可移出参与者、锁定房间，锁定后仍可加入
+	TokenRoleSpeaker  = "speaker"  // 发言人
+	TokenRoleListener = "listener" // 听众：只能以听众身份加入
+)
+
+// tokenIdentityPrefix 加入令牌在移出名单中的身份前缀，后接令牌ID
+const tokenIdentityPrefix = "token:"
+
+// joinTokenAudience 加入令牌的 aud，与上游翻译服务令牌区分
+const joinTokenAudience = "room_join"
+
+// ErrRoomNotRegistered 房间未通过 POST /rooms 登记
+var ErrRoomNotRegistered = errors.New("房间未登记")
+
+// RoomAccessConfig 房间访问控制配置
+type RoomAccessConfig struct {
+	Secret            string        // 加入令牌签名密钥，为空时随机生成（重启后已签发的令牌失效，集群模式下必须配置）
+	TokenTTL          time.Duration // 加入令牌有效期，只在加入时校验
+	RoomTTL           time.Duration // 房间登记信息保留时长，从创建时起算
+	RequireRegistered bool          // 只允许加入已登记的房间，关闭时仍可使用任意房间ID
+}
+
+// RoomInfo 已登记房间的信息
+type RoomInfo struct {
+	ID           string    `json:"room_id"`
+	Owner        string    `json:"owner"` // 创建者的会话身份，只有创建者或主持人令牌持有者可以签发令牌
+	Mode         string    `json:"mode"`
+	FromLanguage string    `json:"from_language"`
+	ToLanguage   string    `json:"to_language"`
+	Private      bool      `json:"private"` // 私有房间只能凭加入令牌进入
+	Locked       bool      `json:"locked"`  // 锁定后只有主持人可以加入
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// IssuedToken 已签发加入令牌的记录，令牌本身不保存
+type IssuedToken struct {
+	ID        string    `json:"token_id"`
+	Role      string    `json:"role"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// JoinClaims 加入令牌声明
+type JoinClaims struct {
+	Room string `json:"room"`
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// RoomAccess 房间登记信息和已吊销的加入令牌保存在 Redis 中，集群内各实例共享
+type RoomAccess struct {
+	rdb    *redis.Client
+	secret []byte
+	cfg    RoomAccessConfig
+}
+
+// Rooms 全局房间访问控制，由 InitRoomAccess 初始化
+var Rooms *RoomAccess
+
+// InitRoomAccess 初始化房间访问控制
+func InitRoomAccess(cfg RoomAccessConfig) {
+	secret := []byte(cfg.Secret)
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatalf("❌ 生成加入令牌密钥失败: %v", err)
+		}
+		log.Printf("⚠️ 未配置 ROOM_TOKEN_SECRET，使用随机密钥，重启后已签发的加入令牌失效")
+	}
+	Rooms = &RoomAccess{rdb: RDB, secret: secret, cfg: cfg}
+}
+
+func roomInfoKey(roomID string) string {
+	return fmt.Sprintf("room:%s:info", roomID)
+}
+
+// kickedKey 被移出的参与者身份集合，包括加入令牌和登录会话
+func kickedKey(roomID string) string {
+	return fmt.Sprintf("room:%s:kicked", roomID)
+}
+
+// issuedTokensKey 已签发的加入令牌记录
+func issuedTokensKey(roomID string) string {
+	return fmt.Sprintf("room:%s:tokens", roomID)
+}
+
+// TokenIdentity 返回加入令牌在移出名单中的身份
+func TokenIdentity(tokenID string) string {
+	return tokenIdentityPrefix + tokenID
+}
+
+// RequireRegistered 是否只允许加入已登记的房间
+func (a *RoomAccess) RequireRegistered() bool {
+	return a.cfg.RequireRegistered
+}
+
+// Create 登记新房间，ID 由服务端生成，owner 为创建者的会话身份
+func (a *RoomAccess) Create(ctx context.Context, owner, mode, fromLang, toLang string, private bool) (*RoomInfo, error) {
+	now := time.Now()
+	info := &RoomInfo{
+		ID:           uuid.New().String(),
+		Owner:        owner,
+		Mode:         mode,
+		FromLanguage: fromLang,
+		ToLanguage:   toLang,
+		Private:      private,
+		CreatedAt:    now,
+		ExpiresAt:    now.Add(a.cfg.RoomTTL),
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, fmt.Errorf("序列化房间信息失败: %w", err)
+	}
+	ok, err := a.rdb.SetNX(ctx, roomInfoKey(info.ID), data, a.cfg.RoomTTL).Result()
+	if err != nil {
+		return nil, fmt.Errorf("登记房间失败: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("房间 %s 已存在", info.ID)
+	}
+	return info, nil
+}
+
+// Get 获取房间信息，未登记或已过期时返回 nil
+func (a *RoomAccess) Get(ctx context.Context, roomID string) (*RoomInfo, error) {
+	data, err := a.rdb.Get(ctx, roomInfoKey(roomID)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取房间信息失败: %w", err)
+	}
+	var info RoomInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("解析房间信息失败: %w", err)
+	}
+	return &info, nil
+}
+
+// SetLocked 锁定或解锁房间，保留原有过期时间
+func (a *RoomAccess) SetLocked(ctx context.Context, roomID string, locked bool) error {
+	info, err := a.Get(ctx, roomID)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return ErrRoomNotRegistered
+	}
+	info.Locked = locked
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("序列化房间信息失败: %w", err)
+	}
+	if err := a.rdb.Set(ctx, roomInfoKey(roomID), data, redis.KeepTTL).Err(); err != nil {
+		return fmt.Errorf("更新房间信息失败: %w", err)
+	}
+	return nil
+}
+
+// IssueToken 签发加入令牌，每次签发的令牌ID不同，应为每位参与者单独签发，移出时只影响该参与者
+func (a *RoomAccess) IssueToken(ctx context.Context, roomID, role string) (string, time.Time, error) {
+	switch role {
+	case TokenRoleHost, TokenRoleSpeaker, TokenRoleListener:
+	default:
+		return "", time.Time{}, fmt.Errorf("未知的令牌角色: %s", role)
+	}
+	now := time.Now()
+	expiresAt := now.Add(a.cfg.TokenTTL)
+	claims := JoinClaims{
+		Room: roomID,
+		Role: role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Audience:  jwt.ClaimStrings{joinTokenAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(a.secret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("签发加入令牌失败: %w", err)
+	}
+
+	record, err := json.Marshal(IssuedToken{ID: claims.ID, Role: role, IssuedAt: now, ExpiresAt: expiresAt})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("序列化令牌记录失败: %w", err)
+	}
+	key := issuedTokensKey(roomID)
+	pipe := a.rdb.TxPipeline()
+	pipe.HSet(ctx, key, claims.ID, record)
+	pipe.Expire(ctx, key, a.cfg.RoomTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", time.Time{}, fmt.Errorf("保存令牌记录失败: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// ListTokens 按签发时间倒序列出房间已签发的加入令牌
+func (a *RoomAccess) ListTokens(ctx context.Context, roomID string) ([]IssuedToken, error) {
+	records, err := a.rdb.HGetAll(ctx, issuedTokensKey(roomID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取令牌记录失败: %w", err)
+	}
+	tokens := make([]IssuedToken, 0, len(records))
+	for _, data := range records {
+		var t IssuedToken
+		if err := json.Unmarshal([]byte(data), &t); err != nil {
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].IssuedAt.After(tokens[j].IssuedAt) })
+	return tokens, nil
+}
+
+// ParseToken 校验加入令牌的签名、有效期和用途
+func (a *RoomAccess) ParseToken(token string) (*JoinClaims, error) {
+	var claims JoinClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithAudience(joinTokenAudience))
+	if err != nil {
+		return nil, fmt.Errorf("加入令牌无效: %w", err)
+	}
+	if claims.Room == "" || claims.ID == "" {
+		return nil, fmt.Errorf("加入令牌缺少房间或令牌ID")
+	}
+	return &claims, nil
+}
+
+// Kick 记录被移出参与者的身份（加入令牌和登录会话），之后凭其中任一身份都不能重新加入；记录保留到房间过期
+func (a *RoomAccess) Kick(ctx context.Context, roomID string, identities ...string) error {
+	members := make([]interface{}, 0, len(identities))
+	for _, id := range identities {
+		if id != "" {
+			members = append(members, id)
+		}
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	key := kickedKey(roomID)
+	pipe := a.rdb.TxPipeline()
+	pipe.SAdd(ctx, key, members...)
+	pipe.Expire(ctx, key, a.cfg.RoomTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("记录移出的参与者失败: %w", err)
+	}
+	return nil
+}
+
+// Kicked 判断任一身份是否已被移出房间
+func (a *RoomAccess) Kicked(ctx context.Context, roomID string, identities ...string) (bool, error) {
+	for _, id := range identities {
+		if id == "" {
+			continue
+		}
+		kicked, err := a.rdb.SIsMember(ctx, kickedKey(roomID), id).Result()
+		if err != nil {
+			return false, fmt.Errorf("查询移出记录失败: %w", err)
+		}
+		if kicked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
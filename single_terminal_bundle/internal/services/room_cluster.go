@@ -0,0 +1,266 @@
+// Package services 提供房间服务在集群模式下的扇出与选主逻辑
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"go-backEnd/internal/models"
+	"log"
+	"time"
+)
+
+// broadcast 广播消息：集群模式下发布到 Redis 频道并由 Redis 分配序号，由各实例的订阅协程投递给本地客户端
+func (rs *RoomService) broadcast(frame models.Frame) {
+	if Cluster != nil {
+		payload, err := json.Marshal(frame)
+		if err != nil {
+			log.Printf("[CLUSTER %s] ❌ 序列化广播帧失败: %v", rs.room.ID, err)
+			return
+		}
+		if err := Cluster.PublishBroadcast(rs.ctx, rs.room.ID, payload); err != nil {
+			log.Printf("[CLUSTER %s] ❌ 发布广播失败: %v", rs.room.ID, err)
+		}
+		return
+	}
+	frame.Seq = rs.seq.Add(1)
+	rs.deliverLocal(frame)
+}
+
+// broadcastFromLoop 在房间主循环中广播，非集群模式下直接投递，避免向自身监听的通道发送而阻塞
+func (rs *RoomService) broadcastFromLoop(frame models.Frame) {
+	if Cluster != nil {
+		rs.broadcast(frame)
+		return
+	}
+	frame.Seq = rs.seq.Add(1)
+	rs.fanOut(frame)
+}
+
+// deliverLocal 将消息投递到本实例的房间广播通道
+func (rs *RoomService) deliverLocal(frame models.Frame) {
+	select {
+	case rs.room.Broadcast <- frame:
+	case <-rs.ctx.Done():
+	}
+}
+
+// canOwnUpstream 当前实例是否允许持有上游翻译连接
+func (rs *RoomService) canOwnUpstream() bool {
+	return Cluster == nil || rs.owner.Load()
+}
+
+// startCluster 启动集群协程：订阅房间广播并参与上游连接选主，每个翻译会话只启动一次
+func (rs *RoomService) startCluster(session context.Context) {
+	rs.sessionMu.Lock()
+	defer rs.sessionMu.Unlock()
+	if rs.clusterSession == session {
+		return
+	}
+	rs.clusterSession = session
+
+	rs.room.RelayMu.Lock()
+	rs.room.UpstreamRelay = rs.relayAudio
+	rs.room.RelayMu.Unlock()
+
+	go rs.subscribeBroadcast(session)
+	go rs.runLeaseLoop(session)
+}
+
+// relayAudio 非 owner 实例将客户端音频连同发言人、上游会话和采集时间发布给 owner
+func (rs *RoomService) relayAudio(sp models.Speaker, upstream string, data []byte, captured time.Time) {
+	if err := Cluster.PublishAudio(rs.ctx, rs.room.ID, encodeRelayedAudio(sp, upstream, data, captured)); err != nil {
+		log.Printf("[CLUSTER %s] ❌ 转发音频失败: %v", rs.room.ID, err)
+	}
+}
+
+// relayedAudioHeader 转发音频的头部，Upstream 为空时按 UpstreamA 处理，Captured 为接入实例记录的采集时间
+type relayedAudioHeader struct {
+	models.Speaker
+	Upstream string    `json:"upstream,omitempty"`
+	Captured time.Time `json:"captured"`
+}
+
+// encodeRelayedAudio 编码转发音频：头部 JSON 长度(2, 大端) + 头部 JSON + PCM
+func encodeRelayedAudio(sp models.Speaker, upstream string, pcm []byte, captured time.Time) []byte {
+	header, _ := json.Marshal(relayedAudioHeader{Speaker: sp, Upstream: upstream, Captured: captured})
+	out := make([]byte, 2+len(header)+len(pcm))
+	binary.BigEndian.PutUint16(out, uint16(len(header)))
+	copy(out[2:], header)
+	copy(out[2+len(header):], pcm)
+	return out
+}
+
+// decodeRelayedAudio 解析 encodeRelayedAudio 编码的音频
+func decodeRelayedAudio(data []byte) (relayedAudioHeader, []byte, error) {
+	var h relayedAudioHeader
+	if len(data) < 2 {
+		return h, nil, fmt.Errorf("转发音频过短: %d 字节", len(data))
+	}
+	n := int(binary.BigEndian.Uint16(data))
+	if len(data) < 2+n {
+		return h, nil, fmt.Errorf("转发音频头部不完整")
+	}
+	if err := json.Unmarshal(data[2:2+n], &h); err != nil {
+		return h, nil, fmt.Errorf("解析发言人失败: %w", err)
+	}
+	return h, data[2+n:], nil
+}
+
+// subscribeBroadcast 订阅房间广播频道并投递给本地客户端
+func (rs *RoomService) subscribeBroadcast(ctx context.Context) {
+	monitor := GetTranslationMonitor()
+	monitor.AddGoroutine(rs.room.ID, "cluster_subscriber")
+	defer monitor.RemoveGoroutine(rs.room.ID, "cluster_subscriber")
+
+	pubsub := Cluster.SubscribeBroadcast(ctx, rs.room.ID)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			seq, payload, err := ParseBroadcast(msg.Payload)
+			if err != nil {
+				log.Printf("[CLUSTER %s] ❌ %v", rs.room.ID, err)
+				continue
+			}
+			var frame models.Frame
+			if err := json.Unmarshal([]byte(payload), &frame); err != nil {
+				log.Printf("[CLUSTER %s] ❌ 解析广播帧失败: %v", rs.room.ID, err)
+				continue
+			}
+			frame.Seq = seq
+			rs.observeFrame(frame)
+			rs.deliverLocal(frame)
+		}
+	}
+}
+
+// runLeaseLoop 周期性抢占/续约上游连接租约，成为 owner 时连接上游，失去租约时断开
+func (rs *RoomService) runLeaseLoop(ctx context.Context) {
+	monitor := GetTranslationMonitor()
+	monitor.AddGoroutine(rs.room.ID, "cluster_lease")
+
+	var ownerCancel context.CancelFunc
+	defer func() {
+		if ownerCancel != nil {
+			ownerCancel()
+		}
+		// 使用独立上下文释放租约，会话上下文此时已被取消
+		releaseCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := Cluster.ReleaseLease(releaseCtx, rs.room.ID); err != nil {
+			log.Printf("[CLUSTER %s] ❌ 释放租约失败: %v", rs.room.ID, err)
+		}
+		rs.owner.Store(false)
+		monitor.UpdateOwnerInstance(rs.room.ID, "")
+		monitor.RemoveGoroutine(rs.room.ID, "cluster_lease")
+	}()
+
+	ticker := time.NewTicker(Cluster.LeaseTTL() / 3)
+	defer ticker.Stop()
+
+	for {
+		acquired, err := Cluster.AcquireLease(ctx, rs.room.ID)
+		if err != nil {
+			log.Printf("[CLUSTER %s] ❌ 租约续约失败: %v", rs.room.ID, err)
+			acquired = false
+		}
+
+		switch {
+		case acquired && !rs.owner.Load(): // 成为 owner
+			log.Printf("👑 [CLUSTER %s] 实例 %s 获得上游连接所有权", rs.room.ID, Cluster.InstanceID())
+			rs.owner.Store(true)
+			monitor.UpdateOwnerInstance(rs.room.ID, Cluster.InstanceID())
+
+			rs.room.RelayMu.Lock()
+			rs.room.UpstreamRelay = nil
+			rs.room.RelayMu.Unlock()
+
+			ownerCtx, cancel := context.WithCancel(ctx)
+			ownerCancel = cancel
+			go rs.forwardRelayedAudio(ownerCtx)
+			rs.startUpstreams()
+		case !acquired && rs.owner.Load(): // 失去租约
+			log.Printf("⚠️ [CLUSTER %s] 实例 %s 失去上游连接所有权", rs.room.ID, Cluster.InstanceID())
+			rs.owner.Store(false)
+			if ownerCancel != nil {
+				ownerCancel()
+				ownerCancel = nil
+			}
+			rs.closeUpstreams()
+
+			rs.room.RelayMu.Lock()
+			rs.room.UpstreamRelay = rs.relayAudio
+			rs.room.RelayMu.Unlock()
+
+			owner, _ := Cluster.Owner(ctx, rs.room.ID)
+			monitor.UpdateOwnerInstance(rs.room.ID, owner)
+		}
+
+		if _, err := Cluster.SetClients(ctx, rs.room.ID, rs.localClients.Load()); err != nil { // 心跳：刷新本实例的客户端数
+			log.Printf("[CLUSTER %s] ❌ 上报客户端数失败: %v", rs.room.ID, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// forwardRelayedAudio owner 实例将其他实例转发的音频写入上游连接
+func (rs *RoomService) forwardRelayedAudio(ctx context.Context) {
+	pubsub := Cluster.SubscribeAudio(ctx, rs.room.ID)
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			h, pcm, err := decodeRelayedAudio([]byte(msg.Payload))
+			if err != nil {
+				log.Printf("[CLUSTER %s] ❌ %v", rs.room.ID, err)
+				continue
+			}
+			if h.Captured.IsZero() { // 旧版本实例未携带采集时间
+				h.Captured = time.Now()
+			}
+			rs.room.ForwardAudio(h.Speaker, h.Upstream, pcm, h.Captured)
+		}
+	}
+}
+
+// clusterClientJoined 上报本实例的房间客户端数量
+func (rs *RoomService) clusterClientJoined() {
+	if _, err := Cluster.SetClients(rs.ctx, rs.room.ID, rs.localClients.Add(1)); err != nil {
+		log.Printf("[CLUSTER %s] ❌ 更新客户端计数失败: %v", rs.room.ID, err)
+	}
+}
+
+// clusterClientLeft 上报本实例的房间客户端数量，全集群无客户端时清理消息历史
+func (rs *RoomService) clusterClientLeft() {
+	remaining, err := Cluster.SetClients(rs.ctx, rs.room.ID, rs.localClients.Add(-1))
+	if err != nil {
+		log.Printf("[CLUSTER %s] ❌ 更新客户端计数失败: %v", rs.room.ID, err)
+		return
+	}
+	if remaining <= 0 {
+		_ = Cluster.ResetClients(rs.ctx, rs.room.ID)
+		_ = Messages.Delete(Ctx, rs.room.ID)
+		log.Printf("[CLUSTER %s] 全集群客户端已断开，清理消息历史", rs.room.ID)
+	}
+}
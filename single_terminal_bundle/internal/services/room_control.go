@@ -0,0 +1,254 @@
+// Package services 提供房间控制消息的处理逻辑
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-backEnd/internal/models"
+	"go-backEnd/pkg/languages"
+	"go-backEnd/pkg/protocol"
+	"log"
+)
+
+// handleControl 处理客户端控制消息，只能在房间主循环中调用
+func (rs *RoomService) handleControl(req models.ControlRequest) {
+	err := rs.applyControl(req)
+	if err != nil {
+		log.Printf("⚠️ [ROOM %s] 控制消息 %s 处理失败: %v", rs.room.ID, req.Type, err)
+		if req.Client != nil {
+			rs.sendTo(req.Client, errorFrame("invalid_control", err.Error(), map[string]interface{}{"control": req.Type}))
+		}
+	}
+	if req.Reply != nil {
+		req.Reply <- err
+	}
+}
+
+// applyControl 执行控制请求
+func (rs *RoomService) applyControl(req models.ControlRequest) error {
+	if req.Err != nil {
+		return req.Err
+	}
+
+	switch req.Type {
+	case protocol.ControlMute:
+		if req.Client == nil {
+			return fmt.Errorf("mute 只能由客户端发起")
+		}
+		var payload protocol.MutePayload
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			return fmt.Errorf("mute 负载格式错误: %w", err)
+		}
+		req.Client.SetMuted(payload.Muted)
+		rs.sendTo(req.Client, statusFrame("muted", "", map[string]interface{}{"muted": payload.Muted}))
+		return nil
+	case protocol.ControlEnd:
+		if req.Client == nil {
+			return fmt.Errorf("end 只能由客户端发起")
+		}
+		rs.sendTo(req.Client, statusFrame("ended", "", nil))
+		rs.dropClient(req.Client) // WritePump 写完剩余消息后发送关闭帧
+		return nil
+	case protocol.ControlChangeLanguage:
+		if req.Client != nil && req.Client.Role == models.RoleListener {
+			return fmt.Errorf("听众不能切换房间语言对")
+		}
+		if req.Client != nil && !req.Client.Host { // 已登记的房间只允许主持人切换，临时房间的发言人都可以切换
+			info, err := Rooms.Get(Ctx, rs.room.ID)
+			if err != nil {
+				return err
+			}
+			if info != nil {
+				return fmt.Errorf("只有主持人可以切换房间语言对")
+			}
+		}
+		var payload protocol.ChangeLanguagePayload
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			return fmt.Errorf("change_language 负载格式错误: %w", err)
+		}
+		return rs.changeLanguage(payload.FromLanguage, payload.ToLanguage)
+	case protocol.ControlKick:
+		if req.Client != nil && !req.Client.Host {
+			return fmt.Errorf("只有主持人可以移出参与者")
+		}
+		var payload protocol.KickPayload
+		if err := json.Unmarshal(req.Payload, &payload); err != nil || payload.ClientID == "" {
+			return fmt.Errorf("kick 负载格式错误")
+		}
+		if req.Client != nil && payload.ClientID == req.Client.ID {
+			return fmt.Errorf("不能移出自己")
+		}
+		// 参与者可能连接在其他实例上，由客户端所在实例收到广播后断开，见 applyKick
+		rs.broadcastFromLoop(statusFrame("participant_kicked", "", map[string]interface{}{"client_id": payload.ClientID}))
+		return nil
+	case protocol.ControlLock:
+		if req.Client != nil && !req.Client.Host {
+			return fmt.Errorf("只有主持人可以锁定房间")
+		}
+		var payload protocol.LockPayload
+		if err := json.Unmarshal(req.Payload, &payload); err != nil {
+			return fmt.Errorf("lock 负载格式错误: %w", err)
+		}
+		if err := Rooms.SetLocked(Ctx, rs.room.ID, payload.Locked); err != nil {
+			return err
+		}
+		log.Printf("🔒 [ROOM %s] 房间锁定状态: %v", rs.room.ID, payload.Locked)
+		rs.broadcastFromLoop(statusFrame("room_locked", "", map[string]interface{}{"locked": payload.Locked}))
+		return nil
+	default:
+		return fmt.Errorf("未知的控制类型: %s", req.Type)
+	}
+}
+
+// statusFrame 构造只发给 v1 客户端的 status 事件
+func statusFrame(code, message string, details map[string]interface{}) models.Frame {
+	payload, _ := json.Marshal(protocol.StatusPayload{Code: code, Message: message, Details: details})
+	return models.EventFrame(protocol.EventStatus, payload, nil)
+}
+
+// errorFrame 构造只发给 v1 客户端的 error 事件
+func errorFrame(code, message string, details map[string]interface{}) models.Frame {
+	payload, _ := json.Marshal(protocol.ErrorPayload{Code: code, Message: message, Details: details})
+	return models.EventFrame(protocol.EventError, payload, nil)
+}
+
+// ErrRoomNotActive 集群中没有实例在运行该房间
+var ErrRoomNotActive = errors.New("房间不存在或未在运行")
+
+// validateLanguagePair 校验要切换到的语言对
+func validateLanguagePair(fromLang, toLang string) error {
+	if fromLang == "" || toLang == "" {
+		return fmt.Errorf("from_language 和 to_language 不能为空")
+	}
+	return languages.Default.ValidatePair(fromLang, toLang)
+}
+
+// changeLanguage 切换房间语言对并广播，集群模式下各实例在收到广播时应用，只能在房间主循环中调用
+func (rs *RoomService) changeLanguage(fromLang, toLang string) error {
+	if err := validateLanguagePair(fromLang, toLang); err != nil {
+		return err
+	}
+
+	if Cluster == nil {
+		rs.applyLanguageChange(fromLang, toLang)
+	}
+	rs.broadcastFromLoop(languageChangedFrame(rs.room.ID, fromLang, toLang))
+	return nil
+}
+
+// PublishLanguageChange 为不在本实例上的房间发布语言对切换广播，由运行该房间的实例在收到广播时应用
+//
+// 仅在集群模式下可用，房间没有 owner 实例时返回 ErrRoomNotActive。
+func PublishLanguageChange(ctx context.Context, roomID, fromLang, toLang string) error {
+	if Cluster == nil {
+		return ErrRoomNotActive
+	}
+	if err := validateLanguagePair(fromLang, toLang); err != nil {
+		return err
+	}
+	owner, err := Cluster.Owner(ctx, roomID)
+	if err != nil {
+		return fmt.Errorf("查询房间 owner 失败: %w", err)
+	}
+	if owner == "" {
+		return ErrRoomNotActive
+	}
+	payload, err := json.Marshal(languageChangedFrame(roomID, fromLang, toLang))
+	if err != nil {
+		return err
+	}
+	return Cluster.PublishBroadcast(ctx, roomID, payload)
+}
+
+// applyLanguageChange 更新本地房间语言对，持有上游连接时所有上游会话按新语言对重连
+func (rs *RoomService) applyLanguageChange(fromLang, toLang string) {
+	if !rs.room.SetLanguages(fromLang, toLang) {
+		return
+	}
+	log.Printf("🔀 [ROOM %s] 语言对切换为 %s → %s", rs.room.ID, fromLang, toLang)
+
+	if rs.session().Err() != nil || !rs.canOwnUpstream() { // 无活跃会话或非 owner 时只更新语言对
+		return
+	}
+	rs.closeUpstreams() // 旧连接的读取协程会随之退出
+	rs.startUpstreams() // 按新语言对重新连接上游
+}
+
+// applyKick 参与者被移出的事件投递后断开本地对应的客户端并记录其令牌和会话身份，只能在房间主循环中调用
+func (rs *RoomService) applyKick(frame models.Frame) {
+	if frame.Type != protocol.EventStatus {
+		return
+	}
+	var status protocol.StatusPayload
+	if err := json.Unmarshal(frame.Data, &status); err != nil || status.Code != "participant_kicked" {
+		return
+	}
+	clientID, _ := status.Details["client_id"].(string)
+	for client := range rs.room.Clients {
+		if client.ID != clientID {
+			continue
+		}
+		var tokenIdentity string
+		if client.TokenID != "" {
+			tokenIdentity = TokenIdentity(client.TokenID)
+		}
+		if err := Rooms.Kick(Ctx, rs.room.ID, tokenIdentity, client.Principal); err != nil {
+			log.Printf("❌ [ROOM %s] %v", rs.room.ID, err)
+		}
+		log.Printf("🚪 [ROOM %s] 客户端 %s 已被主持人移出", rs.room.ID, client.ID)
+		rs.dropClient(client) // WritePump 写完 participant_kicked 后发送关闭帧
+	}
+}
+
+// observeFrame 集群模式下检查经 Redis 收到的广播帧，应用其中的房间状态变更
+func (rs *RoomService) observeFrame(frame models.Frame) {
+	if frame.Type != protocol.EventStatus {
+		return
+	}
+	var status protocol.StatusPayload
+	if err := json.Unmarshal(frame.Data, &status); err != nil {
+		return
+	}
+	fromLang, _ := status.Details["from_language"].(string)
+	toLang, _ := status.Details["to_language"].(string)
+	switch status.Code {
+	case "language_changed":
+		if fromLang != "" && toLang != "" {
+			rs.applyLanguageChange(fromLang, toLang)
+		}
+	case "listener_language_added":
+		if toLang != "" {
+			rs.ensureListenerUpstream(models.ListenerUpstream(toLang))
+		}
+	}
+}
+
+// notifyLanguageMismatch 客户端加入参数与房间当前语言对不一致时发送提示，只能在房间主循环中调用
+func (rs *RoomService) notifyLanguageMismatch(client *models.Client) {
+	fromLang, toLang := rs.room.UpstreamLanguages(client.Upstream)
+	if client.RequestedFrom == "" || (client.RequestedFrom == fromLang && client.RequestedTo == toLang) {
+		return
+	}
+	rs.sendTo(client, statusFrame("language_mismatch", "房间已使用其他语言对，已按房间语言对加入", map[string]interface{}{
+		"from_language":           fromLang,
+		"to_language":             toLang,
+		"requested_from_language": client.RequestedFrom,
+		"requested_to_language":   client.RequestedTo,
+	}))
+}
+
+// languageChangedFrame 构造语言对切换事件，旧格式客户端收到与 language_unsupported 同风格的消息
+func languageChangedFrame(roomID, fromLang, toLang string) models.Frame {
+	details := map[string]interface{}{"from_language": fromLang, "to_language": toLang}
+	payload, _ := json.Marshal(protocol.StatusPayload{Code: "language_changed", Details: details})
+	legacy, _ := json.Marshal(map[string]interface{}{
+		"type":          "language_changed",
+		"room_id":       roomID,
+		"from_language": fromLang,
+		"to_language":   toLang,
+		"status":        "changed",
+	})
+	return models.EventFrame(protocol.EventStatus, payload, legacy)
+}
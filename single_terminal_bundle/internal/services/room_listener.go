@@ -0,0 +1,63 @@
+// Package services 提供听众角色的上游会话管理
+package services
+
+import (
+	"go-backEnd/internal/models"
+	"log"
+)
+
+// addListener 听众加入时创建其所选语言的听众会话，集群模式下广播给其他实例，只能在房间主循环中调用
+func (rs *RoomService) addListener(client *models.Client) {
+	if !models.IsListenerUpstream(client.Upstream) { // 收听房间已有的翻译方向
+		return
+	}
+	if rs.ensureListenerUpstream(client.Upstream) && Cluster != nil {
+		_, toLang := rs.room.UpstreamLanguages(client.Upstream)
+		rs.broadcastFromLoop(statusFrame("listener_language_added", "", map[string]interface{}{"to_language": toLang}))
+	}
+}
+
+// ensureListenerUpstream 创建听众会话，当前实例持有上游连接且翻译会话进行中时立即连接，返回是否新建
+func (rs *RoomService) ensureListenerUpstream(key string) bool {
+	up := models.NewUpstream(key, Mixing)
+	if !rs.room.AddUpstream(up) {
+		return false
+	}
+	fromLang, toLang := rs.room.UpstreamLanguages(key)
+	log.Printf("👂 [ROOM %s] 为听众创建 %s → %s 上游会话", rs.room.ID, fromLang, toLang)
+	if rs.session().Err() == nil && rs.canOwnUpstream() {
+		go rs.StartTranslationService(up)
+	}
+	return true
+}
+
+// removeListener 听众离开后没有其他本地听众收听该语言时关闭听众会话，只能在房间主循环中调用
+//
+// 本实例该语言的输出流水线随之释放；集群模式下其他实例可能仍有听众，听众会话保留到翻译会话结束。
+func (rs *RoomService) removeListener(client *models.Client) {
+	if !models.IsListenerUpstream(client.Upstream) {
+		return
+	}
+	for c := range rs.room.Clients {
+		if c.Upstream == client.Upstream {
+			return
+		}
+	}
+	rs.closeUpstreamOutputs(client.Upstream)
+	if Cluster != nil {
+		return
+	}
+	if rs.room.RemoveUpstream(client.Upstream) {
+		_, toLang := rs.room.UpstreamLanguages(client.Upstream)
+		log.Printf("👂 [ROOM %s] 已无听众收听 %s，关闭听众会话", rs.room.ID, toLang)
+	}
+}
+
+// closeListenerUpstreams 翻译会话结束时移除所有听众会话，听众重新加入时按需创建
+func (rs *RoomService) closeListenerUpstreams() {
+	for _, up := range rs.room.Upstreams() {
+		if models.IsListenerUpstream(up.Key) {
+			rs.room.RemoveUpstream(up.Key)
+		}
+	}
+}
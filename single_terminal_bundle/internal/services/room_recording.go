@@ -0,0 +1,420 @@
+// Package services 提供房间双向音频录制：原声混音、译音及按转写消息划分的片段
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-backEnd/internal/models"
+	"go-backEnd/pkg/audio"
+	"io"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// 房间音轨名称
+const (
+	TrackSource      = "source"      // 送往上游的混音原声
+	TrackTranslation = "translation" // 上游返回的译音
+)
+
+// roomRecording 房间音轨录音配置，由 InitRecordings 设置
+var roomRecording audio.RecordingConfig
+
+// Clip 一条转写消息对应的音频片段，时间为相对会话开始的毫秒数
+type Clip struct {
+	MessageID          string `json:"message_id"`
+	SourceStartMs      int64  `json:"source_start_ms"`
+	SourceEndMs        int64  `json:"source_end_ms"`
+	TranslationStartMs int64  `json:"translation_start_ms"` // 没有收到译音时为 -1
+	TranslationEndMs   int64  `json:"translation_end_ms"`   // 没有收到译音时为 -1
+}
+
+// sessionTrackID 房间音轨的录音ID，由会话ID和音轨名确定
+func sessionTrackID(sessionID, track string) string {
+	return sessionID + "_" + track
+}
+
+func clipKey(sessionID string) string {
+	return fmt.Sprintf("recording_session:%s:clips", sessionID)
+}
+
+// sessionRecorder 一次翻译会话的房间录音：原声与译音按墙上时间对齐写入两条音轨，
+// 译音按到达顺序归属到最近一条转写消息
+type sessionRecorder struct {
+	id     string
+	roomID string
+	start  time.Time
+
+	mu            sync.Mutex
+	recorders     map[string]*audio.Recorder
+	stopped       map[string]bool // 写入失败已停止的音轨，已录制部分照常保存
+	entries       map[string]Recording
+	clips         map[string]*Clip
+	lastMessage   string        // 最近开始的消息，随后收到的译音归属于它
+	lastSourceEnd time.Duration // 上一条消息在原声中的结束位置
+}
+
+// newSessionRecorder 创建两条音轨的录音文件并写入索引
+func newSessionRecorder(roomID, fromLang, toLang string) (*sessionRecorder, error) {
+	sr := &sessionRecorder{
+		id:        uuid.New().String(),
+		roomID:    roomID,
+		start:     time.Now(),
+		recorders: make(map[string]*audio.Recorder),
+		stopped:   make(map[string]bool),
+		entries:   make(map[string]Recording),
+		clips:     make(map[string]*Clip),
+	}
+	formats := map[string]audio.Format{TrackSource: audio.UpstreamInput, TrackTranslation: audio.UpstreamOutput}
+	kinds := map[string]string{TrackSource: KindSource, TrackTranslation: KindTranslation}
+	for track, format := range formats {
+		rec, err := audio.NewRecorder(roomRecording.SessionPath(roomID, sr.id, track), format, roomRecording.SyncInterval)
+		if err != nil {
+			sr.close(Ctx)
+			return nil, err
+		}
+		sr.recorders[track] = rec
+		entry := Recording{
+			ID:           sessionTrackID(sr.id, track),
+			Kind:         kinds[track],
+			RoomID:       roomID,
+			SessionID:    sr.id,
+			FromLanguage: fromLang,
+			ToLanguage:   toLang,
+			Format:       format,
+			Container:    ContainerWAV,
+			Path:         Recordings.RelPath(rec.Path()),
+			CreatedAt:    sr.start,
+		}
+		sr.entries[track] = entry
+		if err := Recordings.Save(Ctx, entry); err != nil {
+			log.Printf("❌ [RECORDING %s] %v", roomID, err)
+		}
+	}
+	log.Printf("🎙️ [RECORDING %s] 开始录制会话 %s", roomID, sr.id)
+	return sr, nil
+}
+
+// write 按到达时间写入音轨
+func (sr *sessionRecorder) write(track string, pcm []byte, at time.Time) (start, end time.Duration, ok bool) {
+	rec := sr.recorders[track]
+	if rec == nil || sr.stopped[track] {
+		return 0, 0, false
+	}
+	start, end, err := rec.WriteAligned(pcm, at.Sub(sr.start))
+	if err != nil {
+		log.Printf("❌ [RECORDING %s] %v，停止录制%s音轨", sr.roomID, err, track)
+		_ = rec.Close()
+		sr.stopped[track] = true
+		return 0, 0, false
+	}
+	return start, end, true
+}
+
+// writeSource 写入一帧送往上游的混音
+func (sr *sessionRecorder) writeSource(pcm []byte, at time.Time) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.write(TrackSource, pcm, at)
+}
+
+// writeTranslation 写入一段译音并归属到最近一条消息
+func (sr *sessionRecorder) writeTranslation(pcm []byte, at time.Time) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	start, end, ok := sr.write(TrackTranslation, pcm, at)
+	clip := sr.clips[sr.lastMessage]
+	if !ok || clip == nil {
+		return
+	}
+	if clip.TranslationStartMs < 0 {
+		clip.TranslationStartMs = start.Milliseconds()
+	}
+	clip.TranslationEndMs = end.Milliseconds()
+}
+
+// messageStarted 新的转写消息开始，原声片段从上一条消息结束处算起
+func (sr *sessionRecorder) messageStarted(msgID string) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	sr.clips[msgID] = &Clip{
+		MessageID:          msgID,
+		SourceStartMs:      sr.lastSourceEnd.Milliseconds(),
+		SourceEndMs:        sr.lastSourceEnd.Milliseconds(),
+		TranslationStartMs: -1,
+		TranslationEndMs:   -1,
+	}
+	sr.lastMessage = msgID
+}
+
+// messageFinished 转写消息完成，记录原声片段结束位置并保存片段
+func (sr *sessionRecorder) messageFinished(msgID string, at time.Time) {
+	sr.mu.Lock()
+	clip := sr.clips[msgID]
+	if clip == nil {
+		sr.mu.Unlock()
+		return
+	}
+	sr.lastSourceEnd = at.Sub(sr.start)
+	clip.SourceEndMs = sr.lastSourceEnd.Milliseconds()
+	snapshot := *clip
+	sr.mu.Unlock()
+
+	if err := Recordings.SaveClip(Ctx, sr.id, snapshot); err != nil {
+		log.Printf("❌ [RECORDING %s] %v", sr.roomID, err)
+	}
+}
+
+// close 保存所有片段（译音可能在消息完成后才到达）并关闭两条音轨，空音轨被删除，两条都为空时片段索引一并删除
+func (sr *sessionRecorder) close(ctx context.Context) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+	for track, rec := range sr.recorders {
+		if err := rec.Close(); err != nil {
+			log.Printf("❌ [RECORDING %s] 关闭%s音轨失败: %v", sr.roomID, track, err)
+		}
+	}
+	for _, clip := range sr.clips {
+		if err := Recordings.SaveClip(ctx, sr.id, *clip); err != nil {
+			log.Printf("❌ [RECORDING %s] %v", sr.roomID, err)
+		}
+	}
+	for track, entry := range sr.entries {
+		rec := sr.recorders[track]
+		var size int64
+		var duration time.Duration
+		if rec != nil {
+			size, duration = rec.Size(), rec.Duration()
+		}
+		if err := Recordings.Finish(ctx, entry, size, duration); err != nil {
+			log.Printf("❌ [RECORDING %s] %v", sr.roomID, err)
+		}
+	}
+	sr.entries = map[string]Recording{}
+	for track := range sr.recorders { // 关闭后的写入直接忽略
+		sr.stopped[track] = true
+	}
+}
+
+// sessionRecording 返回当前会话中上游会话的房间录音，首次写入时创建，未开启录音、创建失败或会话已结束时返回 nil
+//
+// 双终端房间的两个翻译方向各录制为一个录音会话，原声与译音分别对应该方向的发言和译音。
+// 会话结束后迟到的写入不再创建录音，否则 closeSessionRecording 之后新建的录音不会被关闭。
+func (rs *RoomService) sessionRecording(up *models.Upstream) *sessionRecorder {
+	if !roomRecording.Enabled || Recordings == nil || models.IsListenerUpstream(up.Key) { // 听众会话与 A 原声相同，不单独录制
+		return nil
+	}
+	rs.recordingMu.Lock()
+	defer rs.recordingMu.Unlock()
+	sr := rs.recordings[up.Key]
+	if sr == nil && !rs.recordingFailed[up.Key] {
+		if rs.session().Err() != nil { // 会话先于录音关闭，持锁检查保证不会在关闭之后创建
+			return nil
+		}
+		fromLang, toLang := rs.room.UpstreamLanguages(up.Key)
+		var err error
+		sr, err = newSessionRecorder(rs.room.ID, fromLang, toLang)
+		if err != nil {
+			log.Printf("❌ [RECORDING %s] %v，本次会话不录制房间音轨", rs.room.ID, err)
+			rs.recordingFailed[up.Key] = true
+			return nil
+		}
+		rs.recordings[up.Key] = sr
+	}
+	return sr
+}
+
+// closeSessionRecording 会话结束时关闭房间录音
+func (rs *RoomService) closeSessionRecording() {
+	rs.recordingMu.Lock()
+	recordings := rs.recordings
+	rs.recordings, rs.recordingFailed = make(map[string]*sessionRecorder), make(map[string]bool)
+	rs.recordingMu.Unlock()
+	for _, sr := range recordings {
+		sr.close(Ctx)
+		log.Printf("🎙️ [RECORDING %s] 会话 %s 录制结束", rs.room.ID, sr.id)
+	}
+}
+
+// SaveClip 保存片段
+func (s *RecordingStore) SaveClip(ctx context.Context, sessionID string, clip Clip) error {
+	data, err := json.Marshal(clip)
+	if err != nil {
+		return fmt.Errorf("序列化片段失败: %w", err)
+	}
+	if err := s.rdb.HSet(ctx, clipKey(sessionID), clip.MessageID, data).Err(); err != nil {
+		return fmt.Errorf("保存片段失败: %w", err)
+	}
+	return nil
+}
+
+// Clips 返回会话的片段，按原声开始时间排序
+func (s *RecordingStore) Clips(ctx context.Context, sessionID string) ([]Clip, error) {
+	values, err := s.rdb.HGetAll(ctx, clipKey(sessionID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("获取片段失败: %w", err)
+	}
+	clips := make([]Clip, 0, len(values))
+	for _, v := range values {
+		var clip Clip
+		if err := json.Unmarshal([]byte(v), &clip); err != nil {
+			continue
+		}
+		clips = append(clips, clip)
+	}
+	sort.Slice(clips, func(i, j int) bool {
+		if clips[i].SourceStartMs != clips[j].SourceStartMs {
+			return clips[i].SourceStartMs < clips[j].SourceStartMs
+		}
+		return clips[i].SourceEndMs < clips[j].SourceEndMs
+	})
+	return clips, nil
+}
+
+// SessionTrack 获取会话某条音轨的录音条目，不存在时返回 nil
+func (s *RecordingStore) SessionTrack(ctx context.Context, sessionID, track string) (*Recording, error) {
+	return s.Get(ctx, sessionTrackID(sessionID, track))
+}
+
+// StereoExport 会话立体声导出：左声道原声，右声道译音
+type StereoExport struct {
+	Format audio.Format // 输出格式，采样率取译音音轨的采样率
+	Size   int64        // 音频数据字节数（不含头部）
+
+	left, right *monoTrack
+	closers     []io.Closer
+}
+
+// OpenStereoExport 打开会话两条音轨，缺失的音轨按静音处理
+func (s *RecordingStore) OpenStereoExport(ctx context.Context, sessionID string) (*StereoExport, error) {
+	mono := audio.Format{SampleRate: audio.UpstreamOutput.SampleRate, Channels: 1, Encoding: audio.EncodingS16LE}
+	exp := &StereoExport{Format: audio.Format{SampleRate: mono.SampleRate, Channels: 2, Encoding: audio.EncodingS16LE}}
+
+	var frames int64
+	for _, track := range []string{TrackSource, TrackTranslation} {
+		t, err := s.openMonoTrack(ctx, sessionID, track, mono)
+		if err != nil {
+			exp.Close()
+			return nil, err
+		}
+		if t.closer != nil {
+			exp.closers = append(exp.closers, t.closer)
+		}
+		if t.frames > frames {
+			frames = t.frames
+		}
+		if track == TrackSource {
+			exp.left = t
+		} else {
+			exp.right = t
+		}
+	}
+	if len(exp.closers) == 0 {
+		return nil, nil
+	}
+	exp.Size = frames * int64(exp.Format.BlockAlign())
+	return exp, nil
+}
+
+// WriteTo 写出交错后的立体声 PCM，长度恰好为 Size
+func (e *StereoExport) WriteTo(w io.Writer) (int64, error) {
+	const chunkFrames = 4096
+	var written int64
+	for written < e.Size {
+		frames := (e.Size - written) / int64(e.Format.BlockAlign())
+		if frames > chunkFrames {
+			frames = chunkFrames
+		}
+		n := int(frames) * 2
+		left, err := e.left.read(n)
+		if err != nil {
+			return written, err
+		}
+		right, err := e.right.read(n)
+		if err != nil {
+			return written, err
+		}
+		m, err := w.Write(audio.InterleaveStereo(left, right))
+		written += int64(m)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Close 关闭音轨
+func (e *StereoExport) Close() {
+	for _, c := range e.closers {
+		c.Close()
+	}
+}
+
+// monoTrack 把一条音轨转换为目标单声道格式后按需读取，读完后补静音
+type monoTrack struct {
+	r      io.Reader
+	conv   *audio.Processor
+	closer io.Closer
+	frames int64 // 转换后的采样帧数
+	buf    []byte
+	eof    bool
+}
+
+// openMonoTrack 打开音轨，音轨不存在时返回只输出静音的空音轨
+func (s *RecordingStore) openMonoTrack(ctx context.Context, sessionID, track string, out audio.Format) (*monoTrack, error) {
+	rec, err := s.SessionTrack(ctx, sessionID, track)
+	if err != nil {
+		return nil, err
+	}
+	if rec == nil {
+		return &monoTrack{eof: true}, nil
+	}
+	obj, err := s.Open(ctx, *rec)
+	if err != nil {
+		return &monoTrack{eof: true}, nil // 音轨文件缺失按静音处理
+	}
+	dataSize := obj.Info().Size - rec.DataOffset()
+	if dataSize < 0 {
+		dataSize = 0
+	}
+	inFrames := dataSize / int64(rec.Format.BlockAlign())
+	t := &monoTrack{
+		r:      io.NewSectionReader(obj, rec.DataOffset(), inFrames*int64(rec.Format.BlockAlign())),
+		closer: obj,
+		frames: inFrames * int64(out.SampleRate) / int64(rec.Format.SampleRate),
+	}
+	if rec.Format != out {
+		t.conv = audio.NewFormatProcessor(rec.Format, out)
+	}
+	return t, nil
+}
+
+// read 返回恰好 n 字节
+func (t *monoTrack) read(n int) ([]byte, error) {
+	chunk := make([]byte, 32*1024)
+	for len(t.buf) < n && !t.eof {
+		m, err := t.r.Read(chunk)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		data := chunk[:m]
+		if err == io.EOF {
+			t.eof = true
+		}
+		if t.conv != nil {
+			if data, err = t.conv.Resample(data, t.eof); err != nil {
+				return nil, err
+			}
+		}
+		t.buf = append(t.buf, data...)
+	}
+	out := make([]byte, n)
+	copied := copy(out, t.buf)
+	t.buf = t.buf[copied:]
+	return out, nil
+}
@@ -2,7 +2,6 @@
 package services
 
 import (
-	"bytes"                      // 字节缓冲区操作
 	"context"                    // 上下文管理
 	"crypto/tls"                 // TLS加密连接
 	"crypto/x509"                // X.509证书处理
@@ -11,19 +10,80 @@ import (
 	"go-backEnd/internal/config" // 配置管理
 	"go-backEnd/internal/models" // 数据模型
 	"go-backEnd/pkg/audio"       // 音频处理包
+	"go-backEnd/pkg/languages"   // 语言目录
+	"go-backEnd/pkg/protocol"    // 客户端消息协议
+	"go-backEnd/pkg/segmenter"   // 分句
 	"log"                        // 日志记录
 	"strings"                    // 字符串操作
+	"sync"                       // 同步原语
+	"sync/atomic"                // 原子操作
 	"time"                       // 时间处理
 
 	"github.com/google/uuid"       // UUID生成
 	"github.com/gorilla/websocket" // WebSocket连接
 )
 
+// SegmentationConfig 流式译文分句配置
+type SegmentationConfig struct {
+	MaxRunes int           // 未出现句末标点时的最大累计字符数，超过后强制切分
+	MaxWait  time.Duration // 距上次切分的最长时间，超过后在收到新文本时强制切分
+}
+
+// Segmentation 全局分句配置
+var Segmentation = SegmentationConfig{MaxRunes: 160, MaxWait: 6 * time.Second}
+
+// Mixing 全局混音配置
+var Mixing = audio.DefaultMixerConfig()
+
 // RoomService 房间服务结构体，负责管理单个房间的所有业务逻辑
 type RoomService struct {
 	room   *models.Room       // 关联的房间对象指针
-	ctx    context.Context    // 用于协程生命周期管理
+	ctx    context.Context    // 房间生命周期上下文，Stop 时取消
 	cancel context.CancelFunc // 取消函数，用于停止所有协程
+
+	sessionMu      sync.Mutex         // 保护翻译会话上下文
+	sessionCtx     context.Context    // 翻译会话上下文：首个客户端加入时创建，最后一个客户端离开时取消
+	sessionCancel  context.CancelFunc // 翻译会话取消函数
+	clusterSession context.Context    // 已启动集群协程的翻译会话
+
+	owner        atomic.Bool   // 集群模式下当前实例是否持有上游连接
+	seq          atomic.Uint64 // 非集群模式下的房间广播序号，集群模式下由 Redis 分配
+	localClients atomic.Int64  // 集群模式下本实例的房间客户端数，由租约循环定期上报
+
+	reverse *reverseQueue // 反向翻译有序队列
+
+	recordingMu     sync.Mutex                  // 保护房间音轨录音
+	recordings      map[string]*sessionRecorder // 当前会话各上游会话的房间音轨录音，首次写入时创建
+	recordingFailed map[string]bool             // 本次会话创建录音失败的上游会话，不再重试
+
+	outputs map[outputKey]*outputPipeline // 按上游会话和客户端输出格式转换上游音频，每个组合一条流水线，只在房间主循环中使用
+}
+
+// outputKey 上游会话与客户端播放音频的格式和编码；流水线带有跨帧状态，不同上游会话的音频不能共用
+type outputKey struct {
+	upstream string
+	format   audio.Format
+	codec    string
+}
+
+// outputPipeline 上游音频到某种客户端输出的转换流水线，重采样器和编码器都带有跨帧状态
+type outputPipeline struct {
+	proc *audio.Processor
+	enc  audio.PacketEncoder // PCM 输出时为 nil
+	err  error               // 无法创建编码器时的错误，该输出不发送音频
+}
+
+// process 转换一帧上游音频，编码器缓冲不足一帧时返回 nil
+func (p *outputPipeline) process(pcm []byte) ([]byte, error) {
+	data, err := p.proc.Resample(pcm, false)
+	if err != nil || p.enc == nil {
+		return data, err
+	}
+	packets, err := p.enc.Encode(data)
+	if err != nil || len(packets) == 0 {
+		return nil, err
+	}
+	return audio.PackPackets(packets), nil
 }
 
 // NewRoomService 创建新的房间服务实例
@@ -32,13 +92,61 @@ func NewRoomService(room *models.Room) *RoomService {
 
 	// 注册房间到监控系统
 	monitor := GetTranslationMonitor()
-	monitor.RegisterRoom(room.ID, "single", len(room.Clients))
+	monitor.RegisterRoom(room.ID, room.Mode.MonitorType(), len(room.Clients))
+
+	rs := &RoomService{
+		room:            room,   // 关联的房间对象指针
+		ctx:             ctx,    // 上下文对象
+		cancel:          cancel, // 取消函数
+		recordings:      make(map[string]*sessionRecorder),
+		recordingFailed: make(map[string]bool),
+		outputs:         make(map[outputKey]*outputPipeline),
+	}
+	rs.reverse = newReverseQueue(rs.applyReverseResult)
+	room.AddUpstream(models.NewUpstream(models.UpstreamA, Mixing)) // 单终端和双终端房间共用的一路
+	if room.Mode == models.ModeDual {                              // 双终端房间的反方向
+		room.AddUpstream(models.NewUpstream(models.UpstreamB, Mixing))
+	}
+	room.OnVoiceActivity = rs.handleVoiceActivity
+	return rs
+}
+
+// Stop 停止房间服务，由 RoomManager 在房间关闭时调用
+func (rs *RoomService) Stop() {
+	rs.cancel()
+}
 
-	return &RoomService{
-		room:   room,   // 关联的房间对象指针
-		ctx:    ctx,    // 上下文对象
-		cancel: cancel, // 取消函数
+// beginSession 开始新的翻译会话，已有活跃会话时返回该会话
+func (rs *RoomService) beginSession() context.Context {
+	rs.sessionMu.Lock()
+	defer rs.sessionMu.Unlock()
+	if rs.sessionCtx != nil && rs.sessionCtx.Err() == nil {
+		return rs.sessionCtx
 	}
+	rs.sessionCtx, rs.sessionCancel = context.WithCancel(rs.ctx)
+	return rs.sessionCtx
+}
+
+// endSession 结束当前翻译会话，取消所有会话内的协程
+func (rs *RoomService) endSession() {
+	rs.sessionMu.Lock()
+	defer rs.sessionMu.Unlock()
+	if rs.sessionCancel != nil {
+		rs.sessionCancel()
+	}
+	rs.sessionCtx, rs.sessionCancel = nil, nil
+}
+
+// session 返回当前翻译会话上下文，无活跃会话时返回已取消的上下文
+func (rs *RoomService) session() context.Context {
+	rs.sessionMu.Lock()
+	defer rs.sessionMu.Unlock()
+	if rs.sessionCtx != nil {
+		return rs.sessionCtx
+	}
+	ctx, cancel := context.WithCancel(rs.ctx)
+	cancel()
+	return ctx
 }
 
 // Run 房间服务主运行循环，处理所有房间相关的事件
@@ -57,6 +165,9 @@ func (rs *RoomService) Run() {
 		monitor.RemoveGoroutine(rs.room.ID, "room_service")
 		monitor.UnregisterRoom(rs.room.ID)
 		rs.cancel() // 确保退出时取消所有协程
+		rs.closeUpstreams()
+		rs.closeOutputs()
+		rs.closeSessionRecording()
 	}()
 
 	for { // 无限循环处理房间事件
@@ -65,21 +176,30 @@ func (rs *RoomService) Run() {
 			log.Printf("🛑 [ROOM %s] 服务正常退出", rs.room.ID)
 			return
 		case client := <-rs.room.Register: // 处理客户端注册事件
-			rs.room.Clients[client] = true                            // 将客户端添加到房间客户端映射中
-			rs.room.ClientAudioBuffers.Store(client, &bytes.Buffer{}) // 为客户端创建音频缓冲区
-			rs.room.ShouldStopTrans = false                           // 设置翻译服务不应停止
+			rs.room.Clients[client] = true    // 将客户端添加到房间客户端映射中
+			rs.room.ShouldStopTrans = false   // 设置翻译服务不应停止
+			rs.notifyLanguageMismatch(client) // 加入参数与房间语言对不一致时提示客户端
 
 			// 更新监控中的客户端数量
 			monitor.UpdateClientCount(rs.room.ID, len(rs.room.Clients))
 
-			if rs.room.TranslationWS == nil { // 如果翻译WebSocket连接不存在
-				go rs.StartTranslationService() // 启动翻译服务协程
+			session := rs.beginSession() // 开始（或沿用）翻译会话
+			if Cluster != nil {          // 集群模式下由租约协程决定是否连接上游
+				rs.clusterClientJoined()
+				rs.startCluster(session)
+			} else {
+				rs.startUpstreams() // 为尚未连接的上游会话启动翻译服务
+			}
+			if client.Role == models.RoleListener {
+				rs.addListener(client) // 听众选择的语言需要单独的上游会话时创建
 			}
 		case client := <-rs.room.Unregister: // 处理客户端注销事件
-			if _, ok := rs.room.Clients[client]; ok { // 检查客户端是否存在于房间中
-				delete(rs.room.Clients, client)           // 从房间客户端映射中删除客户端
-				close(client.Send)                        // 关闭客户端发送通道
-				rs.room.ClientAudioBuffers.Delete(client) // 删除客户端音频缓冲区
+			rs.dropClient(client) // 被踢出或因消费过慢断开的客户端此前已移出房间
+			if client.Role == models.RoleListener {
+				rs.removeListener(client) // 最后一名收听该语言的听众离开时关闭听众会话
+			}
+			if Cluster != nil { // 每个注册过的客户端恰好注销一次
+				rs.clusterClientLeft()
 			}
 
 			// 更新监控中的客户端数量
@@ -90,83 +210,243 @@ func (rs *RoomService) Run() {
 				rs.CloseTranslationService()                       // 关闭翻译服务
 				log.Printf("[ROOM %s] 所有客户端断开，关闭翻译服务", rs.room.ID) // 记录日志
 			}
-		case message := <-rs.room.Broadcast: // 处理广播消息事件
-			for client := range rs.room.Clients { // 遍历房间中的所有客户端
-				select {
-				case client.Send <- message: // 尝试发送消息到客户端
-					// 成功发送，继续下一个客户端
-				default: // 如果发送失败（通道阻塞）
-					close(client.Send)              // 关闭客户端发送通道
-					delete(rs.room.Clients, client) // 从房间中移除客户端
-				}
+		case frame := <-rs.room.Broadcast: // 处理广播消息事件
+			rs.fanOut(frame)
+		case req := <-rs.room.Control: // 处理控制请求
+			rs.handleControl(req)
+		}
+	}
+}
+
+// fanOut 将帧投递给房间内所有本地客户端，只能在房间主循环中调用
+func (rs *RoomService) fanOut(frame models.Frame) {
+	if frame.Kind == models.FrameAudio {
+		rs.fanOutAudio(frame)
+		return
+	}
+	encoder := models.NewFrameEncoder(frame) // 同一帧按协议只编码一次
+	for client := range rs.room.Clients {    // 遍历房间中的所有客户端
+		if !rs.accepts(client, frame) {
+			continue
+		}
+		if out, ok := encoder.For(client.Protocol); ok {
+			rs.deliver(client, frame.Kind, out)
+		}
+	}
+	rs.applyKick(frame) // 集群模式下移出事件同样经由此处到达客户端所在实例
+}
+
+// fanOutAudio 按客户端输出格式转换上游音频后投递，同一上游会话的同一格式只转换一次
+func (rs *RoomService) fanOutAudio(frame models.Frame) {
+	encoders := make(map[outputKey]*models.FrameEncoder)
+	for client := range rs.room.Clients {
+		if !rs.accepts(client, frame) {
+			continue
+		}
+		key := outputKey{upstream: frame.Upstream, format: client.Output, codec: client.Codec}
+		pipeline := rs.outputPipeline(key)
+		if pipeline.err != nil { // 通知客户端后断开，由客户端改用 PCM 重新连接
+			rs.sendTo(client, errorFrame("codec_unavailable", "服务端无法按所选编码发送音频，请改用 PCM 重新连接", map[string]interface{}{
+				"codec": key.codec,
+			}))
+			rs.dropClient(client)
+			continue
+		}
+		encoder, ok := encoders[key]
+		if !ok {
+			converted := frame
+			data, err := pipeline.process(frame.Data)
+			if err != nil {
+				log.Printf("[ROOM %s] ❌ 转换音频到 %s/%s 失败: %v", rs.room.ID, key.format, key.codec, err)
+				data = nil
+			}
+			converted.Data = data
+			if data != nil {
+				encoder = models.NewFrameEncoder(converted)
+			}
+			encoders[key] = encoder
+		}
+		if encoder == nil {
+			continue
+		}
+		if out, ok := encoder.For(client.Protocol); ok {
+			rs.deliver(client, frame.Kind, out)
+		}
+	}
+}
+
+// accepts 判断客户端是否接收该帧：听众只接收所收听会话的转写和译音，发言人不接收听众会话的内容，
+// 双终端房间中不回放发言一方自己的译音
+func (rs *RoomService) accepts(client *models.Client, frame models.Frame) bool {
+	switch {
+	case frame.Upstream == "": // 房间事件
+		return true
+	case client.Role == models.RoleListener:
+		return client.Upstream == frame.Upstream
+	case models.IsListenerUpstream(frame.Upstream):
+		return false
+	case frame.Kind == models.FrameAudio && rs.room.Mode == models.ModeDual:
+		return client.Upstream != frame.Upstream
+	default:
+		return true
+	}
+}
+
+// outputPipeline 返回上游音频到指定输出的转换流水线
+func (rs *RoomService) outputPipeline(key outputKey) *outputPipeline {
+	p, ok := rs.outputs[key]
+	if !ok {
+		p = &outputPipeline{proc: audio.NewFormatProcessor(audio.UpstreamOutput, key.format)}
+		if key.codec == audio.CodecOpus {
+			enc, err := audio.NewOpusEncoder(key.format)
+			if err != nil { // 握手时已校验，不能退回 PCM：客户端会把 PCM 当作 Opus 解码
+				log.Printf("[ROOM %s] ❌ 创建 Opus 编码器失败: %v", rs.room.ID, err)
 			}
+			p.enc, p.err = enc, err
+		}
+		rs.outputs[key] = p
+	}
+	return p
+}
+
+// closeUpstreamOutputs 释放某个上游会话的输出流水线，只能在房间主循环中调用
+func (rs *RoomService) closeUpstreamOutputs(upstream string) {
+	for key, p := range rs.outputs {
+		if key.upstream == upstream {
+			p.proc.Close()
+			delete(rs.outputs, key)
 		}
 	}
 }
 
-// CloseTranslationService 关闭翻译服务连接
+// closeOutputs 释放所有输出流水线，下一次会话的音频不会接上本次会话残留的采样，只能在房间主循环中调用
+func (rs *RoomService) closeOutputs() {
+	for key, p := range rs.outputs {
+		p.proc.Close()
+		delete(rs.outputs, key)
+	}
+}
+
+// deliver 按客户端策略投递消息，缓冲区写满时断开客户端，只能在房间主循环中调用
+func (rs *RoomService) deliver(client *models.Client, kind models.FrameKind, out models.Outbound) {
+	monitor := GetTranslationMonitor()
+	switch client.Enqueue(kind, out) { // 按客户端策略投递
+	case models.Dropped: // 音频帧被丢弃
+		monitor.RecordDroppedFrame(rs.room.ID, client.ID)
+	case models.Overflow: // 缓冲区写满，断开慢客户端
+		log.Printf("⚠️ [ROOM %s] 客户端 %s 消费过慢 (策略=%s)，断开连接", rs.room.ID, client.ID, client.Policy)
+		monitor.RecordSlowConsumerDisconnect(rs.room.ID, client.ID)
+		rs.dropClient(client)
+	}
+}
+
+// dropClient 关闭客户端发送通道并移出房间，WritePump 随后发送关闭帧，只能在房间主循环中调用
+func (rs *RoomService) dropClient(client *models.Client) {
+	if _, ok := rs.room.Clients[client]; !ok {
+		return
+	}
+	close(client.Send)              // 关闭客户端发送通道
+	delete(rs.room.Clients, client) // 从房间中移除客户端
+	for _, up := range rs.room.Upstreams() {
+		up.Forget(client.ID) // 移除发言人记录和混音来源
+	}
+}
+
+// sendTo 向单个客户端发送帧（seq 为 0），只能在房间主循环中调用
+func (rs *RoomService) sendTo(client *models.Client, frame models.Frame) {
+	if _, ok := rs.room.Clients[client]; !ok {
+		return
+	}
+	if out, ok := frame.Encode(client.Protocol); ok {
+		rs.deliver(client, frame.Kind, out)
+	}
+}
+
+// CloseTranslationService 关闭翻译服务连接，房间服务本身继续运行等待新的客户端，只能在房间主循环中调用
 func (rs *RoomService) CloseTranslationService() {
 	// 更新监控中的连接状态
 	monitor := GetTranslationMonitor()
 	monitor.UpdateTranslationConnection(rs.room.ID, false, "", "")
 
-	// 取消所有相关协程
-	rs.cancel()
+	// 取消翻译会话内的所有协程
+	rs.endSession()
 
-	rs.room.TranslationMux.Lock()         // 获取翻译连接互斥锁
-	defer rs.room.TranslationMux.Unlock() // 函数结束时释放锁
-	if rs.room.TranslationWS != nil {     // 如果翻译WebSocket连接存在
-		_ = rs.room.TranslationWS.WriteMessage(websocket.BinaryMessage, []byte("END")) // 发送结束消息
-		_ = rs.room.TranslationWS.Close()                                              // 关闭WebSocket连接
-		rs.room.TranslationWS = nil                                                    // 清空连接对象
+	rs.closeUpstreams()
+	for _, up := range rs.room.Upstreams() {
+		up.Mixer.Reset() // 丢弃未送出的混音数据
+	}
+	rs.closeListenerUpstreams()
+	rs.closeOutputs()
+	rs.closeSessionRecording() // 完成本次会话的房间音轨
+	if Cluster == nil {        // 集群模式下由全集群客户端计数决定何时清理
+		_ = Messages.Delete(Ctx, rs.room.ID) // 删除Redis中的消息历史
 	}
-	redisKey := fmt.Sprintf("room:%s:messages", rs.room.ID) // 构造Redis键名
-	_ = RDB.Del(Ctx, redisKey).Err()                        // 删除Redis中的消息历史
 
-	log.Printf("✅ [ROOM %s] 翻译服务已完全关闭，所有协程已停止", rs.room.ID)
+	log.Printf("✅ [ROOM %s] 翻译服务已完全关闭，会话协程已停止", rs.room.ID)
 }
 
-// StartTranslationService 启动翻译服务连接
-func (rs *RoomService) StartTranslationService() {
-	rs.room.TranslationLock.Lock()         // 获取翻译服务锁
-	defer rs.room.TranslationLock.Unlock() // 函数结束时释放锁
+// closeUpstreams 向所有上游会话发送结束消息并关闭翻译连接
+func (rs *RoomService) closeUpstreams() {
+	for _, up := range rs.room.Upstreams() {
+		up.Close()
+	}
+}
 
-	rs.room.TranslationMux.Lock()     // 获取翻译连接互斥锁
-	if rs.room.TranslationWS != nil { // 如果翻译连接已存在
-		rs.room.TranslationMux.Unlock() // 释放锁
-		return                          // 直接返回
+// startUpstreams 为尚未连接的上游会话启动翻译服务
+func (rs *RoomService) startUpstreams() {
+	for _, up := range rs.room.Upstreams() {
+		if up.Conn() == nil {
+			go rs.StartTranslationService(up)
+		}
 	}
-	rs.room.TranslationMux.Unlock() // 释放锁
+}
 
-	rs.room.ReconnectLock.Lock() // 获取重连锁
-	if rs.room.IsReconnecting {  // 如果正在重连中
-		rs.room.ReconnectLock.Unlock() // 释放锁
-		return                         // 直接返回
+// upstreamName 日志中的上游会话名称，双终端房间附带会话标识
+func (rs *RoomService) upstreamName(up *models.Upstream) string {
+	if rs.room.Mode == models.ModeDual {
+		return rs.room.ID + "/" + up.Key
 	}
-	rs.room.IsReconnecting = true  // 设置重连状态为true
-	rs.room.ReconnectLock.Unlock() // 释放锁
-	defer func() {                 // 设置延迟执行函数
-		rs.room.ReconnectLock.Lock()   // 获取重连锁
-		rs.room.IsReconnecting = false // 设置重连状态为false
-		rs.room.ReconnectLock.Unlock() // 释放锁
-	}()
+	return rs.room.ID
+}
+
+// StartTranslationService 为指定上游会话启动翻译服务连接
+func (rs *RoomService) StartTranslationService(up *models.Upstream) {
+	ctx := rs.session() // 连接归属于当前翻译会话
+	name := rs.upstreamName(up)
+
+	up.DialLock.Lock()         // 串行化建立连接
+	defer up.DialLock.Unlock() // 函数结束时释放锁
+
+	if !up.BeginConnect() { // 已连接或正在重连时直接返回
+		return
+	}
+	defer up.EndConnect()
 
 	for { // 无限循环尝试连接
 		select {
-		case <-rs.ctx.Done(): // 检查上下文是否被取消
-			log.Printf("🛑 [TRANSLATION %s] 翻译服务连接被取消", rs.room.ID)
+		case <-ctx.Done(): // 检查会话是否被取消
+			log.Printf("🛑 [TRANSLATION %s] 翻译服务连接被取消", name)
 			return
 		default:
 		}
 
+		if !rs.canOwnUpstream() { // 集群模式下已失去上游连接所有权
+			log.Printf("[TRANSLATION %s] ❎ 非 owner 实例，停止连接上游", name)
+			return
+		}
+		if rs.room.Upstream(up.Key) != up { // 听众会话已被移除
+			return
+		}
+
+		fromLang, toLang := rs.room.UpstreamLanguages(up.Key)                                               // 每次连接读取最新的语言对
 		token, _ := GenerateJWT()                                                                           // 生成JWT令牌
 		url := fmt.Sprintf("%s?token=%s&from_language=%s&to_language=%s&model=ultra&mute=False&multi=true", // 构造连接URL
-			config.AppConfig.TranslationAPIURL, token, rs.room.FromLanguage, rs.room.ToLanguage)
+			config.AppConfig.TranslationAPIURL, token, fromLang, toLang)
 
 		rootCAs, err := x509.SystemCertPool() // 获取系统证书池
 		if err != nil {                       // 如果获取证书池失败
-			log.Printf("[TRANSLATION %s] ❌ 加载系统证书池失败: %v", rs.room.ID, err) // 记录错误日志
-			return                                                          // 退出函数
+			log.Printf("[TRANSLATION %s] ❌ 加载系统证书池失败: %v", name, err) // 记录错误日志
+			return                                                    // 退出函数
 		}
 
 		tlsConfig := &tls.Config{ // 创建TLS配置
@@ -179,32 +459,84 @@ func (rs *RoomService) StartTranslationService() {
 
 		conn, _, err := dialer.Dial(url, nil) // 尝试连接到翻译服务
 		if err != nil {                       // 如果连接失败
-			log.Printf("[TRANSLATION %s] ❌ 连接失败: %v", rs.room.ID, err) // 记录错误日志
+			log.Printf("[TRANSLATION %s] ❌ 连接失败: %v", name, err) // 记录错误日志
 			monitor := GetTranslationMonitor()
 			monitor.RecordReconnect(rs.room.ID) // 记录重连尝试
 			time.Sleep(2 * time.Second)         // 等待2秒后重试
 			continue                            // 继续下一次循环
 		}
 
-		rs.room.TranslationMux.Lock()   // 获取翻译连接互斥锁
-		rs.room.TranslationWS = conn    // 保存连接对象
-		rs.room.TranslationMux.Unlock() // 释放锁
+		up.SetConn(conn) // 保存连接对象
 
 		// 更新监控中的连接状态
 		monitor := GetTranslationMonitor()
-		monitor.UpdateTranslationConnection(rs.room.ID, true, rs.room.FromLanguage, rs.room.ToLanguage)
+		monitor.UpdateTranslationConnection(rs.room.ID, true, fromLang, toLang)
 
-		go rs.ReadFromTranslation() // 启动读取翻译消息的协程
-		break                       // 退出循环
+		go rs.ReadFromTranslation(ctx, up) // 启动读取翻译消息的协程
+		go rs.runMixer(ctx, up, conn)      // 启动混音协程
+		break                              // 退出循环
 	}
 }
 
-// ReadFromTranslation 从翻译服务读取消息
-func (rs *RoomService) ReadFromTranslation() {
+// handleVoiceActivity 广播客户端开始/停止说话，并统计被静音抑制的音频时长
+func (rs *RoomService) handleVoiceActivity(sp models.Speaker, event audio.VADEvent, suppressed time.Duration) {
+	if suppressed > 0 {
+		GetTranslationMonitor().RecordSuppressedAudio(rs.room.ID, suppressed)
+	}
+
+	var code string
+	switch event {
+	case audio.VADSpeechStart:
+		code = "speech_start"
+	case audio.VADSpeechEnd:
+		code = "speech_end"
+	default:
+		return
+	}
+	rs.broadcast(statusFrame(code, "", map[string]interface{}{"speaker": sp}))
+}
+
+// runMixer 按帧将上游会话的混音结果写入指定连接，连接被替换或会话结束时退出
+func (rs *RoomService) runMixer(ctx context.Context, up *models.Upstream, conn *websocket.Conn) {
+	monitor := GetTranslationMonitor()
+	monitor.AddGoroutine(rs.room.ID, "audio_mixer")
+	defer monitor.RemoveGoroutine(rs.room.ID, "audio_mixer")
+
+	ticker := time.NewTicker(up.Mixer.FrameDuration())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if !up.Is(conn) {
+			return
+		}
+		for { // 一次写出所有已就绪的帧，避免计时器抖动造成积压
+			frame, ok := up.Mixer.Next()
+			if !ok {
+				break
+			}
+			if !up.Write(conn, frame) {
+				return
+			}
+			if rec := rs.sessionRecording(up); rec != nil {
+				rec.writeSource(frame, time.Now())
+			}
+		}
+	}
+}
+
+// ReadFromTranslation 从上游会话的翻译服务读取消息，转写写入房间共享的消息历史并广播
+func (rs *RoomService) ReadFromTranslation(ctx context.Context, up *models.Upstream) {
 	var currentBuffer strings.Builder // 创建字符串构建器用于累积消息
 	var currentMessageID string       // 当前消息ID
-	var lastProcessedPosition int     // 记录已处理的文本位置
-	processor := audio.NewProcessor() // 创建音频处理器
+	var tracker *segmenter.Tracker    // 当前消息的分句状态
+	var speaker models.Speaker        // 当前消息的发言人
+	name := rs.upstreamName(up)
+	listener := models.IsListenerUpstream(up.Key) // 听众会话的转写只发给听众，不写入房间消息历史
 
 	// 添加协程到监控
 	monitor := GetTranslationMonitor()
@@ -213,53 +545,52 @@ func (rs *RoomService) ReadFromTranslation() {
 
 	for { // 无限循环读取消息
 		select {
-		case <-rs.ctx.Done(): // 检查上下文是否被取消
-			log.Printf("🛑 [TRANSLATION %s] 翻译消息读取被取消", rs.room.ID)
+		case <-ctx.Done(): // 检查会话是否被取消
+			log.Printf("🛑 [TRANSLATION %s] 翻译消息读取被取消", name)
 			return
 		default:
 		}
 
-		rs.room.TranslationMux.Lock()   // 获取翻译连接互斥锁
-		conn := rs.room.TranslationWS   // 获取当前连接
-		rs.room.TranslationMux.Unlock() // 释放锁
-		if conn == nil {                // 如果连接为空
-			log.Printf("[TRANSLATION %s] ❌ nil WS，停止接收", rs.room.ID) // 记录错误日志
-			return                                                   // 退出函数
+		conn := up.Conn() // 获取当前连接
+		if conn == nil {  // 如果连接为空
+			log.Printf("[TRANSLATION %s] ❌ nil WS，停止接收", name) // 记录错误日志
+			return                                             // 退出函数
 		}
 
 		msgType, message, err := conn.ReadMessage() // 读取消息
 		if err != nil {                             // 如果读取失败
-			log.Printf("[TRANSLATION %s] ❌ 读取失败: %v", rs.room.ID, err) // 记录错误日志
+			log.Printf("[TRANSLATION %s] ❌ 读取失败: %v", name, err) // 记录错误日志
 
 			// 检查是否是不支持的语言对错误 (close code 4001)
 			if websocket.IsCloseError(err, 4001) {
-				rs.SendUnsupportedLanguageMessage()
-				log.Printf("[TRANSLATION %s] ❌ 不支持的语言对: from=%s, to=%s", rs.room.ID, rs.room.FromLanguage, rs.room.ToLanguage)
+				fromLang, toLang := rs.room.UpstreamLanguages(up.Key)
+				rs.SendUnsupportedLanguageMessage(up)
+				log.Printf("[TRANSLATION %s] ❌ 不支持的语言对: from=%s, to=%s", name, fromLang, toLang)
 			}
 
-			rs.room.TranslationMux.Lock()     // 获取翻译连接互斥锁
-			if rs.room.TranslationWS != nil { // 如果连接存在
-				_ = rs.room.TranslationWS.Close() // 关闭连接
-				rs.room.TranslationWS = nil       // 清空连接对象
-			}
-			rs.room.TranslationMux.Unlock() // 释放锁
+			_ = conn.Close() // 关闭本协程读取的连接
+			up.Detach(conn)  // 只清空自己的连接，切换语言时可能已建立新连接
 
 			go func() { // 启动协程处理重连
 				select {
-				case <-rs.ctx.Done(): // 检查上下文是否被取消
-					log.Printf("🛑 [TRANSLATION %s] 重连协程被取消", rs.room.ID)
+				case <-ctx.Done(): // 检查会话是否被取消
+					log.Printf("🛑 [TRANSLATION %s] 重连协程被取消", name)
 					return
 				case <-time.After(2 * time.Second): // 等待2秒后重连
 					if rs.room.ShouldStopTrans { // 如果应该停止翻译服务
-						log.Printf("[TRANSLATION %s] ❎ 房间空，无需重连", rs.room.ID) // 记录日志
-						return                                                // 退出协程
+						log.Printf("[TRANSLATION %s] ❎ 房间空，无需重连", name) // 记录日志
+						return                                          // 退出协程
+					}
+					if !rs.canOwnUpstream() { // 集群模式下已失去上游连接所有权
+						log.Printf("[TRANSLATION %s] ❎ 非 owner 实例，无需重连", name)
+						return
 					}
 					// 如果是不支持的语言对错误，不进行重连
 					if websocket.IsCloseError(err, 4001) {
-						log.Printf("[TRANSLATION %s] ❌ 语言对不支持，停止重连", rs.room.ID)
+						log.Printf("[TRANSLATION %s] ❌ 语言对不支持，停止重连", name)
 						return
 					}
-					rs.StartTranslationService() // 重新启动翻译服务
+					rs.StartTranslationService(up) // 重新启动翻译服务
 				}
 			}()
 			return // 退出函数
@@ -271,14 +602,14 @@ func (rs *RoomService) ReadFromTranslation() {
 		switch msgType { // 根据消息类型处理
 		case websocket.TextMessage: // 处理文本消息
 			if len(message) == 0 || message[0] != '{' { // 如果消息为空或不是JSON格式
-				log.Printf("[TRANSLATION %s] ⚠️ 非 JSON 消息: %s", rs.room.ID, string(message)) // 记录警告日志
-				continue                                                                     // 跳过此消息
+				log.Printf("[TRANSLATION %s] ⚠️ 非 JSON 消息: %s", name, string(message)) // 记录警告日志
+				continue                                                               // 跳过此消息
 			}
 
 			var data map[string]interface{}                        // 创建数据映射
 			if err := json.Unmarshal(message, &data); err != nil { // 解析JSON消息
-				log.Printf("[TRANSLATION %s] ❌ JSON 解析失败: %v", rs.room.ID, err) // 记录错误日志
-				continue                                                        // 跳过此消息
+				log.Printf("[TRANSLATION %s] ❌ JSON 解析失败: %v", name, err) // 记录错误日志
+				continue                                                  // 跳过此消息
 			}
 
 			text, _ := data["translation"].(string)         // 获取翻译文本
@@ -286,12 +617,21 @@ func (rs *RoomService) ReadFromTranslation() {
 			lang, _ := data["language"].(string)            // 获取语言
 
 			// 记录收到的翻译信息
-			log.Printf("🌐 [单人模式 %s] 收到翻译消息: 语言=%s, 文本='%s', 完成状态=%v", rs.room.ID, lang, text, partFinished)
+			log.Printf("🌐 [TRANSLATION %s] 收到翻译消息: 语言=%s, 文本='%s', 完成状态=%v", name, lang, text, partFinished)
 
 			currentBuffer.WriteString(text) // 将文本添加到缓冲区
 
 			if currentMessageID == "" { // 如果当前消息ID为空
 				currentMessageID = uuid.New().String() // 生成新的UUID作为消息ID
+				segLang := lang                        // 按译文语言分句
+				if segLang == "" {
+					_, segLang = rs.room.UpstreamLanguages(up.Key)
+				}
+				tracker = segmenter.NewTracker(segmenter.For(segLang), Segmentation.MaxRunes, Segmentation.MaxWait)
+				speaker, _ = up.Speakers.Dominant(time.Now()) // 消息开始时该会话近期能量最高的客户端
+				if rec := rs.sessionRecording(up); rec != nil {
+					rec.messageStarted(currentMessageID)
+				}
 			}
 			msgID := currentMessageID // 保存消息ID
 
@@ -306,192 +646,170 @@ func (rs *RoomService) ReadFromTranslation() {
 				"language":             lang,                   // 语言
 				"part_finished":        partFinished,           // 部分完成状态
 				"timestamp":            timestamp,              // 时间戳
-				"user":                 "",                     // 用户标识
+				"user":                 speaker.Name,           // 用户标识（发言人名称）
+				"speaker":              speaker,                // 发言人
 				"reverseTranslation":   "",                     // 反向翻译文本（初始为空）
 				"isReverseTranslation": false,                  // 是否为反向翻译
 			}
 
 			payload, err := json.Marshal(final) // 将映射转换为JSON
 			if err != nil {                     // 如果转换失败
-				log.Printf("[TRANSLATION %s] ❌ JSON 打包失败: %v", rs.room.ID, err) // 记录错误日志
-				currentBuffer.Reset()                                           // 重置缓冲区
-				currentMessageID = ""                                           // 清空消息ID
-				return                                                          // 退出函数
+				log.Printf("[TRANSLATION %s] ❌ JSON 打包失败: %v", name, err) // 记录错误日志
+				currentBuffer.Reset()                                     // 重置缓冲区
+				currentMessageID = ""                                     // 清空消息ID
+				return                                                    // 退出函数
 			}
 
-			rs.room.Broadcast <- payload // 广播消息到房间
+			eventType := protocol.EventTranscriptPartial // 事件类型
+			if partFinished {
+				eventType = protocol.EventTranscriptFinal
+			}
+			frame := models.TextFrame(eventType, payload)
+			frame.Upstream = up.Key
+			rs.broadcast(frame) // 广播消息到房间
 
 			if partFinished { // 如果部分完成
-				// partFinished=true：句子完成，查找是否已存在记录
-				redisKey := fmt.Sprintf("room:%s:messages", rs.room.ID) // 构造Redis键名
-
-				// 获取所有消息，查找当前msgID的记录
-				messages, err := RDB.LRange(Ctx, redisKey, 0, -1).Result()
-				if err != nil {
-					log.Printf("[REDIS] ❌ 获取消息失败: %v", err)
-					return
-				}
-
-				found := false
-				for i, message := range messages {
-					var existingMsg map[string]interface{}
-					if err := json.Unmarshal([]byte(message), &existingMsg); err != nil {
-						continue
-					}
-
-					if existingID, ok := existingMsg["id"].(string); ok && existingID == msgID {
-						// 找到匹配的记录，更新为最终版本
-						if err := RDB.LSet(Ctx, redisKey, int64(i), payload).Err(); err != nil {
-							log.Printf("[REDIS] ❌ 更新失败: %v", err)
-						} else {
-							go rs.HandleReverseTranslation(msgID, lang)
-						}
-						found = true
-						break
-					}
+				// partFinished=true：句子完成，写入最终版本并触发反向翻译
+				if rec := rs.sessionRecording(up); rec != nil {
+					rec.messageFinished(msgID, time.Now())
 				}
-
-				if !found {
-					// 如果没有找到对应记录，创建新记录
-					if err := RDB.RPush(Ctx, redisKey, payload).Err(); err != nil {
-						log.Printf("[REDIS] ❌ 存储失败: %v", err)
-					} else {
-						go rs.HandleReverseTranslation(msgID, lang)
-					}
+				if !listener {
+					rs.saveTranscript(msgID, lang, final)
 				}
 
-				currentBuffer.Reset()     // 重置缓冲区
-				currentMessageID = ""     // 清空消息ID
-				lastProcessedPosition = 0 // 重置位置
-			} else if endPos := rs.isSentenceEndFromPosition(currentBuffer.String(), lastProcessedPosition); endPos > lastProcessedPosition {
-				// partFinished=false但句子完结：查找并更新当前messageID的记录
-				lastProcessedPosition = endPos // 更新已处理位置
-				redisKey := fmt.Sprintf("room:%s:messages", rs.room.ID)
-
-				// 获取所有消息，查找当前msgID的记录
-				messages, err := RDB.LRange(Ctx, redisKey, 0, -1).Result()
-				if err != nil {
-					log.Printf("[REDIS] ❌ 获取消息失败: %v", err)
-					return
-				}
-
-				found := false
-				for i, message := range messages {
-					var existingMsg map[string]interface{}
-					if err := json.Unmarshal([]byte(message), &existingMsg); err != nil {
-						continue
-					}
-
-					if existingID, ok := existingMsg["id"].(string); ok && existingID == msgID {
-						// 找到匹配的记录，更新它
-						if err := RDB.LSet(Ctx, redisKey, int64(i), payload).Err(); err != nil {
-							log.Printf("[REDIS] ❌ 更新失败: %v", err)
-						} else {
-							// 暂时注释掉句子完结时的回翻调用，仅在 part_finished 时触发
-							// go rs.HandleReverseTranslation(msgID, lang)
-						}
-						found = true
-						break
-					}
+				currentBuffer.Reset() // 重置缓冲区
+				currentMessageID = "" // 清空消息ID
+				tracker = nil         // 下一条消息重新分句
+			} else if _, forced, ok := tracker.Advance(currentBuffer.String(), time.Now()); ok {
+				// partFinished=false但句子完结（或超长/超时强制切分）：写入当前messageID的中间版本并回翻已完成部分
+				// 注意：这里不重置buffer和messageID，继续累积直到partFinished
+				if forced {
+					log.Printf("✂️ [TRANSLATION %s] 长时间未出现句末标点，强制切分", name)
 				}
-
-				if !found {
-					// 如果没有找到对应记录，创建新记录
-					if err := RDB.RPush(Ctx, redisKey, payload).Err(); err != nil {
-						log.Printf("[REDIS] ❌ 存储失败: %v", err)
-					} else {
-						// 暂时注释掉句子完结时的回翻调用，仅在 part_finished 时触发
-						// go rs.HandleReverseTranslation(msgID, lang)
-					}
+				if !listener {
+					rs.saveTranscript(msgID, lang, final)
 				}
-				// 注意：这里不重置buffer和messageID，继续累积直到partFinished
 			}
 
 		case websocket.BinaryMessage: // 处理二进制消息（音频数据）
 			if len(message) <= 20 { // 如果消息长度小于等于20字节
-				log.Printf("[TRANSLATION %s] ⚠️ 音频太短跳过", rs.room.ID) // 记录警告日志
-				continue                                             // 跳过此消息
+				log.Printf("[TRANSLATION %s] ⚠️ 音频太短跳过", name) // 记录警告日志
+				continue                                       // 跳过此消息
 			}
-			trimmed := message[20:]                              // 去掉前20字节的头部
-			resampled, err := processor.Resample(trimmed, false) // 重新采样音频
-			if err != nil {                                      // 如果重采样失败
-				log.Printf("[TRANSLATION %s] ❌ 重采样失败: %v", rs.room.ID, err) // 记录错误日志
-				continue                                                    // 跳过此消息
+			trimmed := message[20:] // 去掉前20字节的头部
+			frame := models.AudioFrame(trimmed)
+			frame.Upstream = up.Key
+			rs.broadcast(frame) // 广播上游原始格式的音频，投递时按客户端输出格式转换
+			if rec := rs.sessionRecording(up); rec != nil {
+				rec.writeTranslation(trimmed, time.Now())
 			}
-			rs.room.Broadcast <- resampled // 广播重采样后的音频数据
 		}
 	}
 }
 
-// HandleReverseTranslation 处理反向翻译逻辑
+// saveTranscript 写入转写消息并触发反向翻译，语言对不支持时跳过回翻
+func (rs *RoomService) saveTranscript(msgID, lang string, msg map[string]interface{}) {
+	if err := rs.persistTranscript(msgID, msg); err != nil {
+		log.Printf("[REDIS] ❌ 存储失败: %v", err)
+	} else if languages.Default.SupportsReverse(rs.room.Languages()) {
+		rs.HandleReverseTranslation(msgID, lang)
+	}
+}
+
+// persistTranscript 将转写消息写入房间消息存储，不覆盖反向翻译写入的字段
+func (rs *RoomService) persistTranscript(msgID string, msg map[string]interface{}) error {
+	fields := make(map[string]interface{}, len(msg))
+	for k, v := range msg {
+		if k == "reverseTranslation" { // 由反向翻译维护
+			continue
+		}
+		if k == "user" && v == "" { // 未识别发言人时由反向翻译按语言补全
+			continue
+		}
+		fields[k] = v
+	}
+	_, err := Messages.Merge(Ctx, rs.room.ID, msgID, fields)
+	return err
+}
+
+// HandleReverseTranslation 将反向翻译任务提交到全局工作池，不阻塞调用方，结果按提交顺序写回并广播
 func (rs *RoomService) HandleReverseTranslation(messageID string, lang string) {
-	// 检查Context是否被取消
-	select {
-	case <-rs.ctx.Done():
-		log.Printf("🛑 [REVERSE %s] 反向翻译协程被取消", rs.room.ID)
+	seq := rs.reverse.reserve()
+	submitted := GetReversePool().submit(reverseJob{
+		ctx: rs.ctx,
+		run: func(ctx context.Context) {
+			rs.reverse.complete(seq, rs.runReverseTranslation(ctx, seq, messageID, lang))
+		},
+	})
+	if !submitted {
+		log.Printf("[REVERSE %s] ⚠️ 工作池队列已满，跳过消息 %s 的反向翻译", rs.room.ID, messageID)
+		GetTranslationMonitor().RecordReverseDropped(rs.room.ID)
+		rs.reverse.complete(seq, reverseResult{messageID: messageID})
 		return
-	default:
 	}
+	rs.reverse.submitted(messageID, seq)
+}
 
-	// 添加协程到监控
+// runReverseTranslation 在工作池中执行反向翻译，ctx 携带任务截止时间并随房间关闭取消
+func (rs *RoomService) runReverseTranslation(ctx context.Context, seq uint64, messageID string, lang string) reverseResult {
+	skipped := reverseResult{messageID: messageID}
+
+	// 检查Context是否被取消
+	if ctx.Err() != nil {
+		log.Printf("🛑 [REVERSE %s] 反向翻译任务被取消", rs.room.ID)
+		return skipped
+	}
+	if rs.reverse.superseded(messageID, seq) { // 同一消息已有更新的任务，直接跳过
+		return skipped
+	}
+
+	// 添加任务到监控
 	monitor := GetTranslationMonitor()
 	monitor.AddGoroutine(rs.room.ID, "reverse_translation")
 	defer monitor.RemoveGoroutine(rs.room.ID, "reverse_translation")
 
-	rs.room.TranslationQueueLock.Lock()                        // 获取翻译队列锁
-	defer rs.room.TranslationQueueLock.Unlock()                // 函数结束时释放锁
-	redisKey := fmt.Sprintf("room:%s:messages", rs.room.ID)    // 构造Redis键名
-	messages, err := RDB.LRange(Ctx, redisKey, 0, -1).Result() // 获取所有历史消息
-	if err != nil {                                            // 如果获取失败
-		log.Printf("[REVERSE] ❌ 获取历史消息失败: %v", err) // 记录错误日志
-		return                                      // 退出函数
+	current, err := Messages.Get(ctx, rs.room.ID, messageID) // 按ID获取当前消息
+	if err != nil {                                          // 如果获取失败
+		log.Printf("[REVERSE] ❌ 获取当前消息失败: %v", err) // 记录错误日志
+		return skipped
 	}
-
-	var currentText string                 // 当前文本
-	var user string                        // 用户标识
-	var toLang string                      // 目标语言
-	var targetIndex = -1                   // 目标索引
-	var updatedItem map[string]interface{} // 更新的项目
-	var contextPieces []string             // 上下文片段
-
-	start := len(messages) - 6 // 开始索引（最近6条消息）
-	if start < 0 {             // 如果开始索引小于0
-		start = 0 // 设置为0
+	currentText, _ := current["translation"].(string) // 当前文本
+	if current == nil || currentText == "" {          // 如果消息不存在或文本为空
+		log.Printf("[REVERSE] ⚠️ 未找到匹配 ID=%s 的消息", messageID) // 记录警告日志
+		return skipped
 	}
 
-	onlyOneMessage := len(messages[start:]) == 1 // 是否只有一条消息
+	// 根据lang设定user和toLang
+	var user string   // 未识别发言人时的用户标识
+	var toLang string // 目标语言
+	roomFrom, roomTo := rs.room.Languages()
+	if lang == roomFrom {
+		user = "B:"
+		toLang = roomTo
+	} else {
+		user = "A:"
+		toLang = roomFrom
+	}
+	currentLabel := speakerLabel(current, user)
 
-	for i := start; i < len(messages); i++ { // 遍历消息
-		var m map[string]interface{}                                    // 创建消息映射
-		if err := json.Unmarshal([]byte(messages[i]), &m); err != nil { // 解析JSON消息
-			continue // 解析失败跳过
-		}
+	history, err := Messages.Recent(ctx, rs.room.ID, int64(ReverseContextWindow)) // 获取最近几条消息作为上下文
+	if err != nil {                                                               // 如果获取失败
+		log.Printf("[REVERSE] ❌ 获取历史消息失败: %v", err) // 记录错误日志
+		return skipped
+	}
 
+	var contextPieces []string          // 上下文片段
+	onlyOneMessage := len(history) == 1 // 是否只有一条消息
+	for _, m := range history {         // 遍历消息
 		idStr, _ := m["id"].(string)              // 获取消息ID
-		u, _ := m["user"].(string)                // 获取用户标识
+		u := speakerLabel(m, "")                  // 获取发言人标识
 		t, _ := m["translation"].(string)         // 获取翻译文本
 		rt, _ := m["reverseTranslation"].(string) // 获取反向翻译文本
-		targetLang := m["language"].(string)      // 获取目标语言
+		targetLang, _ := m["language"].(string)   // 获取目标语言
 
 		isCurrent := idStr == messageID // 是否为当前消息
-
-		if isCurrent { // 如果是当前消息
-			currentText = t // 设置当前文本
-
-			// 一次性根据lang设定user和toLang
-			if lang == rs.room.FromLanguage {
-				user = "B:"
-				toLang = rs.room.ToLanguage
-			} else {
-				user = "A:"
-				toLang = rs.room.FromLanguage
-			}
-
-			// 直接更新消息体中的user字段
-			m["user"] = user
-			u = user
-			targetIndex = i // 设置目标索引
-			updatedItem = m // 设置更新项目（现在包含了更新后的user）
+		if isCurrent {
+			u = currentLabel
 		}
 
 		if !onlyOneMessage || !isCurrent { // 如果不是单一消息或不是当前消息
@@ -502,55 +820,50 @@ func (rs *RoomService) HandleReverseTranslation(messageID string, lang string) {
 			}
 		}
 	}
-	if currentText == "" || targetIndex == -1 { // 如果当前文本为空或目标索引为-1
-		log.Printf("[REVERSE] ⚠️ 未找到匹配 ID=%s 的消息", messageID) // 记录警告日志
-		return                                                // 退出函数
-	}
 
 	resultText := strings.Join(contextPieces, "\n") // 连接上下文片段
 
-	const maxRetries = 3   // 最大重试次数
-	var translated string  // 翻译结果
-	var cost float64       // 翻译成本
-	var translateErr error // 翻译错误
+	resp, err := Reverse.Translate(ctx, ReverseRequest{ToLanguage: toLang, Context: resultText, Text: currentText})
+	RecordRoomCost(Ctx, rs.room.ID, resp) // 失败的尝试同样计费
+	if err != nil {
+		log.Printf("[REVERSE] ❌ 所有翻译尝试均失败: %v", err) // 记录失败日志
+		return skipped
+	}
 
-	for i := 0; i < maxRetries; i++ { // 重试循环
-		timeout := 15 + float64(i*5) // 计算超时时间
+	fields := map[string]interface{}{"reverseTranslation": resp.Text}
+	if currentLabel == user { // 未识别发言人时沿用按语言推断的 A:/B: 标识
+		fields["user"] = user
+	}
+	return reverseResult{messageID: messageID, fields: fields}
+}
 
-		translated, cost, translateErr = translateconvtext.Translate( // 调用翻译服务
-			toLang,      // 目标语言
-			resultText,  // 上下文文本
-			currentText, // 当前文本
-			timeout,     // 超时时间
-		)
-		if translateErr == nil { // 如果翻译成功
-			break // 退出循环
+// speakerLabel 返回消息的发言人前缀，优先使用发言人名称，旧消息使用 user 字段
+func speakerLabel(msg map[string]interface{}, fallback string) string {
+	if sp, ok := msg["speaker"].(map[string]interface{}); ok {
+		if name, _ := sp["name"].(string); name != "" {
+			return name + ":"
 		}
-
-		log.Printf("[REVERSE] ⚠️ 翻译尝试 %d 失败: %v", i+1, translateErr, cost) // 记录失败日志
-
-		if strings.Contains(translateErr.Error(), "unexpected end of JSON input") { // 如果是JSON解析错误
-
-			translated, cost, translateErr = translateconvtext.Translate( // 无上下文翻译
-				toLang,      // 目标语言
-				"",          // 空上下文
-				currentText, // 当前文本
-				20,          // 超时时间
-			)
-			if translateErr == nil { // 如果翻译成功
-				break // 退出循环
-			}
+	}
+	if u, _ := msg["user"].(string); u != "" {
+		if strings.HasSuffix(u, ":") {
+			return u
 		}
-
-		time.Sleep(time.Second * time.Duration(i+1)) // 等待后重试
+		return u + ":"
 	}
+	return fallback
+}
 
-	if translateErr != nil { // 如果所有尝试都失败
-		log.Printf("[REVERSE] ❌ 所有翻译尝试均失败: %v", translateErr) // 记录失败日志
-		return                                                // 退出函数
+// applyReverseResult 原子写入反向翻译结果并广播，由有序队列按提交顺序调用
+func (rs *RoomService) applyReverseResult(res reverseResult) {
+	if rs.ctx.Err() != nil { // 房间已关闭
+		return
 	}
 
-	updatedItem["reverseTranslation"] = translated // 设置反向翻译文本
+	updatedItem, err := Messages.Merge(Ctx, rs.room.ID, res.messageID, res.fields) // 原子写入反向翻译结果
+	if err != nil {                                                                // 如果更新失败
+		log.Printf("[REDIS] ❌ 更新失败: %v", err) // 记录错误日志
+		return                                // 退出函数
+	}
 
 	updatedPayload, err := json.Marshal(updatedItem) // 将更新项目转换为JSON
 	if err != nil {                                  // 如果转换失败
@@ -558,69 +871,18 @@ func (rs *RoomService) HandleReverseTranslation(messageID string, lang string) {
 		return                                    // 退出函数
 	}
 
-	if err := RDB.LSet(Ctx, redisKey, int64(targetIndex), updatedPayload).Err(); err != nil { // 更新Redis中的消息
-		log.Printf("[REDIS] ❌ 更新失败: %v", err) // 记录错误日志
-		return                                // 退出函数
-	}
-
-	rs.room.Broadcast <- updatedPayload // 广播更新后的消息
+	rs.broadcast(models.TextFrame(protocol.EventReverseTranslation, updatedPayload)) // 广播更新后的消息
 }
 
-// isSentenceEndFromPosition 从指定位置开始检测文本是否包含完整句子，返回结束位置
-func (rs *RoomService) isSentenceEndFromPosition(text string, startPos int) int {
-	if len(text) == 0 || startPos >= len(text) {
-		return -1
-	}
-
-	// 定义各种语言的句子结束符
-	endMarkers := []string{
-		"。", "！", "？", // 中文
-		".", "!", "?", // 英文
-		"؟", "۔", // 阿拉伯语
-		"।", "॥", // 印地语
-		"。", "！", "？", // 日文
-		".", "!", "?", // 通用
-	}
-
-	// 只检测从startPos开始的部分
-	searchText := text[startPos:]
-
-	// 检查是否以句子结束符结尾
-	trimmed := strings.TrimSpace(text)
-	for _, marker := range endMarkers {
-		if strings.HasSuffix(trimmed, marker) {
-			endPos := len(trimmed)
-			if endPos > startPos {
-				return endPos
-			}
-		}
-	}
-
-	// 检查是否包含句子结束符后跟空格的模式（表示完整句子）
-	for _, marker := range endMarkers {
-		pattern := marker + " "
-		if pos := strings.Index(searchText, pattern); pos != -1 {
-			endPos := startPos + pos + len(marker)
-			return endPos
-		}
-	}
-
-	return -1
-}
-
-// isSentenceEnd 检测文本是否包含完整句子（保持向后兼容）
-func (rs *RoomService) isSentenceEnd(text string) bool {
-	return rs.isSentenceEndFromPosition(text, 0) > -1
-}
-
-// SendUnsupportedLanguageMessage 发送不支持的语言对消息给所有客户端
-func (rs *RoomService) SendUnsupportedLanguageMessage() {
+// SendUnsupportedLanguageMessage 向收听该上游会话的客户端发送不支持的语言对消息
+func (rs *RoomService) SendUnsupportedLanguageMessage(up *models.Upstream) {
+	fromLang, toLang := rs.room.UpstreamLanguages(up.Key)
 	unsupportedMessage := map[string]interface{}{
 		"type":          "language_unsupported",
 		"room_id":       rs.room.ID,
-		"from_language": rs.room.FromLanguage,
-		"to_language":   rs.room.ToLanguage,
-		"message":       fmt.Sprintf("Sorry, translation from %s to %s is not currently supported", rs.room.FromLanguage, rs.room.ToLanguage),
+		"from_language": fromLang,
+		"to_language":   toLang,
+		"message":       fmt.Sprintf("Sorry, translation from %s to %s is not currently supported", fromLang, toLang),
 		"status":        "unsupported",
 	}
 
@@ -630,6 +892,21 @@ func (rs *RoomService) SendUnsupportedLanguageMessage() {
 		return
 	}
 
-	// 广播消息到房间
-	rs.room.Broadcast <- messageBytes
+	payload, err := json.Marshal(protocol.ErrorPayload{
+		Code:    "language_unsupported",
+		Message: unsupportedMessage["message"].(string),
+		Details: map[string]interface{}{
+			"from_language": fromLang,
+			"to_language":   toLang,
+		},
+	})
+	if err != nil {
+		log.Printf("[ROOM %s] ❌ 序列化不支持语言消息失败: %v", rs.room.ID, err)
+		return
+	}
+
+	// 广播消息到房间，旧格式客户端仍收到原有结构
+	frame := models.EventFrame(protocol.EventError, payload, messageBytes)
+	frame.Upstream = up.Key
+	rs.broadcast(frame)
 }
@@ -32,9 +32,17 @@ type TranslationConnectionInfo struct {
 type RoomStatus struct {
 	RoomID               string                     `json:"room_id"`               // 房间ID
 	RoomType             string                     `json:"room_type"`             // 房间类型: "single" | "dual_terminal"
+	OwnerInstance        string                     `json:"owner_instance,omitempty"` // 集群模式下持有上游连接的实例
 	ClientCount          int                        `json:"client_count"`          // 客户端数量
 	TranslationConnection *TranslationConnectionInfo `json:"translation_connection"` // 翻译连接信息
 	ActiveGoroutines     []GoroutineInfo            `json:"goroutines"`            // 活跃协程
+	DroppedFrames        map[string]int64           `json:"dropped_frames"`        // 各客户端被丢弃的音频帧数
+	SlowDisconnects      int                        `json:"slow_disconnects"`      // 因消费过慢被断开的客户端数
+	ReverseDropped       int                        `json:"reverse_dropped"`       // 因工作池队列已满被跳过的反向翻译数
+	ReverseRequests      int                        `json:"reverse_requests"`      // 反向翻译调用次数
+	ReverseCacheHits     int                        `json:"reverse_cache_hits"`    // 反向翻译缓存命中次数
+	ReverseCost          float64                    `json:"reverse_cost"`          // 反向翻译累计费用
+	SuppressedSeconds    float64                    `json:"suppressed_seconds"`    // 被静音抑制、未送往上游的音频秒数
 	CreatedAt            time.Time                  `json:"created_at"`            // 房间创建时间
 	LastActivity         time.Time                  `json:"last_activity"`         // 最后活动时间
 }
@@ -88,6 +96,7 @@ func (tm *TranslationMonitor) RegisterRoom(roomID, roomType string, clientCount
 			AudioPacketCount: 0,
 		},
 		ActiveGoroutines: []GoroutineInfo{},
+		DroppedFrames:    make(map[string]int64),
 		CreatedAt:        now,
 		LastActivity:     now,
 	}
@@ -128,6 +137,17 @@ func (tm *TranslationMonitor) UpdateTranslationConnection(roomID string, connect
 	}
 }
 
+// UpdateOwnerInstance 更新集群模式下持有上游连接的实例
+func (tm *TranslationMonitor) UpdateOwnerInstance(roomID, instanceID string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if room, exists := tm.rooms[roomID]; exists {
+		room.OwnerInstance = instanceID
+		room.LastActivity = time.Now()
+	}
+}
+
 // RecordReconnect 记录重连
 func (tm *TranslationMonitor) RecordReconnect(roomID string) {
 	tm.mu.Lock()
@@ -156,6 +176,61 @@ func (tm *TranslationMonitor) RecordMessage(roomID string, isAudio bool) {
 	}
 }
 
+// RecordDroppedFrame 记录客户端被丢弃的音频帧
+func (tm *TranslationMonitor) RecordDroppedFrame(roomID, clientID string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if room, exists := tm.rooms[roomID]; exists {
+		room.DroppedFrames[clientID]++
+	}
+}
+
+// RecordSlowConsumerDisconnect 记录因消费过慢被断开的客户端
+func (tm *TranslationMonitor) RecordSlowConsumerDisconnect(roomID, clientID string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if room, exists := tm.rooms[roomID]; exists {
+		room.SlowDisconnects++
+		room.LastActivity = time.Now()
+	}
+}
+
+// RecordReverseDropped 记录因工作池队列已满被跳过的反向翻译
+func (tm *TranslationMonitor) RecordReverseDropped(roomID string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if room, exists := tm.rooms[roomID]; exists {
+		room.ReverseDropped++
+	}
+}
+
+// RecordReverseCost 记录反向翻译调用及费用
+func (tm *TranslationMonitor) RecordReverseCost(roomID string, cost float64, cached bool) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if room, exists := tm.rooms[roomID]; exists {
+		room.ReverseRequests++
+		room.ReverseCost += cost
+		if cached {
+			room.ReverseCacheHits++
+		}
+	}
+}
+
+// RecordSuppressedAudio 记录被静音抑制的音频时长
+func (tm *TranslationMonitor) RecordSuppressedAudio(roomID string, d time.Duration) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+
+	if room, exists := tm.rooms[roomID]; exists {
+		room.SuppressedSeconds += d.Seconds()
+	}
+}
+
 // AddGoroutine 添加协程
 func (tm *TranslationMonitor) AddGoroutine(roomID, goroutineType string) {
 	tm.mu.Lock()
@@ -237,9 +312,10 @@ func (tm *TranslationMonitor) GetSystemStatus() *SystemMonitorResponse {
 
 		// 复制房间状态（避免并发修改）
 		roomCopy := &RoomStatus{
-			RoomID:      room.RoomID,
-			RoomType:    room.RoomType,
-			ClientCount: room.ClientCount,
+			RoomID:        room.RoomID,
+			RoomType:      room.RoomType,
+			OwnerInstance: room.OwnerInstance,
+			ClientCount:   room.ClientCount,
 			TranslationConnection: &TranslationConnectionInfo{
 				Connected:          room.TranslationConnection.Connected,
 				LastMessageTime:    room.TranslationConnection.LastMessageTime,
@@ -252,6 +328,13 @@ func (tm *TranslationMonitor) GetSystemStatus() *SystemMonitorResponse {
 				AudioPacketCount:   room.TranslationConnection.AudioPacketCount,
 			},
 			ActiveGoroutines: make([]GoroutineInfo, len(room.ActiveGoroutines)),
+			DroppedFrames:    copyDroppedFrames(room.DroppedFrames),
+			SlowDisconnects:  room.SlowDisconnects,
+			ReverseDropped:   room.ReverseDropped,
+			ReverseRequests:  room.ReverseRequests,
+			ReverseCacheHits: room.ReverseCacheHits,
+			ReverseCost:      room.ReverseCost,
+			SuppressedSeconds: room.SuppressedSeconds,
 			CreatedAt:        room.CreatedAt,
 			LastActivity:     room.LastActivity,
 		}
@@ -274,11 +357,21 @@ func (tm *TranslationMonitor) GetRoomStatus(roomID string) *RoomStatus {
 		*roomCopy.TranslationConnection = *room.TranslationConnection
 		roomCopy.ActiveGoroutines = make([]GoroutineInfo, len(room.ActiveGoroutines))
 		copy(roomCopy.ActiveGoroutines, room.ActiveGoroutines)
+		roomCopy.DroppedFrames = copyDroppedFrames(room.DroppedFrames)
 		return &roomCopy
 	}
 	return nil
 }
 
+// copyDroppedFrames 复制丢帧统计
+func copyDroppedFrames(src map[string]int64) map[string]int64 {
+	dst := make(map[string]int64, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
 // ForceCloseRoom 强制关闭房间的翻译连接
 func (tm *TranslationMonitor) ForceCloseRoom(roomID string) bool {
 	tm.mu.Lock()
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"go-backEnd/internal/services"
+)
+
+// AdminMiddleware 管理接口认证：Authorization: Bearer <ADMIN_TOKEN>，或登录会话属于管理员用户
+type AdminMiddleware struct {
+	token   string
+	userIDs map[string]bool
+}
+
+// NewAdminMiddleware 创建管理接口认证中间件，token 和 userIDs 都为空时管理接口不可用
+func NewAdminMiddleware(token string, userIDs []string) *AdminMiddleware {
+	am := &AdminMiddleware{token: token, userIDs: make(map[string]bool)}
+	for _, id := range userIDs {
+		am.userIDs[id] = true
+	}
+	return am
+}
+
+// isAdmin 检查管理令牌或会话所属用户
+func (am *AdminMiddleware) isAdmin(r *http.Request) bool {
+	if auth := r.Header.Get("Authorization"); am.token != "" && strings.HasPrefix(auth, "Bearer ") {
+		given := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+		if subtle.ConstantTimeCompare([]byte(given), []byte(am.token)) == 1 {
+			return true
+		}
+	}
+	cookie, err := r.Cookie("auth_session")
+	if err != nil {
+		return false
+	}
+	session, err := services.Auth.ValidateSession(cookie.Value)
+	return err == nil && session.UserID != "" && am.userIDs[session.UserID]
+}
+
+// RequireAdmin 管理接口认证
+func (am *AdminMiddleware) RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if !am.isAdmin(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(ErrorResponse{
+				Success: false,
+				Message: "需要管理员权限",
+				Code:    403,
+			})
+			return
+		}
+		next(w, r)
+	}
+}
@@ -3,8 +3,6 @@ package utils
 import (
 	"encoding/json"
 	"net/http"
-
-	"go-backEnd/internal/services"
 )
 
 type AuthMiddleware struct {
@@ -95,8 +93,8 @@ func (am *AuthMiddleware) RequireAuth(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// 验证session
-		_, err = services.Auth.ValidateSession(cookie.Value)
+		// 验证session并顺延有效期
+		_, err = RefreshAuthSession(w, r, cookie.Value)
 		if err != nil {
 			errorResponse := ErrorResponse{
 				Success: false,
@@ -121,7 +119,7 @@ func ValidateWebSocketAuth(r *http.Request) error {
 		return err
 	}
 
-	// 验证session
-	_, err = services.Auth.ValidateSession(cookie.Value)
+	// 验证session并顺延有效期，握手响应无法设置Cookie
+	_, err = RefreshAuthSession(nil, r, cookie.Value)
 	return err
 }
\ No newline at end of file
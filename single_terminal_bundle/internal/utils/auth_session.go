@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-backEnd/internal/services"
+)
+
+// AuthCookie 创建认证Cookie，maxAge 为负数时删除Cookie
+func AuthCookie(sessionID string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     "auth_session",
+		Value:    sessionID,
+		Path:     "/",
+		Domain:   "session.glot.world", // 只针对当前域名
+		HttpOnly: true,
+		Secure:   true, // SameSite=None时必须为true
+		SameSite: http.SameSiteNoneMode,
+		MaxAge:   maxAge,
+	}
+}
+
+// ClientIP 返回客户端IP，经反向代理时取 X-Forwarded-For 的第一个地址
+func ClientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// SessionMeta 从请求中取得会话记录的客户端信息
+func SessionMeta(r *http.Request) services.SessionMeta {
+	return services.SessionMeta{IP: ClientIP(r), UserAgent: r.UserAgent()}
+}
+
+// RefreshAuthSession 验证会话并顺延有效期，顺延时同步延长Cookie；w 为 nil 时只刷新服务端会话
+func RefreshAuthSession(w http.ResponseWriter, r *http.Request, sessionID string) (*services.SessionData, error) {
+	session, refreshed, err := services.Auth.RefreshSession(sessionID, SessionMeta(r))
+	if err != nil {
+		return nil, err
+	}
+	if refreshed && w != nil {
+		http.SetCookie(w, AuthCookie(sessionID, int(time.Until(session.ExpiresAt).Seconds())))
+	}
+	return session, nil
+}
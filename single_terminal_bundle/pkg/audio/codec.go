@@ -0,0 +1,73 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 客户端音频编码
+const (
+	CodecPCM  = "pcm"  // 原始 PCM（默认）
+	CodecOpus = "opus" // Opus，需使用 -tags opus 构建
+)
+
+// OpusFrame Opus 编码的帧时长
+const OpusFrame = 20 * time.Millisecond
+
+// ErrOpusUnavailable 构建时未启用 Opus
+var ErrOpusUnavailable = errors.New("未启用 Opus 支持，需使用 -tags opus 构建")
+
+// opusRates Opus 支持的采样率
+var opusRates = map[int]bool{8000: true, 12000: true, 16000: true, 24000: true, 48000: true}
+
+// PacketEncoder 将 PCM 编码为压缩包，内部缓冲不足一帧的数据
+type PacketEncoder interface {
+	Encode(pcm []byte) ([][]byte, error)
+}
+
+// PacketDecoder 将单个压缩包解码为 PCM
+type PacketDecoder interface {
+	Decode(packet []byte) ([]byte, error)
+}
+
+// ValidateCodec 检查编码与 PCM 格式是否匹配
+func ValidateCodec(codec string, f Format) error {
+	switch codec {
+	case "", CodecPCM:
+		return nil
+	case CodecOpus:
+		if !OpusAvailable {
+			return ErrOpusUnavailable
+		}
+		if !opusRates[f.SampleRate] {
+			return fmt.Errorf("Opus 不支持采样率 %d", f.SampleRate)
+		}
+		if f.Encoding != EncodingS16LE {
+			return fmt.Errorf("Opus 只支持 %s 编码的 PCM", EncodingS16LE)
+		}
+		return nil
+	default:
+		return fmt.Errorf("不支持的音频编码: %s", codec)
+	}
+}
+
+// PackPackets 将多个压缩包打包为一条消息：每个包前加 2 字节大端长度
+func PackPackets(packets [][]byte) []byte {
+	size := 0
+	for _, p := range packets {
+		size += 2 + len(p)
+	}
+	out := make([]byte, 0, size)
+	for _, p := range packets {
+		out = binary.BigEndian.AppendUint16(out, uint16(len(p)))
+		out = append(out, p...)
+	}
+	return out
+}
+
+// opusFrameBytes Opus 一帧对应的 16 位 PCM 字节数
+func opusFrameBytes(f Format) int {
+	return durationBytes(f.SampleRate, OpusFrame) * f.Channels
+}
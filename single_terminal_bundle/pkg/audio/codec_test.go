@@ -0,0 +1,49 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+var opusTestFormat = Format{SampleRate: 16000, Channels: 1, Encoding: EncodingS16LE}
+
+// sinePCM 生成 d 毫秒 440Hz 的 16 位单声道正弦波
+func sinePCM(f Format, ms int) []byte {
+	n := f.SampleRate * ms / 1000
+	out := make([]byte, n*2)
+	for i := 0; i < n; i++ {
+		v := int16(8000 * math.Sin(2*math.Pi*440*float64(i)/float64(f.SampleRate)))
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(v))
+	}
+	return out
+}
+
+func TestValidateCodec(t *testing.T) {
+	if err := ValidateCodec("", opusTestFormat); err != nil {
+		t.Errorf("默认编码: %v", err)
+	}
+	if err := ValidateCodec(CodecPCM, opusTestFormat); err != nil {
+		t.Errorf("PCM: %v", err)
+	}
+	if err := ValidateCodec("mp3", opusTestFormat); err == nil {
+		t.Error("不支持的编码应返回错误")
+	}
+	if OpusAvailable {
+		if err := ValidateCodec(CodecOpus, Format{SampleRate: 44100, Channels: 1, Encoding: EncodingS16LE}); err == nil {
+			t.Error("Opus 不支持 44100Hz，应返回错误")
+		}
+		if err := ValidateCodec(CodecOpus, Format{SampleRate: 16000, Channels: 1, Encoding: EncodingF32LE}); err == nil {
+			t.Error("Opus 只支持 s16le，应返回错误")
+		}
+	}
+}
+
+func TestPackPackets(t *testing.T) {
+	got := PackPackets([][]byte{{1, 2, 3}, {}, {4}})
+	want := []byte{0, 3, 1, 2, 3, 0, 0, 0, 1, 4}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("PackPackets = %v, 期望 %v", got, want)
+	}
+}
@@ -0,0 +1,113 @@
+package audio
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Encoding PCM 采样编码
+type Encoding string
+
+const (
+	EncodingS16LE Encoding = "s16le" // 16 位有符号小端整数
+	EncodingF32LE Encoding = "f32le" // 32 位小端浮点
+)
+
+// Format 音频格式
+type Format struct {
+	SampleRate int      `json:"sample_rate"`
+	Channels   int      `json:"channels"`
+	Encoding   Encoding `json:"encoding"`
+}
+
+// supportedRates 支持的采样率
+var supportedRates = map[int]bool{
+	8000: true, 12000: true, 16000: true, 22050: true, 24000: true, 32000: true, 44100: true, 48000: true,
+}
+
+var (
+	// UpstreamInput 上游翻译服务接收的音频格式，客户端音频统一转换为该格式后再做检测、混音和录音
+	UpstreamInput = Format{SampleRate: 16000, Channels: Channels, Encoding: EncodingS16LE}
+	// UpstreamOutput 上游翻译服务返回的音频格式
+	UpstreamOutput = Format{SampleRate: InSampleRate, Channels: Channels, Encoding: EncodingS16LE}
+	// DefaultClientOutput 未声明输出格式的客户端收到的音频格式
+	DefaultClientOutput = Format{SampleRate: OutSampleRate, Channels: Channels, Encoding: EncodingS16LE}
+)
+
+// String 返回格式描述，如 "16000Hz/1ch/s16le"
+func (f Format) String() string {
+	return fmt.Sprintf("%dHz/%dch/%s", f.SampleRate, f.Channels, f.Encoding)
+}
+
+// Validate 检查格式是否受支持
+func (f Format) Validate() error {
+	if !supportedRates[f.SampleRate] {
+		return fmt.Errorf("不支持的采样率: %d", f.SampleRate)
+	}
+	if f.Channels != 1 && f.Channels != 2 {
+		return fmt.Errorf("不支持的声道数: %d", f.Channels)
+	}
+	switch f.Encoding {
+	case EncodingS16LE, EncodingF32LE:
+	default:
+		return fmt.Errorf("不支持的编码: %s", f.Encoding)
+	}
+	return nil
+}
+
+// bytesPerSample 单个采样的字节数
+func (f Format) bytesPerSample() int {
+	if f.Encoding == EncodingF32LE {
+		return 4
+	}
+	return 2
+}
+
+// BlockAlign 返回一个采样帧（所有声道）的字节数
+func (f Format) BlockAlign() int {
+	return f.Channels * f.bytesPerSample()
+}
+
+// duration 返回 n 字节音频的时长
+func (f Format) duration(n int64) time.Duration {
+	bytesPerSecond := int64(f.SampleRate * f.BlockAlign())
+	if bytesPerSecond == 0 {
+		return 0
+	}
+	return time.Duration(n * int64(time.Second) / bytesPerSecond)
+}
+
+// BytesAt 返回时长 d 对应的字节数，按采样帧对齐
+func (f Format) BytesAt(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(d) * int64(f.SampleRate) / int64(time.Second) * int64(f.BlockAlign())
+}
+
+// ParseFormat 解析客户端声明的格式，空字段使用 def 中的值
+func ParseFormat(sampleRate, channels, encoding string, def Format) (Format, error) {
+	f := def
+	if sampleRate != "" {
+		rate, err := strconv.Atoi(sampleRate)
+		if err != nil {
+			return Format{}, fmt.Errorf("采样率格式错误: %s", sampleRate)
+		}
+		f.SampleRate = rate
+	}
+	if channels != "" {
+		ch, err := strconv.Atoi(channels)
+		if err != nil {
+			return Format{}, fmt.Errorf("声道数格式错误: %s", channels)
+		}
+		f.Channels = ch
+	}
+	if encoding != "" {
+		f.Encoding = Encoding(encoding)
+	}
+	if err := f.Validate(); err != nil {
+		return Format{}, err
+	}
+	return f, nil
+}
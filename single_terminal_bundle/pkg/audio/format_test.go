@@ -0,0 +1,46 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFormat(t *testing.T) {
+	def := DefaultClientOutput
+	tests := []struct {
+		rate, channels, encoding string
+		want                     Format
+		ok                       bool
+	}{
+		{"", "", "", def, true},
+		{"16000", "2", "f32le", Format{SampleRate: 16000, Channels: 2, Encoding: EncodingF32LE}, true},
+		{"44100", "", "", Format{SampleRate: 44100, Channels: def.Channels, Encoding: def.Encoding}, true},
+		{"abc", "", "", Format{}, false},
+		{"11025", "", "", Format{}, false},
+		{"0", "", "", Format{}, false},
+		{"", "x", "", Format{}, false},
+		{"", "0", "", Format{}, false},
+		{"", "3", "", Format{}, false},
+		{"", "", "mp3", Format{}, false},
+		{"", "", "S16LE", Format{}, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.rate, tt.channels, tt.encoding, def)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("ParseFormat(%q, %q, %q) = %v, %v; 期望 %v, 成功=%v", tt.rate, tt.channels, tt.encoding, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	f := Format{SampleRate: 48000, Channels: 2, Encoding: EncodingF32LE}
+	if got := f.BlockAlign(); got != 8 {
+		t.Fatalf("BlockAlign = %d, 期望 8", got)
+	}
+	if got := f.BytesAt(20 * time.Millisecond); got != 960*8 {
+		t.Fatalf("BytesAt(20ms) = %d, 期望 %d", got, 960*8)
+	}
+	if got := f.duration(960 * 8); got != 20*time.Millisecond {
+		t.Fatalf("duration = %s, 期望 20ms", got)
+	}
+}
@@ -0,0 +1,20 @@
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// RMS 计算 16 位小端 PCM 的均方根能量，归一化到 [0, 1]
+func RMS(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		s := float64(int16(binary.LittleEndian.Uint16(pcm[i*2:]))) / 32768
+		sum += s * s
+	}
+	return math.Sqrt(sum / float64(n))
+}
@@ -0,0 +1,262 @@
+package audio
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// MixerConfig 混音器配置，输入为 16 位小端单声道 PCM
+type MixerConfig struct {
+	SampleRate int           // 输入采样率
+	Frame      time.Duration // 每次输出的帧时长
+	MaxLatency time.Duration // 等待慢速流的最长缓冲时长，超过后不再等待直接输出
+	MaxBuffer  time.Duration // 单个流的最大缓冲，超过时丢弃最旧的数据
+}
+
+// DefaultMixerConfig 返回默认配置
+func DefaultMixerConfig() MixerConfig {
+	return MixerConfig{
+		SampleRate: 16000,
+		Frame:      20 * time.Millisecond,
+		MaxLatency: 120 * time.Millisecond,
+		MaxBuffer:  2 * time.Second,
+	}
+}
+
+// mixStream 单个来源的缓冲，start 为 buf 第一个采样的采集时间
+type mixStream struct {
+	buf   []byte
+	start time.Time
+}
+
+// end 缓冲中最后一个采样之后的时间
+func (s *mixStream) end(m *Mixer) time.Time {
+	return s.start.Add(m.bytesDuration(len(s.buf)))
+}
+
+// Mixer 将多个来源的 PCM 按采集时间对齐后逐帧叠加为一路
+//
+// 每个来源的数据带有采集时间，混音器维护一条输出时间线，Next 每次输出 [pos, pos+Frame) 这段时间内
+// 所有来源的叠加：晚于时间线到达的数据按其采集时间丢弃过期部分，提前到达的数据留到对应的帧再输出，
+// 不同实例转发造成的到达先后差异不会导致错位。某个来源尚未覆盖当前帧时在 MaxLatency 内等待，
+// 超时后以静音补齐。叠加溢出时按帧峰值整体缩放。
+type Mixer struct {
+	mu         sync.Mutex
+	cfg        MixerConfig
+	frameBytes int
+	maxBuffer  int       // 以字节计的缓冲上限
+	pos        time.Time // 下一输出帧的起始时间，零值表示尚未开始
+	streams    map[string]*mixStream
+}
+
+// NewMixer 创建混音器
+func NewMixer(cfg MixerConfig) *Mixer {
+	def := DefaultMixerConfig()
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = def.SampleRate
+	}
+	if cfg.Frame <= 0 {
+		cfg.Frame = def.Frame
+	}
+	if cfg.MaxLatency <= 0 {
+		cfg.MaxLatency = def.MaxLatency
+	}
+	if cfg.MaxBuffer <= 0 {
+		cfg.MaxBuffer = def.MaxBuffer
+	}
+	return &Mixer{
+		cfg:        cfg,
+		frameBytes: durationBytes(cfg.SampleRate, cfg.Frame),
+		maxBuffer:  durationBytes(cfg.SampleRate, cfg.MaxBuffer),
+		streams:    make(map[string]*mixStream),
+	}
+}
+
+// durationBytes 时长对应的 16 位单声道 PCM 字节数
+func durationBytes(sampleRate int, d time.Duration) int {
+	samples := int(int64(sampleRate) * int64(d) / int64(time.Second))
+	if samples < 1 {
+		samples = 1
+	}
+	return samples * 2
+}
+
+// offsetBytes 时间偏移对应的字节数，按采样取整，非正数时为 0
+func (m *Mixer) offsetBytes(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(int64(m.cfg.SampleRate)*int64(d)/int64(time.Second)) * 2
+}
+
+// bytesDuration 字节数对应的时长
+func (m *Mixer) bytesDuration(n int) time.Duration {
+	return time.Duration(int64(n/2) * int64(time.Second) / int64(m.cfg.SampleRate))
+}
+
+// FrameDuration 返回输出帧时长
+func (m *Mixer) FrameDuration() time.Duration {
+	return m.cfg.Frame
+}
+
+// Write 追加来源的 PCM，captured 为这段音频最后一个采样的采集时间
+//
+// 与缓冲末尾的间隔超过 MaxLatency 时视为发言中断，以静音补齐间隔；小于该值的偏差视为网络抖动，
+// 直接接在缓冲之后，保持同一来源的连续性。
+func (m *Mixer) Write(source string, pcm []byte, captured time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	start := captured.Add(-m.bytesDuration(len(pcm)))
+	s, ok := m.streams[source]
+	if !ok {
+		s = &mixStream{}
+		m.streams[source] = s
+	}
+	switch {
+	case len(s.buf) == 0:
+		s.start = start
+	case start.Sub(s.end(m)) > m.cfg.MaxLatency:
+		gap := m.offsetBytes(start.Sub(s.end(m)))
+		if gap > m.maxBuffer {
+			gap = m.maxBuffer
+		}
+		s.buf = append(s.buf, make([]byte, gap)...)
+	}
+	s.buf = append(s.buf, pcm...)
+	if over := len(s.buf) - m.maxBuffer; over > 0 { // 消费跟不上时丢弃最旧的数据
+		over += over % 2
+		s.buf = append(s.buf[:0], s.buf[over:]...)
+		s.start = s.start.Add(m.bytesDuration(over))
+	}
+}
+
+// Remove 移除来源，未输出的数据一并丢弃
+func (m *Mixer) Remove(source string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.streams, source)
+}
+
+// Reset 清空所有来源并重置时间线
+func (m *Mixer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.streams = make(map[string]*mixStream)
+	m.pos = time.Time{}
+}
+
+// Next 输出下一帧混音结果，数据不足时返回 false
+func (m *Mixer) Next() ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var first time.Time // 所有来源中最早的数据
+	for _, s := range m.streams {
+		if len(s.buf) > 0 && (first.IsZero() || s.start.Before(first)) {
+			first = s.start
+		}
+	}
+	if first.IsZero() {
+		return nil, false
+	}
+	frameEnd := m.pos.Add(m.cfg.Frame)
+	if m.pos.IsZero() || !first.Before(frameEnd) { // 首帧或所有来源都停顿过后，时间线跳到最早的数据
+		m.pos = first
+		frameEnd = m.pos.Add(m.cfg.Frame)
+	}
+
+	ready, waiting := 0, 0
+	var latest time.Time // 所有来源中最新的数据
+	for _, s := range m.streams {
+		if len(s.buf) == 0 {
+			continue
+		}
+		end := s.end(m)
+		switch {
+		case !end.Before(frameEnd):
+			ready++
+		case end.After(m.pos):
+			waiting++
+		}
+		if end.After(latest) {
+			latest = end
+		}
+	}
+	if ready == 0 {
+		return nil, false
+	}
+	if waiting > 0 && latest.Sub(frameEnd) < m.cfg.MaxLatency { // 等待其他来源补齐当前帧
+		return nil, false
+	}
+
+	samples := m.frameBytes / 2
+	sum := make([]int32, samples)
+	for _, s := range m.streams {
+		if len(s.buf) == 0 {
+			continue
+		}
+		if stale := m.offsetBytes(m.pos.Sub(s.start)); stale > 0 { // 早于时间线的数据已错过输出
+			if stale > len(s.buf) {
+				stale = len(s.buf)
+			}
+			s.buf = s.buf[stale:]
+			s.start = s.start.Add(m.bytesDuration(stale))
+		}
+		offset := m.offsetBytes(s.start.Sub(m.pos)) // 晚于帧起点开始的来源前面补静音
+		if offset >= m.frameBytes {
+			continue
+		}
+		n := len(s.buf)
+		if n > m.frameBytes-offset {
+			n = m.frameBytes - offset
+		}
+		n -= n % 2
+		for i := 0; i < n/2; i++ {
+			sum[offset/2+i] += int32(int16(binary.LittleEndian.Uint16(s.buf[i*2:])))
+		}
+		s.buf = s.buf[n:]
+		s.start = s.start.Add(m.bytesDuration(n))
+		if len(s.buf) == 0 {
+			s.buf = nil
+		}
+	}
+	m.pos = frameEnd
+	return normalize(sum), true
+}
+
+// normalize 将叠加结果转换为 16 位 PCM，峰值溢出时整体缩放
+func normalize(sum []int32) []byte {
+	var peak int32
+	for _, v := range sum {
+		if v < 0 {
+			v = -v
+		}
+		if v > peak {
+			peak = v
+		}
+	}
+	out := make([]byte, len(sum)*2)
+	for i, v := range sum {
+		if peak > 32767 {
+			v = int32(int64(v) * 32767 / int64(peak))
+		}
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(int16(v)))
+	}
+	return out
+}
+
+// InterleaveStereo 将两路等长的 16 位单声道 PCM 交错为立体声，left 为左声道
+func InterleaveStereo(left, right []byte) []byte {
+	n := len(left)
+	if len(right) < n {
+		n = len(right)
+	}
+	n -= n % 2
+	out := make([]byte, n*2)
+	for i := 0; i < n; i += 2 {
+		copy(out[i*2:], left[i:i+2])
+		copy(out[i*2+2:], right[i:i+2])
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package audio
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// 1kHz 采样、10ms 一帧，每帧 10 个采样
+var mixerTestConfig = MixerConfig{SampleRate: 1000, Frame: 10 * time.Millisecond, MaxLatency: 30 * time.Millisecond, MaxBuffer: time.Second}
+
+// constPCM 生成 ms 毫秒取值恒为 v 的 PCM
+func constPCM(v int16, ms int) []byte {
+	out := make([]byte, ms*2)
+	for i := 0; i < ms; i++ {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(v))
+	}
+	return out
+}
+
+// nextValue 取出下一帧并返回其采样值，要求帧内取值恒定
+func nextValue(t *testing.T, m *Mixer) int16 {
+	t.Helper()
+	frame, ok := m.Next()
+	if !ok {
+		t.Fatal("期望输出一帧")
+	}
+	first := int16(binary.LittleEndian.Uint16(frame))
+	for i := 2; i < len(frame); i += 2 {
+		if v := int16(binary.LittleEndian.Uint16(frame[i:])); v != first {
+			t.Fatalf("帧内采样不一致: %d 与 %d", first, v)
+		}
+	}
+	return first
+}
+
+func TestMixerAlignsByCaptureTime(t *testing.T) {
+	m := NewMixer(mixerTestConfig)
+	t0 := time.Now()
+
+	// B 先到达但采集时间晚 20ms，A 经转发后到达
+	m.Write("b", constPCM(100, 20), t0.Add(40*time.Millisecond))
+	m.Write("a", constPCM(10, 40), t0.Add(40*time.Millisecond))
+
+	for i, want := range []int16{10, 10, 110, 110} {
+		if got := nextValue(t, m); got != want {
+			t.Fatalf("第 %d 帧 = %d, 期望 %d", i, got, want)
+		}
+	}
+	if _, ok := m.Next(); ok {
+		t.Fatal("数据已耗尽，不应继续输出")
+	}
+}
+
+func TestMixerDropsStaleAudio(t *testing.T) {
+	m := NewMixer(mixerTestConfig)
+	t0 := time.Now()
+
+	m.Write("a", constPCM(10, 20), t0.Add(20*time.Millisecond))
+	nextValue(t, m)
+	nextValue(t, m)
+
+	// 采集于已输出时段的数据迟到，只输出时间线之后的部分
+	m.Write("b", constPCM(100, 20), t0.Add(30*time.Millisecond))
+	m.Write("a", constPCM(10, 10), t0.Add(30*time.Millisecond))
+	if got := nextValue(t, m); got != 110 {
+		t.Fatalf("迟到的数据 = %d, 期望 110", got)
+	}
+}
+
+func TestMixerWaitsForSlowSource(t *testing.T) {
+	m := NewMixer(mixerTestConfig)
+	t0 := time.Now()
+
+	m.Write("a", constPCM(10, 10), t0.Add(10*time.Millisecond))
+	m.Write("b", constPCM(100, 5), t0.Add(5*time.Millisecond))
+	if _, ok := m.Next(); ok {
+		t.Fatal("B 未覆盖当前帧时应等待")
+	}
+
+	m.Write("a", constPCM(10, 30), t0.Add(40*time.Millisecond)) // A 领先超过 MaxLatency 后不再等待
+	frame, ok := m.Next()
+	if !ok {
+		t.Fatal("超过等待上限后应输出")
+	}
+	if v := int16(binary.LittleEndian.Uint16(frame)); v != 110 {
+		t.Fatalf("首个采样 = %d, 期望 110", v)
+	}
+	if v := int16(binary.LittleEndian.Uint16(frame[len(frame)-2:])); v != 10 {
+		t.Fatalf("末尾采样 = %d, 期望 B 缺失部分只有 A", v)
+	}
+}
@@ -0,0 +1,90 @@
+//go:build opus
+
+// 基于 libopus 的 Opus 编解码，需要 cgo 以及系统安装的 libopus/libopusfile：
+//
+//	go get gopkg.in/hraban/opus.v2 && go build -tags opus ./...
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"gopkg.in/hraban/opus.v2"
+)
+
+// OpusAvailable 当前构建是否支持 Opus
+const OpusAvailable = true
+
+// opusEncoder 基于 libopus 的编码器，按 20ms 切帧
+type opusEncoder struct {
+	enc        *opus.Encoder
+	channels   int
+	frameBytes int
+	pending    []byte
+}
+
+// NewOpusEncoder 创建 Opus 编码器，输入为 f 格式的 16 位 PCM
+func NewOpusEncoder(f Format) (PacketEncoder, error) {
+	if err := ValidateCodec(CodecOpus, f); err != nil {
+		return nil, err
+	}
+	enc, err := opus.NewEncoder(f.SampleRate, f.Channels, opus.AppVoIP)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Opus 编码器失败: %w", err)
+	}
+	return &opusEncoder{enc: enc, channels: f.Channels, frameBytes: opusFrameBytes(f)}, nil
+}
+
+// Encode 实现 PacketEncoder
+func (e *opusEncoder) Encode(pcm []byte) ([][]byte, error) {
+	e.pending = append(e.pending, pcm...)
+	var packets [][]byte
+	buf := make([]byte, 4000)
+	for len(e.pending) >= e.frameBytes {
+		samples := make([]int16, e.frameBytes/2)
+		for i := range samples {
+			samples[i] = int16(binary.LittleEndian.Uint16(e.pending[i*2:]))
+		}
+		n, err := e.enc.Encode(samples, buf)
+		if err != nil {
+			return packets, fmt.Errorf("Opus 编码失败: %w", err)
+		}
+		packets = append(packets, append([]byte(nil), buf[:n]...))
+		e.pending = e.pending[e.frameBytes:]
+	}
+	e.pending = append([]byte(nil), e.pending...) // 释放已编码部分
+	return packets, nil
+}
+
+// opusDecoder 基于 libopus 的解码器
+type opusDecoder struct {
+	dec      *opus.Decoder
+	channels int
+	buf      []int16
+}
+
+// NewOpusDecoder 创建 Opus 解码器，输出为 f 格式的 16 位 PCM
+func NewOpusDecoder(f Format) (PacketDecoder, error) {
+	if err := ValidateCodec(CodecOpus, f); err != nil {
+		return nil, err
+	}
+	dec, err := opus.NewDecoder(f.SampleRate, f.Channels)
+	if err != nil {
+		return nil, fmt.Errorf("创建 Opus 解码器失败: %w", err)
+	}
+	// 单个包最长 120ms
+	return &opusDecoder{dec: dec, channels: f.Channels, buf: make([]int16, f.SampleRate*120/1000*f.Channels)}, nil
+}
+
+// Decode 实现 PacketDecoder
+func (d *opusDecoder) Decode(packet []byte) ([]byte, error) {
+	n, err := d.dec.Decode(packet, d.buf)
+	if err != nil {
+		return nil, fmt.Errorf("Opus 解码失败: %w", err)
+	}
+	out := make([]byte, n*d.channels*2)
+	for i := 0; i < n*d.channels; i++ {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(d.buf[i]))
+	}
+	return out, nil
+}
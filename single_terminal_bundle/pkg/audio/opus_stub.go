@@ -0,0 +1,16 @@
+//go:build !opus
+
+package audio
+
+// OpusAvailable 当前构建是否支持 Opus
+const OpusAvailable = false
+
+// NewOpusEncoder 未启用 Opus 时始终返回 ErrOpusUnavailable
+func NewOpusEncoder(f Format) (PacketEncoder, error) {
+	return nil, ErrOpusUnavailable
+}
+
+// NewOpusDecoder 未启用 Opus 时始终返回 ErrOpusUnavailable
+func NewOpusDecoder(f Format) (PacketDecoder, error) {
+	return nil, ErrOpusUnavailable
+}
@@ -0,0 +1,20 @@
+//go:build !opus
+
+package audio
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOpusUnavailable(t *testing.T) {
+	if _, err := NewOpusEncoder(opusTestFormat); !errors.Is(err, ErrOpusUnavailable) {
+		t.Errorf("NewOpusEncoder 错误 = %v, 期望 ErrOpusUnavailable", err)
+	}
+	if _, err := NewOpusDecoder(opusTestFormat); !errors.Is(err, ErrOpusUnavailable) {
+		t.Errorf("NewOpusDecoder 错误 = %v, 期望 ErrOpusUnavailable", err)
+	}
+	if err := ValidateCodec(CodecOpus, opusTestFormat); !errors.Is(err, ErrOpusUnavailable) {
+		t.Errorf("ValidateCodec 错误 = %v, 期望 ErrOpusUnavailable", err)
+	}
+}
@@ -0,0 +1,70 @@
+//go:build opus
+
+package audio
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// rms 计算 16 位 PCM 的均方根
+func rms(pcm []byte) float64 {
+	var sum float64
+	n := len(pcm) / 2
+	for i := 0; i < n; i++ {
+		v := float64(int16(binary.LittleEndian.Uint16(pcm[i*2:])))
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+func TestOpusRoundTrip(t *testing.T) {
+	for _, f := range []Format{
+		opusTestFormat,
+		{SampleRate: 48000, Channels: 2, Encoding: EncodingS16LE},
+	} {
+		enc, err := NewOpusEncoder(f)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		dec, err := NewOpusDecoder(f)
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+
+		frame := opusFrameBytes(f)
+		pcm := sinePCM(Format{SampleRate: f.SampleRate * f.Channels}, 200) // 交错的双声道按单声道生成即可
+
+		// 不足一帧时缓冲，不输出
+		packets, err := enc.Encode(pcm[:frame/2])
+		if err != nil || len(packets) != 0 {
+			t.Fatalf("%s: 半帧输入得到 %d 个包, 错误 %v", f, len(packets), err)
+		}
+		rest, err := enc.Encode(pcm[frame/2:])
+		if err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		if want := len(pcm) / frame; len(rest) != want {
+			t.Fatalf("%s: 得到 %d 个包, 期望 %d", f, len(rest), want)
+		}
+
+		var decoded []byte
+		for _, p := range rest {
+			out, err := dec.Decode(p)
+			if err != nil {
+				t.Fatalf("%s: %v", f, err)
+			}
+			if len(out) != frame {
+				t.Fatalf("%s: 解码得到 %d 字节, 期望 %d", f, len(out), frame)
+			}
+			decoded = append(decoded, out...)
+		}
+
+		// 有损编码不比较波形，只检查能量大致保留（跳过编码器起始延迟）
+		in, out := rms(pcm[frame*2:]), rms(decoded[frame*2:])
+		if out < in*0.5 || out > in*1.5 {
+			t.Errorf("%s: 解码后 RMS %.0f, 原始 %.0f", f, out, in)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package audio
 import (
 	"encoding/binary"
 	"fmt"
+	"math"
 	"sync"
 
 	"github.com/dh1tw/gosamplerate"
@@ -15,13 +16,41 @@ const (
 	Ratio         = float64(OutSampleRate) / float64(InSampleRate)
 )
 
+// Processor 在两种音频格式之间转换：解码、声道上/下混、重采样、编码
+//
+// 重采样器保存跨帧状态，同一路音频流应始终使用同一个 Processor。
 type Processor struct {
+	in      Format
+	out     Format
+	quality int
+
 	src   *gosamplerate.Src
 	srcMu sync.Mutex
 }
 
+// NewProcessor 创建上游输出（24 kHz）到默认客户端输出（48 kHz）的转换器
 func NewProcessor() *Processor {
-	return &Processor{}
+	return NewFormatProcessor(UpstreamOutput, DefaultClientOutput)
+}
+
+// NewFormatProcessor 创建任意两种格式之间的转换器
+func NewFormatProcessor(in, out Format) *Processor {
+	return &Processor{in: in, out: out, quality: gosamplerate.SRC_SINC_MEDIUM_QUALITY}
+}
+
+// Input 返回输入格式
+func (p *Processor) Input() Format {
+	return p.in
+}
+
+// Output 返回输出格式
+func (p *Processor) Output() Format {
+	return p.out
+}
+
+// Passthrough 输入输出格式相同，无需转换
+func (p *Processor) Passthrough() bool {
+	return p.in == p.out
 }
 
 func (p *Processor) InitSRC() error {
@@ -30,7 +59,7 @@ func (p *Processor) InitSRC() error {
 	if p.src != nil {
 		return nil
 	}
-	s, err := gosamplerate.New(gosamplerate.SRC_SINC_MEDIUM_QUALITY, Channels, 2048)
+	s, err := gosamplerate.New(p.quality, p.out.Channels, 2048)
 	if err != nil {
 		return fmt.Errorf("init SRC: %w", err)
 	}
@@ -38,37 +67,96 @@ func (p *Processor) InitSRC() error {
 	return nil
 }
 
-func (p *Processor) Resample(pcm24k []byte, final bool) ([]byte, error) {
-	if len(pcm24k) == 0 && !final {
+// Close 释放重采样器，之后再转换时重新创建
+func (p *Processor) Close() {
+	p.srcMu.Lock()
+	defer p.srcMu.Unlock()
+	if p.src != nil {
+		_ = gosamplerate.Delete(*p.src)
+		p.src = nil
+	}
+}
+
+// Resample 将输入格式的音频转换为输出格式，final 为 true 时冲刷重采样器内部缓冲
+func (p *Processor) Resample(data []byte, final bool) ([]byte, error) {
+	if len(data) == 0 && !final {
 		return nil, nil
 	}
-	if err := p.InitSRC(); err != nil {
-		return nil, err
+	if p.Passthrough() {
+		return data, nil
 	}
-	if len(pcm24k)%2 != 0 {
-		pcm24k = pcm24k[:len(pcm24k)-1]
+
+	samples := mixChannels(decodeSamples(data, p.in), p.in.Channels, p.out.Channels)
+	if p.in.SampleRate != p.out.SampleRate {
+		if err := p.InitSRC(); err != nil {
+			return nil, err
+		}
+		ratio := float64(p.out.SampleRate) / float64(p.in.SampleRate)
+		p.srcMu.Lock()
+		out, err := p.src.Process(samples, ratio, final)
+		p.srcMu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+		samples = out
 	}
-	n := len(pcm24k) / 2
-	in := make([]float32, n)
+	return encodeSamples(samples, p.out), nil
+}
+
+// decodeSamples 将 PCM 解码为交错的 float32 采样，丢弃末尾不完整的帧
+func decodeSamples(data []byte, f Format) []float32 {
+	frame := f.bytesPerSample() * f.Channels
+	n := (len(data) / frame) * f.Channels
+	samples := make([]float32, n)
 	for i := 0; i < n; i++ {
-		s := int16(binary.LittleEndian.Uint16(pcm24k[2*i:]))
-		in[i] = float32(s) / 32768.0
+		switch f.Encoding {
+		case EncodingF32LE:
+			samples[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+		default:
+			samples[i] = float32(int16(binary.LittleEndian.Uint16(data[2*i:]))) / 32768.0
+		}
 	}
-	p.srcMu.Lock()
-	outFloats, err := p.src.Process(in, Ratio, final)
-	p.srcMu.Unlock()
-	if err != nil {
-		return nil, err
+	return samples
+}
+
+// encodeSamples 将交错的 float32 采样编码为 PCM
+func encodeSamples(samples []float32, f Format) []byte {
+	out := make([]byte, len(samples)*f.bytesPerSample())
+	for i, v := range samples {
+		if v > 1 {
+			v = 1
+		} else if v < -1 {
+			v = -1
+		}
+		switch f.Encoding {
+		case EncodingF32LE:
+			binary.LittleEndian.PutUint32(out[4*i:], math.Float32bits(v))
+		default:
+			binary.LittleEndian.PutUint16(out[2*i:], uint16(int16(v*32767.0)))
+		}
 	}
-	out := make([]byte, len(outFloats)*2)
-	for i, f := range outFloats {
-		if f > 1 {
-			f = 1
-		} else if f < -1 {
-			f = -1
+	return out
+}
+
+// mixChannels 声道转换：多声道下混取平均，单声道上混复制到各声道
+func mixChannels(samples []float32, from, to int) []float32 {
+	if from == to {
+		return samples
+	}
+	frames := len(samples) / from
+	out := make([]float32, frames*to)
+	for i := 0; i < frames; i++ {
+		if to == 1 {
+			var sum float32
+			for c := 0; c < from; c++ {
+				sum += samples[i*from+c]
+			}
+			out[i] = sum / float32(from)
+			continue
+		}
+		for c := 0; c < to; c++ {
+			out[i*to+c] = samples[i*from+c%from]
 		}
-		s := int16(f * 32767.0)
-		binary.LittleEndian.PutUint16(out[2*i:], uint16(s))
 	}
-	return out, nil
+	return out
 }
@@ -0,0 +1,123 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// s16 将采样编码为 16 位小端 PCM
+func s16(samples ...int16) []byte {
+	out := make([]byte, len(samples)*2)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint16(out[i*2:], uint16(v))
+	}
+	return out
+}
+
+// f32 将采样编码为 32 位小端浮点 PCM
+func f32(samples ...float32) []byte {
+	out := make([]byte, len(samples)*4)
+	for i, v := range samples {
+		binary.LittleEndian.PutUint32(out[i*4:], math.Float32bits(v))
+	}
+	return out
+}
+
+// readS16 解码 16 位小端 PCM
+func readS16(data []byte) []int16 {
+	out := make([]int16, len(data)/2)
+	for i := range out {
+		out[i] = int16(binary.LittleEndian.Uint16(data[i*2:]))
+	}
+	return out
+}
+
+func TestResampleWithoutSRC(t *testing.T) {
+	mono := Format{SampleRate: 16000, Channels: 1, Encoding: EncodingS16LE}
+	stereo := Format{SampleRate: 16000, Channels: 2, Encoding: EncodingS16LE}
+	monoF32 := Format{SampleRate: 16000, Channels: 1, Encoding: EncodingF32LE}
+
+	tests := []struct {
+		name    string
+		in, out Format
+		data    []byte
+		want    []int16 // 输出为 f32 时按 wantF32 比较
+		wantF32 []float32
+	}{
+		{"s16le 转 f32le", mono, monoF32, s16(16384, -16384, 0), nil, []float32{0.5, -0.5, 0}},
+		{"f32le 转 s16le 并限幅", monoF32, mono, f32(0.5, -0.5, 1.5, -2), []int16{16383, -16383, 32767, -32767}, nil},
+		{"立体声下混取平均", stereo, mono, s16(1000, 3000, -2000, 2000), []int16{1999, 0}, nil},
+		{"单声道上混复制到两个声道", mono, stereo, s16(1000, -1000), []int16{999, 999, -999, -999}, nil},
+		{"丢弃末尾不完整的帧", stereo, mono, append(s16(1000, 1000), 0x01, 0x02), []int16{999}, nil},
+	}
+	for _, tt := range tests {
+		got, err := NewFormatProcessor(tt.in, tt.out).Resample(tt.data, false)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if tt.wantF32 != nil {
+			if !bytes.Equal(got, f32(tt.wantF32...)) {
+				t.Errorf("%s: 得到 % x, 期望 %v", tt.name, got, tt.wantF32)
+			}
+			continue
+		}
+		if samples := readS16(got); len(samples) != len(tt.want) || !equalS16(samples, tt.want) {
+			t.Errorf("%s: 得到 %v, 期望 %v", tt.name, samples, tt.want)
+		}
+	}
+}
+
+func equalS16(a, b []int16) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestResamplePassthrough(t *testing.T) {
+	p := NewFormatProcessor(UpstreamInput, UpstreamInput)
+	if !p.Passthrough() {
+		t.Fatal("相同格式应直接透传")
+	}
+	data := s16(1, -2, 3)
+	got, err := p.Resample(data, false)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("透传 = %v, %v", got, err)
+	}
+	if got, err := NewFormatProcessor(UpstreamOutput, DefaultClientOutput).Resample(nil, false); got != nil || err != nil {
+		t.Fatalf("空输入 = %v, %v, 期望不输出", got, err)
+	}
+}
+
+func TestResampleRateChange(t *testing.T) {
+	in := Format{SampleRate: 16000, Channels: 1, Encoding: EncodingS16LE}
+	out := Format{SampleRate: 48000, Channels: 1, Encoding: EncodingS16LE}
+	p := NewFormatProcessor(in, out)
+	defer p.Close()
+
+	chunk := constPCM(8000, 1600) // 100ms
+	var got []int16
+	for i := 0; i < 5; i++ {
+		data, err := p.Resample(chunk, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, readS16(data)...)
+	}
+	tail, err := p.Resample(nil, true) // 冲刷重采样器缓冲
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, readS16(tail)...)
+
+	if want := 5 * 4800; math.Abs(float64(len(got)-want)) > float64(want)/100 {
+		t.Fatalf("输出 %d 个采样, 期望约 %d", len(got), want)
+	}
+	if mid := got[len(got)/2]; math.Abs(float64(mid)-8000) > 100 {
+		t.Fatalf("稳态采样 = %d, 期望约 8000", mid)
+	}
+}
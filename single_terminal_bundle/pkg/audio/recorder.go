@@ -0,0 +1,204 @@
+package audio
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// RecordingConfig 录音配置
+type RecordingConfig struct {
+	Enabled      bool          // 是否录制客户端麦克风音频
+	Dir          string        // 录音根目录，文件存放在其下的 RecordingsPrefix 目录中，按房间分子目录
+	SyncInterval time.Duration // 刷新 WAV 头部并 fsync 的间隔，进程崩溃时最多丢失该时长的音频
+}
+
+// DefaultRecordingConfig 返回默认配置
+func DefaultRecordingConfig() RecordingConfig {
+	return RecordingConfig{
+		Enabled:      true,
+		Dir:          "audio",
+		SyncInterval: 5 * time.Second,
+	}
+}
+
+// RecordingsPrefix 录音在录音根目录和存储后端中的目录，与 Save* 保存的旧版音频文件分开
+const RecordingsPrefix = "recordings"
+
+// SessionPath 返回房间一次翻译会话中某条音轨的录音文件路径：<Dir>/recordings/<roomID>/session_<sessionID>_<track>.wav
+func (c RecordingConfig) SessionPath(roomID, sessionID, track string) string {
+	return filepath.Join(c.Dir, RecordingsPrefix, roomID, fmt.Sprintf("session_%s_%s.wav", sessionID, track))
+}
+
+// Path 返回客户端本次连接的录音文件路径：<Dir>/recordings/<roomID>/<clientID>_<开始时间>.wav
+func (c RecordingConfig) Path(roomID, clientID string, start time.Time) string {
+	name := fmt.Sprintf("%s_%s.wav", clientID, start.UTC().Format("20060102T150405Z"))
+	return filepath.Join(c.Dir, RecordingsPrefix, roomID, name)
+}
+
+// Recorder 将音频流式写入 WAV 文件，定期回写头部长度并 fsync，关闭时完成头部
+type Recorder struct {
+	mu        sync.Mutex
+	file      *os.File
+	path      string
+	format    Format
+	dataBytes int64
+	syncEvery time.Duration
+	lastSync  time.Time
+	closed    bool
+}
+
+// NewRecorder 创建录音文件并写入初始头部
+func NewRecorder(path string, format Format, syncEvery time.Duration) (*Recorder, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("创建录音目录失败: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("创建录音文件失败: %w", err)
+	}
+	if _, err := file.Write(WAVHeader(format, 0)); err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, fmt.Errorf("写入WAV头部失败: %w", err)
+	}
+	return &Recorder{
+		file:      file,
+		path:      path,
+		format:    format,
+		syncEvery: syncEvery,
+		lastSync:  time.Now(),
+	}, nil
+}
+
+// Path 返回录音文件路径
+func (r *Recorder) Path() string {
+	return r.path
+}
+
+// Format 返回录音格式
+func (r *Recorder) Format() Format {
+	return r.format
+}
+
+// Duration 返回已录制的时长
+func (r *Recorder) Duration() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.format.duration(r.dataBytes)
+}
+
+// Size 返回已写入的音频数据字节数（不含头部）
+func (r *Recorder) Size() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dataBytes
+}
+
+// Write 追加音频数据，距上次同步超过 SyncInterval 时回写头部并 fsync
+func (r *Recorder) Write(pcm []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.writeLocked(pcm); err != nil {
+		return err
+	}
+	return r.maybeSyncLocked()
+}
+
+// silence 补静音用的零值缓冲
+var silence = make([]byte, 64*1024)
+
+// WriteAligned 在时间线 offset 处写入音频：已录制长度不足 offset 时先补静音，已超过时紧接着追加，
+// 返回该段音频在录音中的起止时间
+func (r *Recorder) WriteAligned(pcm []byte, offset time.Duration) (start, end time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for gap := r.format.BytesAt(offset) - r.dataBytes; gap > 0; {
+		n := int64(len(silence))
+		if gap < n {
+			n = gap
+		}
+		if err := r.writeLocked(silence[:n]); err != nil {
+			return 0, 0, err
+		}
+		gap -= n
+	}
+	start = r.format.duration(r.dataBytes)
+	if err := r.writeLocked(pcm); err != nil {
+		return start, start, err
+	}
+	return start, r.format.duration(r.dataBytes), r.maybeSyncLocked()
+}
+
+// writeLocked 追加数据，调用方需持有 mu
+func (r *Recorder) writeLocked(pcm []byte) error {
+	if r.closed {
+		return fmt.Errorf("录音已关闭: %s", r.path)
+	}
+	if r.dataBytes+int64(len(pcm)) > maxWAVData {
+		return fmt.Errorf("录音超出WAV长度上限: %s", r.path)
+	}
+	n, err := r.file.Write(pcm)
+	r.dataBytes += int64(n)
+	if err != nil {
+		return fmt.Errorf("写入录音失败: %w", err)
+	}
+	return nil
+}
+
+// maybeSyncLocked 距上次同步超过 SyncInterval 时同步，调用方需持有 mu
+func (r *Recorder) maybeSyncLocked() error {
+	if r.syncEvery > 0 && time.Since(r.lastSync) >= r.syncEvery {
+		return r.syncLocked()
+	}
+	return nil
+}
+
+// Sync 回写头部长度并 fsync
+func (r *Recorder) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	return r.syncLocked()
+}
+
+// syncLocked 调用方需持有 mu
+func (r *Recorder) syncLocked() error {
+	r.lastSync = time.Now()
+	if _, err := r.file.WriteAt(WAVHeader(r.format, r.dataBytes), 0); err != nil {
+		return fmt.Errorf("回写WAV头部失败: %w", err)
+	}
+	if err := r.file.Sync(); err != nil {
+		return fmt.Errorf("同步录音文件失败: %w", err)
+	}
+	return nil
+}
+
+// Close 完成头部并关闭文件，没有录到任何音频时删除文件
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+
+	if r.dataBytes == 0 {
+		r.file.Close()
+		return os.Remove(r.path)
+	}
+	syncErr := r.syncLocked()
+	if err := r.file.Close(); err != nil {
+		return fmt.Errorf("关闭录音文件失败: %w", err)
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	log.Printf("[AUDIO] ✅ 已保存录音 %s（%s，%s）", r.path, r.format, r.format.duration(r.dataBytes).Round(time.Millisecond))
+	return nil
+}
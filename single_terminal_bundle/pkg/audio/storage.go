@@ -1,22 +1,22 @@
 package audio
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"go-backEnd/pkg/storage"
 	"log"
-	"os"
-	"path/filepath"
 )
 
-func SavePCMFile(roomID string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
+// putBlob 把整段音频写入全局存储后端，filename 为对象名
+func putBlob(filename string, data []byte) error {
+	return storage.Default.Put(context.Background(), filename, bytes.NewReader(data), int64(len(data)))
+}
 
-	filename := filepath.Join(audioDir, fmt.Sprintf("%s.pcm", roomID))
+func SavePCMFile(roomID string, audioData []byte) error {
+	filename := fmt.Sprintf("%s.pcm", roomID)
 
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存PCM文件失败: %w", err)
 	}
 
@@ -26,14 +26,9 @@ func SavePCMFile(roomID string, audioData []byte) error {
 
 // SavePCMFileWithLanguage 保存带语言前缀的PCM文件
 func SavePCMFileWithLanguage(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
-
-	filename := filepath.Join(audioDir, fmt.Sprintf("%s_%s.pcm", language, roomID))
+	filename := fmt.Sprintf("%s_%s.pcm", language, roomID)
 
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存PCM文件失败: %w", err)
 	}
 
@@ -43,14 +38,9 @@ func SavePCMFileWithLanguage(roomID string, language string, audioData []byte) e
 
 // SavePCMFileWithHeader 保存带20字节头部的PCM文件
 func SavePCMFileWithHeader(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
-
-	filename := filepath.Join(audioDir, fmt.Sprintf("%s_%s_with_header.pcm", language, roomID))
+	filename := fmt.Sprintf("%s_%s_with_header.pcm", language, roomID)
 
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存PCM文件失败: %w", err)
 	}
 
@@ -60,14 +50,9 @@ func SavePCMFileWithHeader(roomID string, language string, audioData []byte) err
 
 // SavePCMFileProcessed 保存处理后的PCM文件（添加头部后再去除）
 func SavePCMFileProcessed(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
+	filename := fmt.Sprintf("%s_%s_processed.pcm", language, roomID)
 
-	filename := filepath.Join(audioDir, fmt.Sprintf("%s_%s_processed.pcm", language, roomID))
-
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存PCM文件失败: %w", err)
 	}
 
@@ -77,14 +62,9 @@ func SavePCMFileProcessed(roomID string, language string, audioData []byte) erro
 
 // SaveMixedPCMFile 保存混合后的PCM音频文件
 func SaveMixedPCMFile(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
-
-	filename := filepath.Join(audioDir, fmt.Sprintf("mixed_%s_%s.pcm", language, roomID))
+	filename := fmt.Sprintf("mixed_%s_%s.pcm", language, roomID)
 
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存混合PCM文件失败: %w", err)
 	}
 
@@ -94,14 +74,9 @@ func SaveMixedPCMFile(roomID string, language string, audioData []byte) error {
 
 // SaveSentAudioFile 保存发送给翻译服务的音频文件（直接模式）
 func SaveSentAudioFile(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
-
 	// 保存带语言头部的完整音频
-	filename := filepath.Join(audioDir, fmt.Sprintf("sent_%s_%s.pcm", language, roomID))
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	filename := fmt.Sprintf("sent_%s_%s.pcm", language, roomID)
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存发送音频文件失败: %w", err)
 	}
 	log.Printf("[AUDIO] ✅ 已保存房间 %s 的%s语言发送音频文件: %s, 大小: %d字节", roomID, language, filename, len(audioData))
@@ -111,14 +86,9 @@ func SaveSentAudioFile(roomID string, language string, audioData []byte) error {
 
 // SaveSentMixedAudioFile 保存发送给翻译服务的混合音频文件
 func SaveSentMixedAudioFile(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
-
 	// 保存带语言头部的完整混合音频
-	filename := filepath.Join(audioDir, fmt.Sprintf("sent_mixed_%s_%s.pcm", language, roomID))
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	filename := fmt.Sprintf("sent_mixed_%s_%s.pcm", language, roomID)
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存发送混合音频文件失败: %w", err)
 	}
 	log.Printf("[AUDIO] ✅ 已保存房间 %s 的%s语言发送混合音频文件: %s, 大小: %d字节", roomID, language, filename, len(audioData))
@@ -128,14 +98,9 @@ func SaveSentMixedAudioFile(roomID string, language string, audioData []byte) er
 
 // SaveSentPureAudioFile 保存发送给翻译服务的纯音频文件（不含20字节头部）
 func SaveSentPureAudioFile(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
-
 	// 保存纯音频数据（不含语言头部）
-	filename := filepath.Join(audioDir, fmt.Sprintf("sent_pure_%s_%s.pcm", language, roomID))
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	filename := fmt.Sprintf("sent_pure_%s_%s.pcm", language, roomID)
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存发送纯音频文件失败: %w", err)
 	}
 	log.Printf("[AUDIO] ✅ 已保存房间 %s 的%s语言发送纯音频文件: %s, 大小: %d字节", roomID, language, filename, len(audioData))
@@ -145,14 +110,9 @@ func SaveSentPureAudioFile(roomID string, language string, audioData []byte) err
 
 // SaveSentPureMixedAudioFile 保存发送给翻译服务的纯混合音频文件（不含20字节头部）
 func SaveSentPureMixedAudioFile(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
-
 	// 保存纯混合音频数据（不含语言头部）
-	filename := filepath.Join(audioDir, fmt.Sprintf("sent_pure_mixed_%s_%s.pcm", language, roomID))
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	filename := fmt.Sprintf("sent_pure_mixed_%s_%s.pcm", language, roomID)
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存发送纯混合音频文件失败: %w", err)
 	}
 	log.Printf("[AUDIO] ✅ 已保存房间 %s 的%s语言发送纯混合音频文件: %s, 大小: %d字节", roomID, language, filename, len(audioData))
@@ -162,13 +122,8 @@ func SaveSentPureMixedAudioFile(roomID string, language string, audioData []byte
 
 // SaveMixedPurePCMFile 保存混音后的纯音频文件（不含20字节头部）
 func SaveMixedPurePCMFile(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
-
-	filename := filepath.Join(audioDir, fmt.Sprintf("mixed_pure_%s_%s.pcm", language, roomID))
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	filename := fmt.Sprintf("mixed_pure_%s_%s.pcm", language, roomID)
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存混音纯音频文件失败: %w", err)
 	}
 	log.Printf("[AUDIO] ✅ 已保存房间 %s 的%s语言混音纯音频文件: %s, 大小: %d字节", roomID, language, filename, len(audioData))
@@ -178,13 +133,8 @@ func SaveMixedPurePCMFile(roomID string, language string, audioData []byte) erro
 
 // SaveMixedSentPCMFile 保存混音后发送给翻译服务的音频文件（含20字节头部）
 func SaveMixedSentPCMFile(roomID string, language string, audioData []byte) error {
-	audioDir := "audio"
-	if err := os.MkdirAll(audioDir, 0755); err != nil {
-		return fmt.Errorf("创建音频目录失败: %w", err)
-	}
-
-	filename := filepath.Join(audioDir, fmt.Sprintf("mixed_sent_%s_%s.pcm", language, roomID))
-	if err := ioutil.WriteFile(filename, audioData, 0644); err != nil {
+	filename := fmt.Sprintf("mixed_sent_%s_%s.pcm", language, roomID)
+	if err := putBlob(filename, audioData); err != nil {
 		return fmt.Errorf("保存混音发送音频文件失败: %w", err)
 	}
 	log.Printf("[AUDIO] ✅ 已保存房间 %s 的%s语言混音发送音频文件: %s, 大小: %d字节", roomID, language, filename, len(audioData))
@@ -0,0 +1,143 @@
+package audio
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+// VADEvent 语音活动状态变化
+type VADEvent int
+
+const (
+	VADNone        VADEvent = iota // 状态未变化
+	VADSpeechStart                 // 开始说话
+	VADSpeechEnd                   // 停止说话（已超过拖尾时长）
+)
+
+// VADConfig 语音活动检测配置，输入为 16 位小端单声道 PCM
+type VADConfig struct {
+	SampleRate      int           // 输入采样率
+	EnergyThreshold float64       // 判定为语音的最小 RMS（归一化到 [0, 1]）
+	MaxZeroCrossing float64       // 语音帧的最大过零率，能量达标但过零率过高视为噪声
+	Hangover        time.Duration // 语音结束后继续放行的拖尾时长，避免切掉句尾和词间停顿
+	PreRoll         time.Duration // 语音开始前补发的音频时长，避免切掉起音
+}
+
+// DefaultVADConfig 返回默认配置
+func DefaultVADConfig() VADConfig {
+	return VADConfig{
+		SampleRate:      16000,
+		EnergyThreshold: 0.012,
+		MaxZeroCrossing: 0.35,
+		Hangover:        600 * time.Millisecond,
+		PreRoll:         200 * time.Millisecond,
+	}
+}
+
+// VAD 基于能量和过零率的语音活动检测，按音频时长（而非墙钟时间）计算拖尾，结果与网络抖动无关
+type VAD struct {
+	cfg      VADConfig
+	speaking bool
+	silence  time.Duration // 语音状态下连续非语音帧的时长
+	preRoll  []byte        // 静音期间保留的最近音频
+	maxPre   int
+}
+
+// NewVAD 创建语音活动检测器
+func NewVAD(cfg VADConfig) *VAD {
+	def := DefaultVADConfig()
+	if cfg.SampleRate <= 0 {
+		cfg.SampleRate = def.SampleRate
+	}
+	if cfg.EnergyThreshold <= 0 {
+		cfg.EnergyThreshold = def.EnergyThreshold
+	}
+	if cfg.MaxZeroCrossing <= 0 {
+		cfg.MaxZeroCrossing = def.MaxZeroCrossing
+	}
+	if cfg.Hangover < 0 {
+		cfg.Hangover = 0
+	}
+	v := &VAD{cfg: cfg}
+	if cfg.PreRoll > 0 { // durationBytes 至少返回一个采样，PreRoll 为 0 时不保留预留音频
+		v.maxPre = durationBytes(cfg.SampleRate, cfg.PreRoll)
+	}
+	return v
+}
+
+// Speaking 当前是否处于语音状态
+func (v *VAD) Speaking() bool {
+	return v.speaking
+}
+
+// Process 检测一帧音频，返回应发送到上游的数据（静音时为 nil）、状态变化以及被抑制的时长
+func (v *VAD) Process(pcm []byte) (out []byte, event VADEvent, suppressed time.Duration) {
+	d := v.duration(pcm)
+	voiced := isVoiced(pcm, v.cfg.EnergyThreshold, v.cfg.MaxZeroCrossing)
+
+	switch {
+	case voiced && !v.speaking: // 起音：补发预留音频
+		v.speaking = true
+		v.silence = 0
+		out = append(v.preRoll, pcm...)
+		v.preRoll = nil
+		return out, VADSpeechStart, 0
+	case voiced:
+		v.silence = 0
+		return pcm, VADNone, 0
+	case v.speaking:
+		v.silence += d
+		if v.silence <= v.cfg.Hangover { // 拖尾期间继续放行
+			return pcm, VADNone, 0
+		}
+		v.speaking = false
+		v.silence = 0
+		v.keep(pcm)
+		return nil, VADSpeechEnd, d
+	default:
+		v.keep(pcm)
+		return nil, VADNone, d
+	}
+}
+
+// keep 保留最近的静音音频作为下一次起音的预留
+func (v *VAD) keep(pcm []byte) {
+	if v.maxPre <= 0 {
+		return
+	}
+	v.preRoll = append(v.preRoll, pcm...)
+	if over := len(v.preRoll) - v.maxPre; over > 0 {
+		over += over % 2
+		v.preRoll = append(v.preRoll[:0], v.preRoll[over:]...)
+	}
+}
+
+func (v *VAD) duration(pcm []byte) time.Duration {
+	return time.Duration(len(pcm)/2) * time.Second / time.Duration(v.cfg.SampleRate)
+}
+
+// isVoiced 能量达到阈值且过零率不高于上限时判定为语音
+func isVoiced(pcm []byte, threshold, maxZCR float64) bool {
+	if RMS(pcm) < threshold {
+		return false
+	}
+	return ZeroCrossingRate(pcm) <= maxZCR
+}
+
+// ZeroCrossingRate 计算 16 位小端 PCM 的过零率
+func ZeroCrossingRate(pcm []byte) float64 {
+	n := len(pcm) / 2
+	if n < 2 {
+		return 0
+	}
+	crossings := 0
+	prev := int16(binary.LittleEndian.Uint16(pcm))
+	for i := 1; i < n; i++ {
+		cur := int16(binary.LittleEndian.Uint16(pcm[i*2:]))
+		if (prev >= 0) != (cur >= 0) {
+			crossings++
+		}
+		prev = cur
+	}
+	return float64(crossings) / float64(n-1)
+}
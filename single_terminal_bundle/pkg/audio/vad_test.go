@@ -0,0 +1,92 @@
+package audio
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// vadFrame 生成 10ms 16kHz 的测试帧：voice 为正弦波，quiet 为低于阈值的直流，noise 为能量高但逐点过零的噪声
+func vadFrame(kind string) []byte {
+	switch kind {
+	case "voice":
+		return sinePCM(opusTestFormat, 10)
+	case "quiet":
+		return constPCM(50, 160)
+	case "noise":
+		out := constPCM(8000, 160)
+		for i := 1; i < 160; i += 2 {
+			copy(out[i*2:], constPCM(-8000, 1))
+		}
+		return out
+	}
+	return make([]byte, 320)
+}
+
+type vadStep struct {
+	in         string
+	outMs      int // 放行的音频时长，包括补发的预留音频
+	event      VADEvent
+	suppressed int // 被抑制的时长（毫秒）
+}
+
+func TestVAD(t *testing.T) {
+	tests := []struct {
+		name    string
+		preRoll time.Duration
+		steps   []vadStep
+	}{
+		{"拖尾期间放行，超过后结束并抑制", 20 * time.Millisecond, []vadStep{
+			{"silence", 0, VADNone, 10},
+			{"silence", 0, VADNone, 10},
+			{"silence", 0, VADNone, 10},
+			{"voice", 30, VADSpeechStart, 0}, // 补发最近 20ms
+			{"voice", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 0, VADSpeechEnd, 10},
+			{"voice", 20, VADSpeechStart, 0}, // 结束时的一帧成为下一次起音的预留
+		}},
+		{"语音帧重置拖尾计时", 20 * time.Millisecond, []vadStep{
+			{"voice", 10, VADSpeechStart, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"voice", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 10, VADNone, 0},
+			{"silence", 0, VADSpeechEnd, 10},
+		}},
+		{"过零率过高的噪声不算语音", 20 * time.Millisecond, []vadStep{
+			{"noise", 0, VADNone, 10},
+			{"noise", 0, VADNone, 10},
+			{"voice", 30, VADSpeechStart, 0},
+		}},
+		{"能量低于阈值不算语音", 20 * time.Millisecond, []vadStep{
+			{"quiet", 0, VADNone, 10},
+			{"voice", 20, VADSpeechStart, 0},
+		}},
+		{"不保留预留音频", 0, []vadStep{
+			{"silence", 0, VADNone, 10},
+			{"voice", 10, VADSpeechStart, 0},
+		}},
+	}
+	for _, tt := range tests {
+		v := NewVAD(VADConfig{SampleRate: 16000, Hangover: 40 * time.Millisecond, PreRoll: tt.preRoll})
+		for i, step := range tt.steps {
+			frame := vadFrame(step.in)
+			out, event, suppressed := v.Process(frame)
+			if len(out) != step.outMs*32 || event != step.event || suppressed != time.Duration(step.suppressed)*time.Millisecond {
+				t.Fatalf("%s 第 %d 步 (%s): 放行 %dms, 事件 %d, 抑制 %s; 期望 %dms, %d, %dms",
+					tt.name, i, step.in, len(out)/32, event, suppressed, step.outMs, step.event, step.suppressed)
+			}
+			if out != nil && !bytes.HasSuffix(out, frame) {
+				t.Fatalf("%s 第 %d 步: 放行的音频应以当前帧结尾", tt.name, i)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// wavHeaderSize 标准 PCM WAV 头部长度
+const wavHeaderSize = 44
+
+// maxWAVData WAV 数据块长度上限（RIFF 使用 32 位长度）
+const maxWAVData = 1<<32 - 1 - (wavHeaderSize - 8)
+
+// WAVHeader 构造 dataBytes 字节音频数据对应的 WAV 头部
+func WAVHeader(format Format, dataBytes int64) []byte {
+	if dataBytes > maxWAVData {
+		dataBytes = maxWAVData
+	}
+	formatTag := uint16(1) // PCM
+	if format.Encoding == EncodingF32LE {
+		formatTag = 3 // IEEE float
+	}
+	blockAlign := format.BlockAlign()
+
+	h := make([]byte, wavHeaderSize)
+	copy(h[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(h[4:8], uint32(dataBytes+wavHeaderSize-8))
+	copy(h[8:12], "WAVE")
+	copy(h[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(h[16:20], 16)
+	binary.LittleEndian.PutUint16(h[20:22], formatTag)
+	binary.LittleEndian.PutUint16(h[22:24], uint16(format.Channels))
+	binary.LittleEndian.PutUint32(h[24:28], uint32(format.SampleRate))
+	binary.LittleEndian.PutUint32(h[28:32], uint32(format.SampleRate*blockAlign))
+	binary.LittleEndian.PutUint16(h[32:34], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(h[34:36], uint16(format.bytesPerSample()*8))
+	copy(h[36:40], "data")
+	binary.LittleEndian.PutUint32(h[40:44], uint32(dataBytes))
+	return h
+}
+
+// WAVReader 在裸 PCM 数据前拼接 WAV 头部，支持 Seek，可直接交给 http.ServeContent 做范围下载
+func WAVReader(format Format, pcm *io.SectionReader) io.ReadSeeker {
+	return &wavReader{header: bytes.NewReader(WAVHeader(format, pcm.Size())), pcm: pcm}
+}
+
+type wavReader struct {
+	header *bytes.Reader
+	pcm    *io.SectionReader
+	offset int64
+}
+
+func (r *wavReader) size() int64 {
+	return r.header.Size() + r.pcm.Size()
+}
+
+func (r *wavReader) Read(p []byte) (int, error) {
+	headerSize := r.header.Size()
+	var n int
+	var err error
+	if r.offset < headerSize {
+		n, err = r.header.ReadAt(p, r.offset)
+		if err == io.EOF {
+			err = nil
+		}
+	} else {
+		n, err = r.pcm.ReadAt(p, r.offset-headerSize)
+		if err == io.EOF && n > 0 {
+			err = nil
+		}
+	}
+	r.offset += int64(n)
+	return n, err
+}
+
+func (r *wavReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size()
+	default:
+		return 0, errors.New("wavReader: 无效的 whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("wavReader: 负的偏移")
+	}
+	r.offset = offset
+	return offset, nil
+}
@@ -0,0 +1,26 @@
+package languages
+
+// 西文句末标点，后面需跟空白才视为句子结束
+var latinEnd = []string{".", "!", "?"}
+
+// 中日文句末标点，全角标点出现即结束句子，夹带的西文标点仍需跟空白
+var cjkEnd = []string{"。", "！", "？", "．", ".", "!", "?"}
+
+// builtin 内置语言目录
+var builtin = []Language{
+	{Code: "zh", Name: "Chinese", NativeName: "中文", SentenceEnd: cjkEnd, ReverseTranslation: true},
+	{Code: "en", Name: "English", NativeName: "English", SentenceEnd: latinEnd, ReverseTranslation: true},
+	{Code: "ja", Name: "Japanese", NativeName: "日本語", SentenceEnd: cjkEnd, ReverseTranslation: true},
+	{Code: "ko", Name: "Korean", NativeName: "한국어", SentenceEnd: []string{"。", "！", "？", ".", "!", "?"}, ReverseTranslation: true},
+	{Code: "ar", Name: "Arabic", NativeName: "العربية", SentenceEnd: []string{".", "!", "؟", "۔"}, ReverseTranslation: true},
+	{Code: "hi", Name: "Hindi", NativeName: "हिन्दी", SentenceEnd: []string{"।", "॥", ".", "!", "?"}, ReverseTranslation: true},
+	{Code: "th", Name: "Thai", NativeName: "ไทย", SentenceEnd: nil, ReverseTranslation: false}, // 不使用句末标点，按空白切分
+	{Code: "fr", Name: "French", NativeName: "Français", SentenceEnd: latinEnd, ReverseTranslation: true},
+	{Code: "de", Name: "German", NativeName: "Deutsch", SentenceEnd: latinEnd, ReverseTranslation: true},
+	{Code: "es", Name: "Spanish", NativeName: "Español", SentenceEnd: latinEnd, ReverseTranslation: true},
+	{Code: "it", Name: "Italian", NativeName: "Italiano", SentenceEnd: latinEnd, ReverseTranslation: true},
+	{Code: "pt", Name: "Portuguese", NativeName: "Português", SentenceEnd: latinEnd, ReverseTranslation: true},
+	{Code: "ru", Name: "Russian", NativeName: "Русский", SentenceEnd: latinEnd, ReverseTranslation: true},
+	{Code: "vi", Name: "Vietnamese", NativeName: "Tiếng Việt", SentenceEnd: latinEnd, ReverseTranslation: true},
+	{Code: "id", Name: "Indonesian", NativeName: "Bahasa Indonesia", SentenceEnd: latinEnd, ReverseTranslation: true},
+}
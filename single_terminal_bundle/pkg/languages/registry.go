@@ -0,0 +1,175 @@
+// Package languages 维护支持的语言目录：语言代码、显示名称、句末标点、可翻译的目标语言及反向翻译能力
+//
+// 内置目录覆盖上游翻译服务当前支持的语言，部署时可通过 JSON 文件整体替换：
+//
+//	{"languages": [{"code": "zh", "name": "Chinese", "native_name": "中文", "sentence_end": ["。", "！", "？"],
+//	                "targets": ["en", "ja"], "reverse_translation": true}]}
+//
+// targets 为空表示可翻译为目录中的任意其他语言。sentence_end 决定分句规则（见 segmenter 包），
+// 为空表示该语言不使用句末标点。
+package languages
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Language 单个语言的元数据
+type Language struct {
+	Code               string   `json:"code"`                // 语言代码，与上游翻译服务一致
+	Name               string   `json:"name"`                // 英文显示名称
+	NativeName         string   `json:"native_name"`         // 本地化显示名称
+	SentenceEnd        []string `json:"sentence_end"`        // 句末标点
+	Targets            []string `json:"targets,omitempty"`   // 支持的目标语言，为空表示全部
+	ReverseTranslation bool     `json:"reverse_translation"` // 是否支持反向翻译
+}
+
+// Registry 语言目录
+type Registry struct {
+	mu     sync.RWMutex
+	byCode map[string]*Language
+}
+
+// NewRegistry 使用给定语言列表创建目录
+func NewRegistry(langs []Language) (*Registry, error) {
+	reg := &Registry{}
+	if err := reg.Replace(langs); err != nil {
+		return nil, err
+	}
+	return reg, nil
+}
+
+// Replace 整体替换目录内容
+func (reg *Registry) Replace(langs []Language) error {
+	byCode := make(map[string]*Language, len(langs))
+	for i := range langs {
+		lang := langs[i]
+		if lang.Code == "" {
+			return fmt.Errorf("第 %d 个语言缺少 code", i+1)
+		}
+		if _, dup := byCode[lang.Code]; dup {
+			return fmt.Errorf("语言代码重复: %s", lang.Code)
+		}
+		byCode[lang.Code] = &lang
+	}
+	for _, lang := range byCode {
+		for _, target := range lang.Targets {
+			if _, ok := byCode[target]; !ok {
+				return fmt.Errorf("语言 %s 的目标语言 %s 不在目录中", lang.Code, target)
+			}
+		}
+	}
+
+	reg.mu.Lock()
+	reg.byCode = byCode
+	reg.mu.Unlock()
+	return nil
+}
+
+// LoadFile 从 JSON 文件加载并替换目录
+func (reg *Registry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("读取语言目录失败: %w", err)
+	}
+	var file struct {
+		Languages []Language `json:"languages"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析语言目录失败: %w", err)
+	}
+	if len(file.Languages) == 0 {
+		return fmt.Errorf("语言目录为空: %s", path)
+	}
+	return reg.Replace(file.Languages)
+}
+
+// Get 按代码查找语言
+func (reg *Registry) Get(code string) (Language, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	lang, ok := reg.byCode[code]
+	if !ok {
+		return Language{}, false
+	}
+	return *lang, true
+}
+
+// All 返回按代码排序的全部语言
+func (reg *Registry) All() []Language {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	list := make([]Language, 0, len(reg.byCode))
+	for _, lang := range reg.byCode {
+		list = append(list, *lang)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code < list[j].Code })
+	return list
+}
+
+// TargetsOf 返回语言可翻译的目标语言代码
+func (reg *Registry) TargetsOf(code string) []string {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	lang, ok := reg.byCode[code]
+	if !ok {
+		return nil
+	}
+	if len(lang.Targets) > 0 {
+		return append([]string(nil), lang.Targets...)
+	}
+	targets := make([]string, 0, len(reg.byCode)-1)
+	for other := range reg.byCode {
+		if other != code {
+			targets = append(targets, other)
+		}
+	}
+	sort.Strings(targets)
+	return targets
+}
+
+// ValidatePair 检查语言对是否受支持
+func (reg *Registry) ValidatePair(from, to string) error {
+	if from == to {
+		return fmt.Errorf("源语言与目标语言不能相同: %s", from)
+	}
+	if _, ok := reg.Get(from); !ok {
+		return fmt.Errorf("不支持的源语言: %s", from)
+	}
+	if _, ok := reg.Get(to); !ok {
+		return fmt.Errorf("不支持的目标语言: %s", to)
+	}
+	for _, target := range reg.TargetsOf(from) {
+		if target == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("不支持从 %s 翻译到 %s", from, to)
+}
+
+// SupportsReverse 语言对是否支持反向翻译（目标语言译回源语言）
+func (reg *Registry) SupportsReverse(from, to string) bool {
+	fromLang, ok := reg.Get(from)
+	if !ok {
+		return false
+	}
+	toLang, ok := reg.Get(to)
+	if !ok {
+		return false
+	}
+	return fromLang.ReverseTranslation && toLang.ReverseTranslation
+}
+
+// Default 全局语言目录，初始为内置目录
+var Default, _ = NewRegistry(builtin)
+
+// Init 加载全局语言目录，path 为空时使用内置目录
+func Init(path string) error {
+	if path == "" {
+		return nil
+	}
+	return Default.LoadFile(path)
+}
@@ -0,0 +1,145 @@
+// Package protocol 定义客户端 WebSocket 的版本化消息协议
+//
+// 通过 Sec-WebSocket-Protocol 协商，客户端请求 "glot.v1" 时使用本协议：
+//   - 服务端→客户端的事件以文本帧发送 JSON 信封 {"v":1,"type":...,"seq":...,"payload":{...}}
+//   - 音频以二进制帧发送，前 10 字节为头部：版本(1) + 类型(1) + seq(8, 大端)，其后为 PCM
+//   - 客户端→服务端的控制消息以文本帧发送同样的信封，二进制帧为麦克风音频
+//
+// 以 codec=opus 连接时，麦克风方向每个二进制帧为一个 Opus 包；播放方向的音频负载（v1 头部之后）
+// 为若干 [长度(2, 大端) + Opus 包]。
+//
+// seq 为房间广播序号，客户端可据此发现丢帧；只发给单个客户端的回复 seq 为 0。
+// 未协商子协议的客户端使用旧格式：所有消息（JSON 与 PCM）均以二进制帧原样发送。
+package protocol
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+const (
+	// Version 当前协议版本
+	Version = 1
+	// SubprotocolV1 v1 协议的 Sec-WebSocket-Protocol 名称
+	SubprotocolV1 = "glot.v1"
+	// Legacy 未协商子协议时的旧格式
+	Legacy = ""
+)
+
+// 服务端→客户端事件类型
+const (
+	EventTranscriptPartial  = "transcript.partial"
+	EventTranscriptFinal    = "transcript.final"
+	EventReverseTranslation = "reverse_translation"
+	EventAudio              = "audio"
+	EventStatus             = "status"
+	EventError              = "error"
+)
+
+// 客户端→服务端控制消息类型
+const (
+	ControlMute           = "mute"
+	ControlChangeLanguage = "change_language"
+	ControlEnd            = "end"
+	ControlKick           = "kick" // 主持人移出参与者
+	ControlLock           = "lock" // 主持人锁定或解锁房间
+)
+
+// 二进制音频帧头部
+const (
+	audioKind       byte = 1
+	AudioHeaderSize      = 10
+)
+
+// Envelope 协议信封
+type Envelope struct {
+	V       int             `json:"v"`
+	Type    string          `json:"type"`
+	Seq     uint64          `json:"seq"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// StatusPayload status 事件负载
+type StatusPayload struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message,omitempty"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// ErrorPayload error 事件负载
+type ErrorPayload struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// MutePayload mute 控制消息负载
+type MutePayload struct {
+	Muted bool `json:"muted"`
+}
+
+// KickPayload kick 控制消息负载
+type KickPayload struct {
+	ClientID string `json:"client_id"`
+}
+
+// LockPayload lock 控制消息负载
+type LockPayload struct {
+	Locked bool `json:"locked"`
+}
+
+// ChangeLanguagePayload change_language 控制消息负载
+type ChangeLanguagePayload struct {
+	FromLanguage string `json:"from_language"`
+	ToLanguage   string `json:"to_language"`
+}
+
+// Negotiate 根据客户端请求的子协议选择协议，不允许旧格式且客户端未请求 v1 时返回错误
+func Negotiate(requested []string, allowLegacy bool) (string, error) {
+	for _, p := range requested {
+		if p == SubprotocolV1 {
+			return SubprotocolV1, nil
+		}
+	}
+	if allowLegacy {
+		return Legacy, nil
+	}
+	return "", fmt.Errorf("不支持的子协议，请使用 %s", SubprotocolV1)
+}
+
+// EncodeEvent 编码文本事件
+func EncodeEvent(eventType string, seq uint64, payload []byte) ([]byte, error) {
+	return json.Marshal(Envelope{
+		V:       Version,
+		Type:    eventType,
+		Seq:     seq,
+		Payload: payload,
+	})
+}
+
+// EncodeAudio 编码二进制音频帧
+func EncodeAudio(seq uint64, pcm []byte) []byte {
+	out := make([]byte, AudioHeaderSize+len(pcm))
+	out[0] = Version
+	out[1] = audioKind
+	binary.BigEndian.PutUint64(out[2:AudioHeaderSize], seq)
+	copy(out[AudioHeaderSize:], pcm)
+	return out
+}
+
+// DecodeControl 解析客户端控制消息
+func DecodeControl(data []byte) (*Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("解析控制消息失败: %w", err)
+	}
+	if env.V != Version {
+		return nil, fmt.Errorf("不支持的协议版本: %d", env.V)
+	}
+	if env.Type == "" {
+		return nil, errors.New("控制消息缺少 type")
+	}
+	return &env, nil
+}
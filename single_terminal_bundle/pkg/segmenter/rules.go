@@ -0,0 +1,56 @@
+package segmenter
+
+import (
+	"go-backEnd/pkg/languages"
+	"unicode/utf8"
+)
+
+// 英文常见缩写
+var englishAbbreviations = abbreviations(
+	"mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "vs", "etc", "e.g", "i.e",
+	"inc", "ltd", "co", "corp", "no", "fig", "jan", "feb", "mar", "apr", "jun",
+	"jul", "aug", "sep", "sept", "oct", "nov", "dec", "u.s", "u.k", "a.m", "p.m",
+)
+
+// languageAbbreviations 各语言不结束句子的缩写，句末标点取自语言目录的 sentence_end
+var languageAbbreviations = map[string]map[string]bool{
+	"zh": englishAbbreviations, // 译文中夹带的英文
+	"ja": englishAbbreviations,
+	"en": englishAbbreviations,
+	"fr": abbreviations("m", "mme", "mlle", "dr", "etc", "p.ex", "av", "apr", "env"),
+	"de": abbreviations("dr", "prof", "hr", "fr", "nr", "bzw", "usw", "z.b", "d.h", "ca", "vgl", "evtl"),
+	"es": abbreviations("sr", "sra", "srta", "dr", "dra", "etc", "ud", "uds", "pág"),
+	"it": abbreviations("sig", "sig.ra", "dott", "prof", "ecc", "pag"),
+	"pt": abbreviations("sr", "sra", "dr", "dra", "etc", "pág"),
+	"ru": abbreviations("т.е", "т.д", "т.п", "г", "гг", "др", "см", "стр"),
+}
+
+// spaceRuleMinRunes 没有句末标点的语言（如泰语）按空白切分前句子至少包含的字符数
+const spaceRuleMinRunes = 20
+
+// catalogRule 按语言目录构造分句规则
+//
+// ASCII 标点（. ! ?）需跟空白才结束句子，以排除小数、缩写和网址；其余标点（。、।、؟ 等）
+// 出现即结束句子。sentence_end 为空表示该语言不使用句末标点，按空白切分。
+func catalogRule(lang languages.Language) Segmenter {
+	if len(lang.SentenceEnd) == 0 {
+		return &SpaceRule{MinRunes: spaceRuleMinRunes}
+	}
+	rule := &Rule{NeedSpace: true, Abbreviations: languageAbbreviations[lang.Code]}
+	for _, stop := range lang.SentenceEnd {
+		if r, _ := utf8.DecodeRuneInString(stop); r < utf8.RuneSelf {
+			rule.Terminators = append(rule.Terminators, stop)
+		} else {
+			rule.WideStops = append(rule.WideStops, stop)
+		}
+	}
+	return rule
+}
+
+func abbreviations(words ...string) map[string]bool {
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[w] = true
+	}
+	return set
+}
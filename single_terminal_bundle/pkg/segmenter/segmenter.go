@@ -0,0 +1,248 @@
+// Package segmenter 按语言规则在流式翻译文本中检测句子边界
+//
+// 每种语言对应一个 Segmenter，可通过 Register 替换或扩展。Tracker 在 Segmenter 之上
+// 维护流式状态，并在长时间或长文本没有出现句末标点时强制切分。
+package segmenter
+
+import (
+	"go-backEnd/pkg/languages"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Segmenter 句子边界检测
+type Segmenter interface {
+	// Boundary 返回 text[start:] 中第一个完整句子的结束位置（字节偏移），没有时返回 -1
+	Boundary(text string, start int) int
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Segmenter{}
+)
+
+// Register 注册语言的分句器，覆盖按语言目录构造的规则
+func Register(lang string, seg Segmenter) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[lang] = seg
+}
+
+// For 返回语言对应的分句器：优先使用注册的分句器，其次按语言目录中的句末标点构造规则，
+// 不在目录中的语言使用通用规则
+func For(lang string) Segmenter {
+	registryMu.RLock()
+	seg, ok := registry[lang]
+	registryMu.RUnlock()
+	if ok {
+		return seg
+	}
+	if l, ok := languages.Default.Get(lang); ok {
+		return catalogRule(l)
+	}
+	return generic
+}
+
+// generic 未知语言使用的通用规则，兼容中西文标点
+var generic = &Rule{
+	Terminators: []string{".", "!", "?"},
+	WideStops:   []string{"。", "！", "？", "؟", "۔", "।", "॥"},
+	NeedSpace:   true,
+}
+
+// closers 可跟在句末标点后、仍属于当前句子的闭合符号
+const closers = `"'”’」』）)]》〉`
+
+// Rule 基于句末标点的分句规则
+type Rule struct {
+	Terminators   []string        // 句末标点
+	NeedSpace     bool            // 标点后需跟空白（或文本结束）才算句子结束，用于有空格分词的语言
+	WideStops     []string        // 无需空白即可结束句子的标点，如中文句号
+	Abbreviations map[string]bool // 不结束句子的缩写（小写，不含末尾句点）
+}
+
+// Boundary 实现 Segmenter
+func (r *Rule) Boundary(text string, start int) int {
+	if start < 0 || start >= len(text) {
+		return -1
+	}
+	for i := start; i < len(text); {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		if marker, wide := r.matchAt(text, i); marker != "" {
+			end := extendStops(text, i+len(marker), r)
+			if wide || r.acceptLatin(text, i, end) {
+				return end
+			}
+			i = end
+			continue
+		}
+		i += size
+	}
+	return -1
+}
+
+// matchAt 判断 text[i:] 是否以句末标点开头，wide 表示无需空白的标点
+func (r *Rule) matchAt(text string, i int) (string, bool) {
+	for _, m := range r.WideStops {
+		if strings.HasPrefix(text[i:], m) {
+			return m, true
+		}
+	}
+	for _, m := range r.Terminators {
+		if strings.HasPrefix(text[i:], m) {
+			return m, !r.NeedSpace
+		}
+	}
+	return "", false
+}
+
+// extendStops 将连续的句末标点（如 "?!"、"..."）和闭合符号并入当前句子
+func extendStops(text string, end int, r *Rule) int {
+	for end < len(text) {
+		if m, _ := r.matchAt(text, end); m != "" {
+			end += len(m)
+			continue
+		}
+		ch, size := utf8.DecodeRuneInString(text[end:])
+		if !strings.ContainsRune(closers, ch) {
+			break
+		}
+		end += size
+	}
+	return end
+}
+
+// acceptLatin 检查需要空白分隔的标点是否真正结束句子：排除小数、缩写和单字母缩写
+func (r *Rule) acceptLatin(text string, pos, end int) bool {
+	if end < len(text) {
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		if !unicode.IsSpace(next) { // "3.14"、"example.com"
+			return false
+		}
+	}
+
+	if text[pos] != '.' {
+		return true
+	}
+	word := lastWord(text[:pos])
+	if word == "" {
+		return true
+	}
+	if end == len(text) && isDigits(word) { // 流式文本末尾的 "3." 可能是小数的前半部分
+		return false
+	}
+	if utf8.RuneCountInString(word) == 1 && unicode.IsUpper([]rune(word)[0]) { // 人名首字母 "J."
+		return false
+	}
+	return !r.Abbreviations[strings.ToLower(word)]
+}
+
+// lastWord 返回文本末尾的单词（去掉前导标点）
+func lastWord(text string) string {
+	i := strings.LastIndexFunc(text, unicode.IsSpace)
+	word := text[i+1:]
+	return strings.TrimLeftFunc(word, func(ch rune) bool { return !unicode.IsLetter(ch) && !unicode.IsDigit(ch) })
+}
+
+func isDigits(s string) bool {
+	for _, ch := range s {
+		if !unicode.IsDigit(ch) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// SpaceRule 适用于无句末标点、以空白分隔句子的语言（如泰语）：累计足够长度后遇到空白即切分
+type SpaceRule struct {
+	MinRunes int // 切分前句子至少包含的字符数
+}
+
+// Boundary 实现 Segmenter
+func (r *SpaceRule) Boundary(text string, start int) int {
+	if start < 0 || start >= len(text) {
+		return -1
+	}
+	runes := 0
+	for i, ch := range text[start:] {
+		if unicode.IsSpace(ch) {
+			if runes >= r.MinRunes && i > 0 {
+				return start + i
+			}
+			continue
+		}
+		runes++
+	}
+	return -1
+}
+
+// Tracker 流式分句状态，句末标点长时间未出现时按长度或时间强制切分
+type Tracker struct {
+	seg      Segmenter
+	maxRunes int           // 未切分文本的最大字符数，0 表示不限制
+	maxWait  time.Duration // 距上次切分的最长等待时间，0 表示不限制
+
+	pos  int       // 已切分位置
+	last time.Time // 上次切分时间
+}
+
+// NewTracker 创建流式分句状态
+func NewTracker(seg Segmenter, maxRunes int, maxWait time.Duration) *Tracker {
+	return &Tracker{seg: seg, maxRunes: maxRunes, maxWait: maxWait, last: time.Now()}
+}
+
+// Advance 检查累计文本中是否出现新的句子边界，返回边界位置以及是否为强制切分
+func (t *Tracker) Advance(text string, now time.Time) (end int, forced bool, ok bool) {
+	if t.pos >= len(text) {
+		return 0, false, false
+	}
+	if end := t.seg.Boundary(text, t.pos); end > t.pos {
+		t.commit(end, now)
+		return end, false, true
+	}
+
+	pending := text[t.pos:]
+	if t.maxRunes > 0 && utf8.RuneCountInString(pending) >= t.maxRunes {
+		end := t.pos + softBreak(pending, t.maxRunes)
+		t.commit(end, now)
+		return end, true, true
+	}
+	if t.maxWait > 0 && now.Sub(t.last) >= t.maxWait && strings.TrimSpace(pending) != "" {
+		t.commit(len(text), now)
+		return len(text), true, true
+	}
+	return 0, false, false
+}
+
+// Reset 开始新的消息
+func (t *Tracker) Reset(now time.Time) {
+	t.pos = 0
+	t.last = now
+}
+
+func (t *Tracker) commit(end int, now time.Time) {
+	t.pos = end
+	t.last = now
+}
+
+// softBreak 在前 maxRunes 个字符内寻找最后一个空白或逗号作为切分点，找不到时在 maxRunes 处硬切
+func softBreak(text string, maxRunes int) int {
+	cut, runes, soft := len(text), 0, -1
+	for i, ch := range text {
+		if runes == maxRunes {
+			cut = i
+			break
+		}
+		runes++
+		if unicode.IsSpace(ch) || ch == ',' || ch == '，' || ch == '、' {
+			soft = i + utf8.RuneLen(ch)
+		}
+	}
+	if soft > 0 && soft < cut {
+		return soft
+	}
+	return cut
+}
@@ -0,0 +1,110 @@
+package segmenter
+
+import (
+	"go-backEnd/pkg/languages"
+	"testing"
+	"time"
+)
+
+// firstSentence 返回第一个完整句子，没有时返回空字符串
+func firstSentence(lang, text string) string {
+	end := For(lang).Boundary(text, 0)
+	if end < 0 {
+		return ""
+	}
+	return text[:end]
+}
+
+func TestBoundary(t *testing.T) {
+	tests := []struct {
+		lang string
+		text string
+		want string
+	}{
+		// 英文：缩写、小数、人名首字母、连续标点
+		{"en", "Dr. Smith arrived. He sat down.", "Dr. Smith arrived."},
+		{"en", "Pi is 3.14 today. Yes", "Pi is 3.14 today."},
+		{"en", "The answer is 3.", ""},
+		{"en", "J. K. Rowling wrote it. Then", "J. K. Rowling wrote it."},
+		{"en", "Wait...what? Ok", "Wait...what?"},
+		{"en", `He said "go." Then`, `He said "go."`},
+		{"en", "See example.com for details", ""},
+		{"de", "Das ist z.B. gut. Ja", "Das ist z.B. gut."},
+		{"fr", "M. Dupont est là. Oui", "M. Dupont est là."},
+		// 中日文无需空格，夹带的西文句点仍需空白
+		{"zh", "你好。我很好", "你好。"},
+		{"zh", "他说：“走吧。”然后离开", "他说：“走吧。”"},
+		{"zh", "版本3.14发布了", ""},
+		{"ja", "こんにちは！元気ですか", "こんにちは！"},
+		{"ko", "안녕하세요. 반갑습니다", "안녕하세요."},
+		{"ko", "정말？네", "정말？"},
+		{"ar", "كيف حالك؟ أنا بخير", "كيف حالك؟"},
+		{"hi", "नमस्ते। आप कैसे हैं", "नमस्ते।"},
+		// 泰语没有句末标点，累计足够长度后按空白切分
+		{"th", "สวัสดีครับวันนี้อากาศดีมาก ผมชื่อ", "สวัสดีครับวันนี้อากาศดีมาก"},
+		{"th", "สวัสดี ครับ", ""},
+		// 不在目录中的语言使用通用规则
+		{"xx", "Hi. There", "Hi."},
+		{"xx", "你好。再见", "你好。"},
+	}
+	for _, tt := range tests {
+		if got := firstSentence(tt.lang, tt.text); got != tt.want {
+			t.Errorf("[%s] %q: 第一句 = %q, 期望 %q", tt.lang, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestForFollowsCatalog(t *testing.T) {
+	saved := languages.Default.All()
+	defer languages.Default.Replace(saved)
+
+	if err := languages.Default.Replace([]languages.Language{{Code: "ko", SentenceEnd: []string{"?"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := firstSentence("ko", "좋아요. 정말? 네"); got != "좋아요. 정말?" {
+		t.Errorf("目录中的句末标点未生效: %q", got)
+	}
+}
+
+func TestRegisterOverridesCatalog(t *testing.T) {
+	Register("en", &SpaceRule{MinRunes: 3})
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "en")
+		registryMu.Unlock()
+	}()
+
+	if got := firstSentence("en", "abcd efg. h"); got != "abcd" {
+		t.Errorf("注册的分句器未生效: %q", got)
+	}
+}
+
+func TestTrackerForcesLongText(t *testing.T) {
+	now := time.Now()
+	tr := NewTracker(For("en"), 10, 0)
+
+	text := "one two three four five"
+	end, forced, ok := tr.Advance(text, now)
+	if !ok || !forced || text[:end] != "one two " {
+		t.Fatalf("Advance = (%q, %v, %v), 期望在空白处强制切分", text[:end], forced, ok)
+	}
+
+	text += ". Next"
+	end, forced, ok = tr.Advance(text, now)
+	if !ok || forced || text[:end] != "one two three four five." {
+		t.Fatalf("Advance = (%q, %v, %v), 期望在句号处切分", text[:end], forced, ok)
+	}
+}
+
+func TestTrackerForcesAfterWait(t *testing.T) {
+	now := time.Now()
+	tr := NewTracker(For("zh"), 0, time.Second)
+
+	if _, _, ok := tr.Advance("还没说完", now); ok {
+		t.Fatal("未超时不应切分")
+	}
+	end, forced, ok := tr.Advance("还没说完", now.Add(2*time.Second))
+	if !ok || !forced || end != len("还没说完") {
+		t.Fatalf("Advance = (%d, %v, %v), 期望超时后整体切分", end, forced, ok)
+	}
+}
@@ -0,0 +1,81 @@
+// Package storage 提供录音等文件的存储后端：本地目录或 S3 兼容对象存储
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// Info 对象元信息
+type Info struct {
+	Key     string    `json:"key"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// Object 可随机读取的对象，可直接交给 io.NewSectionReader / http.ServeContent
+type Object interface {
+	io.ReaderAt
+	io.Closer
+	Info() Info
+}
+
+// BlobStore 对象存储，key 使用 "/" 分隔的相对路径
+type BlobStore interface {
+	// Put 写入对象，size 为 -1 时表示长度未知
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// PutFile 把本地文件存为对象，成功后本地文件不再需要（本地存储可能直接移动该文件）
+	PutFile(ctx context.Context, key, localPath string) error
+	// Open 打开对象，不存在时返回 ErrNotFound
+	Open(ctx context.Context, key string) (Object, error)
+	// Stat 获取对象元信息，不存在时返回 ErrNotFound
+	Stat(ctx context.Context, key string) (Info, error)
+	// List 列出 key 以 prefix 开头的对象
+	List(ctx context.Context, prefix string) ([]Info, error)
+	// Delete 删除对象，对象不存在不视为错误
+	Delete(ctx context.Context, key string) error
+}
+
+// Config 存储后端配置
+type Config struct {
+	Backend string // "local"（默认）或 "s3"
+	Root    string // 本地存储根目录
+	S3      S3Config
+}
+
+// Default 全局存储后端，由 Init 创建，未初始化时使用 "audio" 目录
+var Default BlobStore = NewLocalStore("audio")
+
+// Init 按配置创建全局存储后端
+func Init(cfg Config) error {
+	switch cfg.Backend {
+	case "", "local":
+		Default = NewLocalStore(cfg.Root)
+	case "s3":
+		store, err := NewS3Store(cfg.S3)
+		if err != nil {
+			return err
+		}
+		Default = store
+	default:
+		return fmt.Errorf("未知的存储后端: %s", cfg.Backend)
+	}
+	return nil
+}
+
+// CleanKey 校验并规范化 key，拒绝绝对路径和 ".." 越界
+func CleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, "\\", "/")), "/")
+	if key == "" || key == "." {
+		return "", fmt.Errorf("非法的对象名")
+	}
+	return key, nil
+}
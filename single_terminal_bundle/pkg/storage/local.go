@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore 本地目录存储
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore 创建以 root 为根目录的本地存储
+func NewLocalStore(root string) *LocalStore {
+	if root == "" {
+		root = "audio"
+	}
+	return &LocalStore{root: root}
+}
+
+// Root 返回根目录
+func (s *LocalStore) Root() string {
+	return s.root
+}
+
+// path 返回 key 对应的文件路径
+func (s *LocalStore) path(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put 写入临时文件后重命名，读取方不会看到写了一半的文件
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	dst, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	return nil
+}
+
+// PutFile 文件已在目标位置时不做任何事，否则移动过去，跨设备时退回到复制
+func (s *LocalStore) PutFile(ctx context.Context, key, localPath string) error {
+	dst, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	srcAbs, _ := filepath.Abs(localPath)
+	dstAbs, _ := filepath.Abs(dst)
+	if srcAbs == dstAbs {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("创建存储目录失败: %w", err)
+	}
+	if os.Rename(localPath, dst) == nil {
+		return nil
+	}
+	f, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("打开本地文件失败: %w", err)
+	}
+	defer f.Close()
+	if err := s.Put(ctx, key, f, -1); err != nil {
+		return err
+	}
+	return os.Remove(localPath)
+}
+
+// localObject 本地文件对象
+type localObject struct {
+	*os.File
+	info Info
+}
+
+func (o *localObject) Info() Info {
+	return o.info
+}
+
+// Open 打开文件
+func (s *LocalStore) Open(ctx context.Context, key string) (Object, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开文件失败: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil || st.IsDir() {
+		f.Close()
+		return nil, ErrNotFound
+	}
+	return &localObject{File: f, info: Info{Key: key, Size: st.Size(), ModTime: st.ModTime()}}, nil
+}
+
+// Stat 获取文件信息
+func (s *LocalStore) Stat(ctx context.Context, key string) (Info, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return Info{}, err
+	}
+	st, err := os.Stat(p)
+	if os.IsNotExist(err) || (err == nil && st.IsDir()) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("获取文件信息失败: %w", err)
+	}
+	return Info{Key: key, Size: st.Size(), ModTime: st.ModTime()}, nil
+}
+
+// List 递归列出根目录下的文件，跳过上传中的临时文件
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]Info, error) {
+	var result []Info
+	err := filepath.WalkDir(s.root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == s.root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return nil
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		st, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		result = append(result, Info{Key: key, Size: st.Size(), ModTime: st.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("列出文件失败: %w", err)
+	}
+	return result, nil
+}
+
+// Delete 删除文件
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除文件失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Config S3 兼容对象存储配置
+type S3Config struct {
+	Endpoint  string // 如 https://s3.amazonaws.com 或 http://127.0.0.1:9000
+	Region    string // 签名使用的区域，MinIO 等兼容实现通常为 us-east-1
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string // 所有 key 的公共前缀，如 "recordings/"
+	PathStyle bool   // 使用 /<bucket>/<key> 形式的地址，MinIO 等兼容实现需要开启
+	Timeout   time.Duration
+}
+
+// S3Store S3 兼容对象存储，使用 AWS Signature V4 签名，不依赖 SDK
+type S3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// NewS3Store 创建 S3 存储
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("S3 存储需要配置 endpoint 和 bucket")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("S3 存储需要配置访问密钥")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 endpoint 格式错误: %s", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Minute
+	}
+	return &S3Store{cfg: cfg, endpoint: endpoint, client: &http.Client{Timeout: cfg.Timeout}}, nil
+}
+
+// objectURL 返回对象地址，key 为空时返回存储桶地址
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	if s.cfg.PathStyle {
+		u.Path = "/" + s.cfg.Bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false) // 保证发送的路径与签名时的规范路径一致
+	return &u
+}
+
+// fullKey 拼接公共前缀
+func (s *S3Store) fullKey(key string) (string, error) {
+	key, err := CleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	return s.cfg.Prefix + key, nil
+}
+
+// do 签名并发送请求
+func (s *S3Store) do(ctx context.Context, method string, u *url.URL, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	s.sign(req, time.Now().UTC())
+	return s.client.Do(req)
+}
+
+// unsignedPayload 不对请求体做哈希，S3 及兼容实现均支持
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// sign 按 AWS Signature V4 为请求添加 Authorization 头
+func (s *S3Store) sign(req *http.Request, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", unsignedPayload)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": unsignedPayload,
+		"x-amz-date":           amzDate,
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + s.cfg.Region + "/s3/aws4_request"
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hashed[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), date)
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode 按 SigV4 规则编码，只保留 RFC 3986 非保留字符，路径中的 "/" 可选择保留
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// canonicalQuery 按键排序并编码查询参数
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// responseError 读取错误响应，404 返回 ErrNotFound
+func responseError(resp *http.Response, op string) error {
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("S3 %s 失败: %s %s", op, resp.Status, strings.TrimSpace(string(body)))
+}
+
+// Put 上传对象，长度未知时先读入内存
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	full, err := s.fullKey(key)
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("读取上传内容失败: %w", err)
+		}
+		r, size = bytes.NewReader(data), int64(len(data))
+	}
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(full), r, size, nil)
+	if err != nil {
+		return fmt.Errorf("S3 上传失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return responseError(resp, "上传")
+	}
+	return nil
+}
+
+// PutFile 上传本地文件，成功后删除本地文件
+func (s *S3Store) PutFile(ctx context.Context, key, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("打开本地文件失败: %w", err)
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("获取本地文件信息失败: %w", err)
+	}
+	err = s.Put(ctx, key, f, st.Size())
+	f.Close()
+	if err != nil {
+		return err
+	}
+	return os.Remove(localPath)
+}
+
+// Stat 通过 HEAD 获取对象信息
+func (s *S3Store) Stat(ctx context.Context, key string) (Info, error) {
+	full, err := s.fullKey(key)
+	if err != nil {
+		return Info{}, err
+	}
+	resp, err := s.do(ctx, http.MethodHead, s.objectURL(full), nil, 0, nil)
+	if err != nil {
+		return Info{}, fmt.Errorf("S3 获取对象信息失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return Info{}, responseError(resp, "获取对象信息")
+	}
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return Info{Key: key, Size: resp.ContentLength, ModTime: modTime}, nil
+}
+
+// Open 打开对象，读取时按需发起范围请求
+func (s *S3Store) Open(ctx context.Context, key string) (Object, error) {
+	info, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	full, _ := s.fullKey(key)
+	return &s3Object{store: s, ctx: ctx, url: s.objectURL(full), info: info}, nil
+}
+
+// s3Object 顺序读取时复用同一个响应体，偏移不连续时重新发起范围请求
+type s3Object struct {
+	store *S3Store
+	ctx   context.Context
+	url   *url.URL
+	info  Info
+
+	mu   sync.Mutex
+	body io.ReadCloser
+	pos  int64
+}
+
+func (o *s3Object) Info() Info {
+	return o.info
+}
+
+func (o *s3Object) ReadAt(p []byte, off int64) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if off >= o.info.Size {
+		return 0, io.EOF
+	}
+	if o.body == nil || o.pos != off {
+		if o.body != nil {
+			o.body.Close()
+			o.body = nil
+		}
+		header := http.Header{"Range": []string{"bytes=" + strconv.FormatInt(off, 10) + "-"}}
+		resp, err := o.store.do(o.ctx, http.MethodGet, o.url, nil, 0, header)
+		if err != nil {
+			return 0, fmt.Errorf("S3 读取失败: %w", err)
+		}
+		if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+			defer resp.Body.Close()
+			return 0, responseError(resp, "读取")
+		}
+		if resp.StatusCode == http.StatusOK && off > 0 { // 服务端忽略了 Range
+			if _, err := io.CopyN(io.Discard, resp.Body, off); err != nil {
+				resp.Body.Close()
+				return 0, fmt.Errorf("S3 读取失败: %w", err)
+			}
+		}
+		o.body, o.pos = resp.Body, off
+	}
+
+	n, err := io.ReadFull(o.body, p)
+	o.pos += int64(n)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	if err != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	return n, err
+}
+
+func (o *s3Object) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.body != nil {
+		o.body.Close()
+		o.body = nil
+	}
+	return nil
+}
+
+// listBucketResult ListObjectsV2 响应
+type listBucketResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+// List 通过 ListObjectsV2 分页列出对象
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Info, error) {
+	var result []Info
+	token := ""
+	for {
+		u := s.objectURL("")
+		q := url.Values{"list-type": {"2"}, "prefix": {s.cfg.Prefix + prefix}}
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		u.RawQuery = canonicalQuery(q)
+
+		resp, err := s.do(ctx, http.MethodGet, u, nil, 0, nil)
+		if err != nil {
+			return nil, fmt.Errorf("S3 列出对象失败: %w", err)
+		}
+		if resp.StatusCode/100 != 2 {
+			err := responseError(resp, "列出对象")
+			resp.Body.Close()
+			return nil, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("解析 S3 对象列表失败: %w", err)
+		}
+
+		for _, c := range page.Contents {
+			result = append(result, Info{Key: strings.TrimPrefix(c.Key, s.cfg.Prefix), Size: c.Size, ModTime: c.LastModified})
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return result, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// Delete 删除对象
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	full, err := s.fullKey(key)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(full), nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("S3 删除失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 && resp.StatusCode != http.StatusNotFound {
+		return responseError(resp, "删除")
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// 签名向量由独立实现（Python hmac/hashlib）按 SigV4 规则计算
+func TestS3SignatureVectors(t *testing.T) {
+	now := time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		endpoint  string
+		pathStyle bool
+		method    string
+		key       string
+		query     url.Values
+		signature string
+	}{
+		{"路径风格并编码空格和加号", "http://minio.local:9000", true, http.MethodPut, "recordings/room 1/a+b.wav", nil,
+			"c59f7ebabbf8172de0cbd5b9efa96053ac2c5accef9e7289c51374883d873e5c"},
+		{"列出对象的查询参数", "http://minio.local:9000", true, http.MethodGet, "", url.Values{"list-type": {"2"}, "prefix": {"recordings/"}},
+			"62e12e368251b7827a38dd97b789301e8ac985c8f415b0bf8224118da466566c"},
+		{"虚拟主机风格", "https://s3.amazonaws.com", false, http.MethodGet, "recordings/a.wav", nil,
+			"c77ad9b2c9deae327526731e3c79c2bf5f2ed8e3d970510578bf5b512a207f49"},
+	}
+	for _, tt := range tests {
+		store, err := NewS3Store(S3Config{Endpoint: tt.endpoint, Bucket: "bucket", AccessKey: testAccessKey, SecretKey: testSecretKey, PathStyle: tt.pathStyle})
+		if err != nil {
+			t.Fatal(err)
+		}
+		u := store.objectURL(tt.key)
+		if tt.query != nil {
+			u.RawQuery = canonicalQuery(tt.query)
+		}
+		req, _ := http.NewRequest(tt.method, u.String(), nil)
+		store.sign(req, now)
+
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20130524/us-east-1/s3/aws4_request, " +
+			"SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=" + tt.signature
+		if got := req.Header.Get("Authorization"); got != want {
+			t.Errorf("%s:\n得到 %s\n期望 %s", tt.name, got, want)
+		}
+	}
+}
+
+// fakeS3 内存中的 S3 替身，支持路径风格的 PUT/HEAD/GET(Range)/DELETE 和 ListObjectsV2，并校验签名
+type fakeS3 struct {
+	store   *S3Store // 服务端持有的凭证，用于重新签名收到的请求
+	pageLen int
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	ranges  int // 收到的范围请求数
+}
+
+var fakeModTime = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+// verify 按收到的方法、路径、查询和 X-Amz-Date 重新签名，确认线上的请求与签名时一致
+func (f *fakeS3) verify(r *http.Request) bool {
+	now, err := time.Parse("20060102T150405Z", r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return false
+	}
+	u := *r.URL
+	u.Scheme, u.Host = "http", r.Host
+	check := &http.Request{Method: r.Method, URL: &u, Header: http.Header{}}
+	f.store.sign(check, now)
+	return check.Header.Get("Authorization") == r.Header.Get("Authorization")
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !f.verify(r) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/bucket" && r.Method == http.MethodGet {
+		f.list(w, r)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/bucket/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		if int64(len(data)) != r.ContentLength {
+			http.Error(w, "length mismatch", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = data
+	case http.MethodHead, http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Last-Modified", fakeModTime.Format(http.TimeFormat))
+		start := 0
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(rng, "bytes="), "-"))
+			f.ranges++
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)-start))
+		if r.Header.Get("Range") != "" {
+			w.WriteHeader(http.StatusPartialContent)
+		}
+		if r.Method == http.MethodGet {
+			w.Write(data[start:])
+		}
+	case http.MethodDelete:
+		if _, ok := f.objects[key]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// list 按 key 排序分页，continuation-token 为上一页最后一个 key
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("list-type") != "2" {
+		http.Error(w, "only v2", http.StatusBadRequest)
+		return
+	}
+	var keys []string
+	for k := range f.objects {
+		if strings.HasPrefix(k, q.Get("prefix")) && k > q.Get("continuation-token") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var page listBucketResult
+	if len(keys) > f.pageLen {
+		keys = keys[:f.pageLen]
+		page.IsTruncated, page.NextContinuationToken = true, keys[len(keys)-1]
+	}
+	for _, k := range keys {
+		page.Contents = append(page.Contents, struct {
+			Key          string    `xml:"Key"`
+			LastModified time.Time `xml:"LastModified"`
+			Size         int64     `xml:"Size"`
+		}{k, fakeModTime, int64(len(f.objects[k]))})
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"ListBucketResult"`
+		listBucketResult
+	}{listBucketResult: page})
+}
+
+func newFakeS3(t *testing.T, prefix string) (*S3Store, *fakeS3) {
+	fake := &fakeS3{pageLen: 2, objects: map[string][]byte{}}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	store, err := NewS3Store(S3Config{
+		Endpoint: srv.URL, Bucket: "bucket", AccessKey: testAccessKey, SecretKey: testSecretKey,
+		Prefix: prefix, PathStyle: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := *store // 独立副本，客户端改动凭证不影响服务端校验
+	fake.store = &server
+	return store, fake
+}
+
+func TestS3PutGetListDelete(t *testing.T) {
+	ctx := context.Background()
+	store, fake := newFakeS3(t, "prod/")
+
+	if err := store.Put(ctx, "recordings/room 1/a+b.wav", strings.NewReader("hello world"), 11); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := store.Put(ctx, "recordings/room 1/unknown.wav", strings.NewReader("size unknown"), -1); err != nil {
+		t.Fatalf("Put 未知长度: %v", err)
+	}
+	local := filepath.Join(t.TempDir(), "c.wav")
+	os.WriteFile(local, []byte("from file"), 0o644)
+	if err := store.PutFile(ctx, "recordings/room 2/c.wav", local); err != nil {
+		t.Fatalf("PutFile: %v", err)
+	}
+	if _, err := os.Stat(local); !os.IsNotExist(err) {
+		t.Errorf("PutFile 后本地文件应被删除")
+	}
+	if _, ok := fake.objects["prod/recordings/room 1/a+b.wav"]; !ok {
+		t.Fatalf("对象未按前缀保存: %v", fake.objects)
+	}
+
+	info, err := store.Stat(ctx, "recordings/room 1/a+b.wav")
+	if err != nil || info.Size != 11 || !info.ModTime.Equal(fakeModTime) {
+		t.Fatalf("Stat = %+v, %v", info, err)
+	}
+	if _, err := store.Stat(ctx, "recordings/missing.wav"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("不存在的对象 Stat 错误 = %v, 期望 ErrNotFound", err)
+	}
+
+	obj, err := store.Open(ctx, "recordings/room 1/a+b.wav")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	buf := make([]byte, 5)
+	if n, err := obj.ReadAt(buf, 0); n != 5 || err != nil || string(buf) != "hello" {
+		t.Fatalf("ReadAt(0) = %d %q %v", n, buf, err)
+	}
+	if n, err := obj.ReadAt(buf[:1], 5); n != 1 || err != nil || buf[0] != ' ' { // 顺序读取复用响应体
+		t.Fatalf("ReadAt(5) = %d %q %v", n, buf[:1], err)
+	}
+	if n, err := obj.ReadAt(buf, 6); n != 5 || (err != nil && err != io.EOF) || string(buf) != "world" {
+		t.Fatalf("ReadAt(6) = %d %q %v", n, buf, err)
+	}
+	all, err := io.ReadAll(io.NewSectionReader(obj, 0, obj.Info().Size))
+	if err != nil || string(all) != "hello world" {
+		t.Fatalf("SectionReader = %q, %v", all, err)
+	}
+	obj.Close()
+	if fake.ranges != 2 { // 第一次读取和回到偏移 0 时各一次
+		t.Errorf("范围请求 %d 次, 期望 2", fake.ranges)
+	}
+
+	list, err := store.List(ctx, "recordings/")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var keys []string
+	for _, info := range list {
+		keys = append(keys, info.Key)
+	}
+	want := []string{"recordings/room 1/a+b.wav", "recordings/room 1/unknown.wav", "recordings/room 2/c.wav"}
+	if strings.Join(keys, ",") != strings.Join(want, ",") {
+		t.Fatalf("List（分页）= %v, 期望 %v", keys, want)
+	}
+	if list[1].Size != int64(len("size unknown")) {
+		t.Errorf("List 大小 = %d", list[1].Size)
+	}
+
+	if err := store.Delete(ctx, "recordings/room 1/a+b.wav"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, "recordings/room 1/a+b.wav"); err != nil {
+		t.Fatalf("删除不存在的对象不应报错: %v", err)
+	}
+	if _, err := store.Open(ctx, "recordings/room 1/a+b.wav"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("删除后 Open 错误 = %v, 期望 ErrNotFound", err)
+	}
+}
+
+func TestS3RejectsBadSignature(t *testing.T) {
+	store, _ := newFakeS3(t, "")
+	store.cfg.SecretKey = "wrong"
+
+	err := store.Put(context.Background(), "a.wav", bytes.NewReader([]byte("x")), 1)
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("错误的密钥应被拒绝, 得到 %v", err)
+	}
+}
@@ -1,52 +1,173 @@
 package websocket
 
 import (
-	"bytes"
 	"go-backEnd/internal/models"
 	"go-backEnd/pkg/audio"
+	"go-backEnd/pkg/protocol"
 	"log"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-// ReadPump 处理单终端模式下的客户端读取和转发，结束时会把音频缓冲写入磁盘
-func ReadPump(c *models.Client, r *models.Room) {
+// PumpConfig 客户端读写协程的超时与保活配置
+type PumpConfig struct {
+	WriteWait      time.Duration // 单次写入超时
+	PongWait       time.Duration // 等待 pong（或任意消息）的读超时
+	PingPeriod     time.Duration // ping 发送间隔，必须小于 PongWait
+	MaxMessageSize int64         // 单条客户端消息最大字节数
+}
+
+// DefaultPumpConfig 返回默认配置
+func DefaultPumpConfig() PumpConfig {
+	return PumpConfig{
+		WriteWait:      10 * time.Second,
+		PongWait:       60 * time.Second,
+		PingPeriod:     54 * time.Second,
+		MaxMessageSize: 1 << 20,
+	}
+}
+
+// ReadPump 处理客户端读取并将麦克风音频转发到客户端所属的上游会话，边收边写入录音文件，结束时完成录音
+func ReadPump(c *models.Client, r *models.Room, cfg PumpConfig) {
 	defer func() {
-		if buf, ok := r.ClientAudioBuffers.Load(c); ok {
-			audioBuffer := buf.(*bytes.Buffer)
-			if audioBuffer.Len() > 0 {
-				if err := audio.SavePCMFile(r.ID, audioBuffer.Bytes()); err != nil {
-					log.Printf("[AUDIO] ❌ 保存PCM文件失败: %v", err)
-				}
+		if c.Recorder != nil {
+			if err := c.Recorder.Close(); err != nil {
+				log.Printf("[AUDIO] ❌ 关闭录音失败: %v", err)
 			}
 		}
 
-		r.Unregister <- c
+		if c.VAD != nil && c.VAD.Speaking() { // 说话中断开视为停止说话
+			r.ReportVoiceActivity(c.Speaker(), audio.VADSpeechEnd, 0)
+		}
+
+		select {
+		case r.Unregister <- c:
+		case <-r.Done(): // 房间服务已退出
+		}
 		if err := c.Conn.Close(); err != nil {
 			log.Printf("[CLIENT %s] ❌ 关闭连接失败: %v", r.ID, err)
 		}
 	}()
 
+	c.Conn.SetReadLimit(cfg.MaxMessageSize)
+	_ = c.Conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(cfg.PongWait))
+	})
+
 	for {
-		_, message, err := c.Conn.ReadMessage()
+		messageType, message, err := c.Conn.ReadMessage()
+		captured := time.Now() // 以收到时间作为这段音频末尾的采集时间，混音时据此对齐不同实例上的发言人
 		if err != nil {
 			log.Printf("[CLIENT %s] ❌ 断开: %v", r.ID, err)
 			break
 		}
-		if buf, ok := r.ClientAudioBuffers.Load(c); ok {
-			buf.(*bytes.Buffer).Write(message)
+		_ = c.Conn.SetReadDeadline(time.Now().Add(cfg.PongWait)) // 收到任意消息都视为存活
+
+		if messageType == websocket.TextMessage && c.Protocol == protocol.SubprotocolV1 { // v1 协议的文本帧为控制消息
+			req := models.ControlRequest{Client: c}
+			if env, err := protocol.DecodeControl(message); err != nil {
+				req.Err = err
+			} else {
+				req.Type, req.Payload = env.Type, env.Payload
+			}
+			select {
+			case r.Control <- req:
+			case <-r.Done():
+				return
+			}
+			continue
+		}
+		if c.Muted() || c.Role == models.RoleListener { // 静音时既不录音也不转发，听众的音频直接忽略
+			continue
+		}
+
+		if c.Decoder != nil { // 每条二进制消息为一个压缩包
+			decoded, err := c.Decoder.Decode(message)
+			if err != nil {
+				log.Printf("[CLIENT %s] ❌ 音频解码失败: %v", c.ID, err)
+				continue
+			}
+			message = decoded
+		}
+		if c.InputConverter != nil { // 统一转换为上游输入格式
+			converted, err := c.InputConverter.Resample(message, false)
+			if err != nil {
+				log.Printf("[CLIENT %s] ❌ 音频格式转换失败: %v", c.ID, err)
+				continue
+			}
+			message = converted
+		}
+
+		if c.Recorder != nil {
+			if err := c.Recorder.Write(message); err != nil { // 录音失败不影响翻译
+				log.Printf("[AUDIO] ❌ %v，停止录音", err)
+				_ = c.Recorder.Close()
+				c.Recorder = nil
+			}
 		}
-		r.TranslationMux.Lock()
-		if r.TranslationWS != nil {
-			_ = r.TranslationWS.WriteMessage(websocket.BinaryMessage, message)
+		if c.VAD != nil { // 静音段不送往上游
+			out, event, suppressed := c.VAD.Process(message)
+			r.ReportVoiceActivity(c.Speaker(), event, suppressed)
+			if out == nil {
+				continue
+			}
+			message = out
 		}
-		r.TranslationMux.Unlock()
+		r.ForwardAudio(c.Speaker(), c.Upstream, message, captured)
 	}
 }
 
-// WritePump 将翻译结果写回单终端客户端
-func WritePump(c *models.Client) {
-	for message := range c.Send {
-		_ = c.Conn.WriteMessage(websocket.BinaryMessage, message)
+// WritePump 将翻译结果写回客户端，文本消息优先于音频帧，并定期发送 ping 保活
+func WritePump(c *models.Client, cfg PumpConfig) {
+	ticker := time.NewTicker(cfg.PingPeriod)
+	defer func() {
+		ticker.Stop()
+		_ = c.Conn.Close() // 写失败时关闭连接，ReadPump 随之退出并注销客户端
+	}()
+
+	write := func(messageType int, data []byte) bool {
+		_ = c.Conn.SetWriteDeadline(time.Now().Add(cfg.WriteWait))
+		if err := c.Conn.WriteMessage(messageType, data); err != nil {
+			log.Printf("[CLIENT %s] ❌ 写入失败: %v", c.ID, err)
+			return false
+		}
+		return true
+	}
+
+	for {
+		// 优先清空文本通道，避免转写消息排在大量音频帧之后
+		select {
+		case message, ok := <-c.Send:
+			if !ok {
+				write(websocket.CloseMessage, []byte{})
+				return
+			}
+			if !write(message.MessageType, message.Data) {
+				return
+			}
+			continue
+		default:
+		}
+
+		select {
+		case message, ok := <-c.Send:
+			if !ok {
+				write(websocket.CloseMessage, []byte{})
+				return
+			}
+			if !write(message.MessageType, message.Data) {
+				return
+			}
+		case message := <-c.Audio:
+			if !write(message.MessageType, message.Data) {
+				return
+			}
+		case <-ticker.C:
+			if !write(websocket.PingMessage, nil) {
+				return
+			}
+		}
 	}
 }